@@ -35,6 +35,10 @@ func Command(command string) slog.Attr {
 	return slog.String("command", command)
 }
 
+func UserID(id string) slog.Attr {
+	return slog.String("user_id", id)
+}
+
 // Attr is an exception to the general pattern,
 // Use it sparingly and only for quick debugging
 // where you don't want to introduce a dedicated function.
@@ -189,3 +193,27 @@ func ValueBool(v bool) slog.Attr {
 func Object(object any) slog.Attr {
 	return slog.Any("object", object)
 }
+
+//
+// Resilience
+//
+
+func Attempt(attempt int) slog.Attr {
+	return slog.Int("attempt", attempt)
+}
+
+func Backoff(d time.Duration) slog.Attr {
+	return slog.String("backoff", d.String())
+}
+
+func CircuitBreaker(name string) slog.Attr {
+	return slog.String("circuit_breaker", name)
+}
+
+func StateChange(from string, to string) slog.Attr {
+	return slog.Group("state", slog.String("from", from), slog.String("to", to))
+}
+
+func Bulkhead(name string) slog.Attr {
+	return slog.String("bulkhead", name)
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/alog/logging"
+	"github.com/go-arrower/arrower/contexts/auth"
+)
+
+// ErrForbidden is matched by all ForbiddenErrors, use errors.Is(err, ErrForbidden).
+var ErrForbidden = errors.New("forbidden")
+
+// ForbiddenError is returned by the authorization decorators, if the policy denies the use case.
+type ForbiddenError struct {
+	UserID  auth.UserID
+	Command string
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.UserID == "" {
+		return fmt.Sprintf("%s: anonymous user is not allowed to execute %s", ErrForbidden, e.Command)
+	}
+
+	return fmt.Sprintf("%s: user %s is not allowed to execute %s", ErrForbidden, e.UserID, e.Command)
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden //nolint:errorlint // compare to the sentinel only
+}
+
+// Policy reports if the user is allowed to execute a use case with the value in.
+// Use ctx to check further information, e.g. auth.IsSuperuser.
+type Policy[T any] func(ctx context.Context, user auth.User, in T) bool
+
+// LoggedIn is a Policy allowing all logged-in users.
+func LoggedIn[T any](ctx context.Context, _ auth.User, _ T) bool {
+	return auth.IsLoggedIn(ctx)
+}
+
+// Superuser is a Policy allowing superusers only.
+func Superuser[T any](ctx context.Context, _ auth.User, _ T) bool {
+	return auth.IsLoggedIn(ctx) && auth.IsSuperuser(ctx)
+}
+
+// AnyOf is a Policy allowing the use case, if one of the policies allows it.
+func AnyOf[T any](policies ...Policy[T]) Policy[T] {
+	return func(ctx context.Context, user auth.User, in T) bool {
+		for _, policy := range policies {
+			if policy(ctx, user, in) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// NewAuthorizedRequest calls the request only, if the policy allows it for auth.CurrentUser,
+// otherwise it returns a ForbiddenError. If the ctx contains only the ID of the user,
+// the policy gets a user with the ID set, see auth.CurrentUserID.
+//
+// Denials are logged and counted as metric, so attempts to access forbidden use cases can be monitored.
+func NewAuthorizedRequest[Req any, Res any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	policy Policy[Req],
+	req Request[Req, Res],
+) Request[Req, Res] {
+	return &requestAuthorizationDecorator[Req, Res]{
+		authorizer: newAuthorizer(logger, meterProvider),
+		policy:     policy,
+		base:       req,
+	}
+}
+
+type requestAuthorizationDecorator[Req any, Res any] struct {
+	authorizer *authorizer
+	policy     Policy[Req]
+	base       Request[Req, Res]
+}
+
+func (d *requestAuthorizationDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	if err := authorize(ctx, d.authorizer, d.policy, req); err != nil {
+		return *new(Res), err
+	}
+
+	return d.base.H(ctx, req) //nolint:wrapcheck // decorate but not change anything
+}
+
+// NewAuthorizedCommand calls the command only, if the policy allows it, see NewAuthorizedRequest.
+func NewAuthorizedCommand[C any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	policy Policy[C],
+	cmd Command[C],
+) Command[C] {
+	return &commandAuthorizationDecorator[C]{
+		authorizer: newAuthorizer(logger, meterProvider),
+		policy:     policy,
+		base:       cmd,
+	}
+}
+
+type commandAuthorizationDecorator[C any] struct {
+	authorizer *authorizer
+	policy     Policy[C]
+	base       Command[C]
+}
+
+func (d *commandAuthorizationDecorator[C]) H(ctx context.Context, cmd C) error {
+	if err := authorize(ctx, d.authorizer, d.policy, cmd); err != nil {
+		return err
+	}
+
+	return d.base.H(ctx, cmd) //nolint:wrapcheck // decorate but not change anything
+}
+
+// NewAuthorizedQuery calls the query only, if the policy allows it, see NewAuthorizedRequest.
+func NewAuthorizedQuery[Q any, Res any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	policy Policy[Q],
+	query Query[Q, Res],
+) Query[Q, Res] {
+	return &queryAuthorizationDecorator[Q, Res]{
+		authorizer: newAuthorizer(logger, meterProvider),
+		policy:     policy,
+		base:       query,
+	}
+}
+
+type queryAuthorizationDecorator[Q any, Res any] struct {
+	authorizer *authorizer
+	policy     Policy[Q]
+	base       Query[Q, Res]
+}
+
+func (d *queryAuthorizationDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
+	if err := authorize(ctx, d.authorizer, d.policy, query); err != nil {
+		return *new(Res), err
+	}
+
+	return d.base.H(ctx, query) //nolint:wrapcheck // decorate but not change anything
+}
+
+type authorizer struct {
+	logger  alog.Logger
+	denials metric.Int64Counter
+}
+
+func newAuthorizer(logger alog.Logger, meterProvider metric.MeterProvider) *authorizer {
+	meter := meterProvider.Meter("arrower.application")
+	denials, _ := meter.Int64Counter("usecases_authorization_denials",
+		metric.WithDescription("number of use cases not called, because the policy denied it"))
+
+	return &authorizer{
+		logger:  logger,
+		denials: denials,
+	}
+}
+
+func authorize[T any](ctx context.Context, a *authorizer, policy Policy[T], in T) error {
+	user := auth.CurrentUser(ctx)
+	if user.ID == "" {
+		user.ID = auth.CurrentUserID(ctx)
+	}
+
+	if policy(ctx, user, in) {
+		return nil
+	}
+
+	cmdName := commandName(in)
+
+	a.denials.Add(ctx, 1, metric.WithAttributes(attribute.String("command", cmdName)))
+	a.logger.InfoContext(ctx, "authorization denied",
+		logging.Command(cmdName),
+		logging.UserID(string(user.ID)),
+	)
+
+	return &ForbiddenError{UserID: user.ID, Command: cmdName}
+}
@@ -0,0 +1,106 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/app"
+	"github.com/go-arrower/arrower/contexts/auth"
+)
+
+func TestRequestAuthorizationDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	t.Run("allowed", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.WithValue(t.Context(), auth.CtxLoggedIn, true)
+
+		handler := app.NewAuthorizedRequest(alog.Test(t), noop.NewMeterProvider(),
+			app.LoggedIn[request], app.TestSuccessRequestHandler[request, response]())
+
+		_, err := handler.H(ctx, request{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		ctx := context.WithValue(t.Context(), auth.CtxLoggedIn, true)
+		ctx = context.WithValue(ctx, auth.CtxUserID, auth.UserID("some-user"))
+
+		called := false
+		handler := app.NewAuthorizedRequest(logger, noop.NewMeterProvider(), app.Superuser[request],
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				called = true
+				return response{}, nil
+			}),
+		)
+
+		_, err := handler.H(ctx, request{})
+		assert.ErrorIs(t, err, app.ErrForbidden)
+		assert.False(t, called)
+
+		var forbidden *app.ForbiddenError
+		assert.True(t, errors.As(err, &forbidden))
+		assert.Equal(t, auth.UserID("some-user"), forbidden.UserID)
+		assert.Equal(t, "app_test.request", forbidden.Command)
+
+		logger.Contains(`msg="authorization denied"`)
+		logger.Contains(`command=app_test.request`)
+		logger.Contains(`user_id=some-user`)
+	})
+
+	t.Run("policy gets the user and the request", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.WithValue(t.Context(), auth.CtxUser, auth.User{ID: "owner"}) //nolint:exhaustruct // only the ID matters
+
+		type ownedRequest struct{ Owner auth.UserID }
+
+		isOwner := func(_ context.Context, user auth.User, req ownedRequest) bool {
+			return req.Owner == user.ID
+		}
+
+		handler := app.NewAuthorizedRequest(alog.Test(t), noop.NewMeterProvider(),
+			app.AnyOf(app.Superuser[ownedRequest], isOwner),
+			app.TestSuccessRequestHandler[ownedRequest, response](),
+		)
+
+		_, err := handler.H(ctx, ownedRequest{Owner: "owner"})
+		assert.NoError(t, err)
+
+		_, err = handler.H(ctx, ownedRequest{Owner: "other"})
+		assert.ErrorIs(t, err, app.ErrForbidden)
+	})
+}
+
+func TestCommandAuthorizationDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	handler := app.NewAuthorizedCommand(alog.Test(t), noop.NewMeterProvider(),
+		app.LoggedIn[command], app.TestSuccessCommandHandler[command]())
+
+	err := handler.H(t.Context(), command{})
+	assert.ErrorIs(t, err, app.ErrForbidden, "anonymous user")
+	assert.Contains(t, err.Error(), "anonymous")
+}
+
+func TestQueryAuthorizationDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.WithValue(t.Context(), auth.CtxLoggedIn, true)
+	ctx = context.WithValue(ctx, auth.CtxIsSuperuser, true)
+
+	handler := app.NewAuthorizedQuery(alog.Test(t), noop.NewMeterProvider(),
+		app.Superuser[query], app.TestSuccessQueryHandler[query, response]())
+
+	_, err := handler.H(ctx, query{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,386 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/alog/logging"
+)
+
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerTimeout  = 30 * time.Second
+	defaultBreakerProbes   = 1
+)
+
+// ErrCircuitOpen is returned by the circuit breaker decorators, instead of calling the use case.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed calls the use cases.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen calls a limited number of use cases, to probe if they succeed again.
+	BreakerHalfOpen
+	// BreakerOpen fails all use cases with ErrCircuitOpen.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// BreakerOption configures a CircuitBreaker.
+type BreakerOption func(*CircuitBreaker)
+
+// WithFailureThreshold sets the number of failures in a row, after which the breaker opens.
+func WithFailureThreshold(failures int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.threshold = max(failures, 1)
+	}
+}
+
+// WithOpenTimeout sets how long the breaker stays open, before it becomes half-open.
+func WithOpenTimeout(timeout time.Duration) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.timeout = max(timeout, 0)
+	}
+}
+
+// WithHalfOpenProbes sets how many use cases are called in the half-open state.
+// If all of them succeed, the breaker closes, if one fails, it opens again.
+func WithHalfOpenProbes(probes int) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.probes = max(probes, 1)
+	}
+}
+
+// WithFailureIf sets which errors count as failures.
+// Without it, all errors count, except a cancelled ctx.
+// Use it to ignore errors of the domain, e.g. validation errors, that don't indicate a broken dependency.
+func WithFailureIf(failureIf func(err error) bool) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		cb.failureIf = failureIf
+	}
+}
+
+// WithBreakerClock sets the clock used for the open timeout.
+// Use it in tests to get predictable times.
+func WithBreakerClock(now func() time.Time) BreakerOption {
+	return func(cb *CircuitBreaker) {
+		if now != nil {
+			cb.now = now
+		}
+	}
+}
+
+// CircuitBreaker stops calling use cases, that keep failing, e.g. because a dependency is down,
+// so the dependency can recover and callers fail fast.
+// One CircuitBreaker can be shared by all use cases depending on the same dependency.
+//
+// The breaker is closed, until WithFailureThreshold failures happen in a row. Then it opens
+// and all calls fail with ErrCircuitOpen. After WithOpenTimeout it becomes half-open and
+// lets WithHalfOpenProbes calls through. If they succeed, it closes, otherwise it opens again.
+type CircuitBreaker struct {
+	name   string
+	logger alog.Logger
+
+	threshold int
+	timeout   time.Duration
+	probes    int
+	failureIf func(err error) bool
+	now       func() time.Time
+
+	transitions metric.Int64Counter
+	rejections  metric.Int64Counter
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// inFlight and succeeded count the probes in the half-open state.
+	inFlight  int
+	succeeded int
+	// generation changes with each state, so results of calls started in a previous state are ignored.
+	generation uint64
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+// The name identifies it in the logs and metrics, e.g. the name of the dependency it protects.
+func NewCircuitBreaker(
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	name string,
+	opts ...BreakerOption,
+) *CircuitBreaker {
+	meter := meterProvider.Meter("arrower.application")
+
+	transitions, _ := meter.Int64Counter("usecases_circuit_breaker_transitions",
+		metric.WithDescription("number of state changes of a circuit breaker"))
+	rejections, _ := meter.Int64Counter("usecases_circuit_breaker_rejections",
+		metric.WithDescription("number of use cases not called, because the circuit breaker was open"))
+
+	cb := &CircuitBreaker{
+		name:        name,
+		logger:      logger,
+		threshold:   defaultBreakerFailures,
+		timeout:     defaultBreakerTimeout,
+		probes:      defaultBreakerProbes,
+		failureIf:   func(err error) bool { return !errors.Is(err, context.Canceled) },
+		now:         time.Now,
+		transitions: transitions,
+		rejections:  rejections,
+		mu:          sync.Mutex{},
+		state:       BreakerClosed,
+		failures:    0,
+		openedAt:    time.Time{},
+		inFlight:    0,
+		succeeded:   0,
+		generation:  0,
+	}
+
+	for _, opt := range opts {
+		opt(cb)
+	}
+
+	_, _ = meter.Int64ObservableGauge("usecases_circuit_breaker_state",
+		metric.WithDescription("state of a circuit breaker: 0 closed, 1 half-open, 2 open"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(cb.State()), metric.WithAttributes(attribute.String("circuit_breaker", name)))
+			return nil
+		}),
+	)
+
+	return cb
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.timeout {
+		return BreakerHalfOpen
+	}
+
+	return cb.state
+}
+
+// allow reports if a use case can be called and reserves a probe in the half-open state.
+// It returns the generation to pass to done.
+func (cb *CircuitBreaker) allow(ctx context.Context) (uint64, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.timeout {
+		cb.transition(ctx, BreakerHalfOpen)
+	}
+
+	switch cb.state {
+	case BreakerClosed:
+		return cb.generation, true
+	case BreakerHalfOpen:
+		if cb.inFlight+cb.succeeded >= cb.probes {
+			return cb.generation, false
+		}
+
+		cb.inFlight++
+
+		return cb.generation, true
+	case BreakerOpen:
+		return cb.generation, false
+	default:
+		return cb.generation, false
+	}
+}
+
+// done records the result of a use case, that allow let through.
+// A use case that panicked counts as failed.
+func (cb *CircuitBreaker) done(ctx context.Context, generation uint64, err error, panicked bool) {
+	failed := panicked || (err != nil && cb.failureIf(err))
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if generation != cb.generation { // the call started before the state changed
+		return
+	}
+
+	switch cb.state {
+	case BreakerClosed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+
+		cb.failures++
+		if cb.failures >= cb.threshold {
+			cb.transition(ctx, BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		cb.inFlight--
+
+		if failed {
+			cb.transition(ctx, BreakerOpen)
+			return
+		}
+
+		cb.succeeded++
+		if cb.succeeded >= cb.probes {
+			cb.transition(ctx, BreakerClosed)
+		}
+	case BreakerOpen:
+	}
+}
+
+// transition changes the state. The caller has to hold the lock.
+func (cb *CircuitBreaker) transition(ctx context.Context, to BreakerState) {
+	from := cb.state
+
+	cb.state = to
+	cb.failures = 0
+	cb.inFlight = 0
+	cb.succeeded = 0
+	cb.generation++
+
+	if to == BreakerOpen {
+		cb.openedAt = cb.now()
+	}
+
+	cb.transitions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("circuit_breaker", cb.name),
+		attribute.String("from", from.String()),
+		attribute.String("to", to.String()),
+	))
+
+	cb.logger.InfoContext(ctx, "circuit breaker changed state",
+		logging.CircuitBreaker(cb.name),
+		logging.StateChange(from.String(), to.String()),
+	)
+}
+
+func breaker[Res any](ctx context.Context, cb *CircuitBreaker, cmdName string, fn func(ctx context.Context) (Res, error)) (Res, error) {
+	generation, ok := cb.allow(ctx)
+	if !ok {
+		cb.rejections.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("circuit_breaker", cb.name),
+			attribute.String("command", cmdName),
+		))
+
+		return *new(Res), fmt.Errorf("%w: %s", ErrCircuitOpen, cb.name)
+	}
+
+	var (
+		res Res
+		err error
+	)
+
+	// record the result also if fn panics, so a half-open probe is not in flight forever.
+	panicked := true
+	defer func() { cb.done(ctx, generation, err, panicked) }()
+
+	res, err = fn(ctx)
+	panicked = false
+
+	return res, err
+}
+
+// NewCircuitBreakerRequest calls the request only, if the CircuitBreaker allows it,
+// otherwise it returns ErrCircuitOpen.
+func NewCircuitBreakerRequest[Req any, Res any](cb *CircuitBreaker, req Request[Req, Res]) Request[Req, Res] {
+	return &requestBreakerDecorator[Req, Res]{
+		breaker: cb,
+		base:    req,
+	}
+}
+
+type requestBreakerDecorator[Req any, Res any] struct {
+	breaker *CircuitBreaker
+	base    Request[Req, Res]
+}
+
+func (d *requestBreakerDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	return breaker(ctx, d.breaker, commandName(req), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, req)
+	})
+}
+
+// NewCircuitBreakerCommand calls the command only, if the CircuitBreaker allows it, see NewCircuitBreakerRequest.
+func NewCircuitBreakerCommand[C any](cb *CircuitBreaker, cmd Command[C]) Command[C] {
+	return &commandBreakerDecorator[C]{
+		breaker: cb,
+		base:    cmd,
+	}
+}
+
+type commandBreakerDecorator[C any] struct {
+	breaker *CircuitBreaker
+	base    Command[C]
+}
+
+func (d *commandBreakerDecorator[C]) H(ctx context.Context, cmd C) error {
+	_, err := breaker(ctx, d.breaker, commandName(cmd), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, cmd)
+	})
+
+	return err
+}
+
+// NewCircuitBreakerQuery calls the query only, if the CircuitBreaker allows it, see NewCircuitBreakerRequest.
+func NewCircuitBreakerQuery[Q any, Res any](cb *CircuitBreaker, query Query[Q, Res]) Query[Q, Res] {
+	return &queryBreakerDecorator[Q, Res]{
+		breaker: cb,
+		base:    query,
+	}
+}
+
+type queryBreakerDecorator[Q any, Res any] struct {
+	breaker *CircuitBreaker
+	base    Query[Q, Res]
+}
+
+func (d *queryBreakerDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
+	return breaker(ctx, d.breaker, commandName(query), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, query)
+	})
+}
+
+// NewCircuitBreakerJob calls the job only, if the CircuitBreaker allows it, see NewCircuitBreakerRequest.
+// A rejected job fails and is rescheduled by the job queue.
+func NewCircuitBreakerJob[J any](cb *CircuitBreaker, job Job[J]) Job[J] {
+	return &jobBreakerDecorator[J]{
+		breaker: cb,
+		base:    job,
+	}
+}
+
+type jobBreakerDecorator[J any] struct {
+	breaker *CircuitBreaker
+	base    Job[J]
+}
+
+func (d *jobBreakerDecorator[J]) H(ctx context.Context, job J) error {
+	_, err := breaker(ctx, d.breaker, commandName(job), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, job)
+	})
+
+	return err
+}
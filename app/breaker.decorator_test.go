@@ -0,0 +1,158 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/app"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("open after failures and close after probe", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		now := time.Now()
+		cb := app.NewCircuitBreaker(logger, noop.NewMeterProvider(), "some-dependency",
+			app.WithFailureThreshold(2),
+			app.WithOpenTimeout(time.Minute),
+			app.WithBreakerClock(func() time.Time { return now }),
+		)
+
+		fail := true
+		calls := 0
+		handler := app.NewCircuitBreakerRequest(cb,
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				if fail {
+					return response{}, errTemporary
+				}
+
+				return response{}, nil
+			}),
+		)
+
+		_, _ = handler.H(t.Context(), request{})
+		assert.Equal(t, app.BreakerClosed, cb.State())
+		_, _ = handler.H(t.Context(), request{})
+		assert.Equal(t, app.BreakerOpen, cb.State())
+
+		_, err := handler.H(t.Context(), request{})
+		assert.ErrorIs(t, err, app.ErrCircuitOpen)
+		assert.Equal(t, 2, calls, "use case is not called while open")
+		logger.Contains(`msg="circuit breaker changed state"`)
+		logger.Contains(`circuit_breaker=some-dependency`)
+		logger.Contains(`state.to=open`)
+
+		now = now.Add(59 * time.Second)
+		assert.Equal(t, app.BreakerOpen, cb.State())
+
+		now = now.Add(time.Second)
+		assert.Equal(t, app.BreakerHalfOpen, cb.State())
+
+		fail = false
+		_, err = handler.H(t.Context(), request{})
+		assert.NoError(t, err)
+		assert.Equal(t, app.BreakerClosed, cb.State())
+		logger.Contains(`state.to=closed`)
+	})
+
+	t.Run("open again if probe fails", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		cb := app.NewCircuitBreaker(alog.Test(t), noop.NewMeterProvider(), "some-dependency",
+			app.WithFailureThreshold(1),
+			app.WithOpenTimeout(time.Minute),
+			app.WithBreakerClock(func() time.Time { return now }),
+		)
+		handler := app.NewCircuitBreakerCommand(cb, app.TestFailureCommandHandler[command]())
+
+		_ = handler.H(t.Context(), command{})
+		now = now.Add(time.Minute)
+
+		err := handler.H(t.Context(), command{})
+		assert.NotErrorIs(t, err, app.ErrCircuitOpen, "probe is called")
+		assert.Equal(t, app.BreakerOpen, cb.State())
+	})
+
+	t.Run("limit probes in half-open", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		cb := app.NewCircuitBreaker(alog.Test(t), noop.NewMeterProvider(), "some-dependency",
+			app.WithFailureThreshold(1),
+			app.WithOpenTimeout(time.Minute),
+			app.WithHalfOpenProbes(1),
+			app.WithBreakerClock(func() time.Time { return now }),
+		)
+
+		_ = app.NewCircuitBreakerJob(cb, app.TestFailureJobHandler[job]()).H(t.Context(), job{})
+		now = now.Add(time.Minute)
+
+		probing := make(chan struct{})
+		release := make(chan struct{})
+		handler := app.NewCircuitBreakerQuery(cb,
+			app.TestQueryHandler(func(_ context.Context, _ query) (response, error) {
+				close(probing)
+				<-release
+
+				return response{}, nil
+			}),
+		)
+
+		go func() { _, _ = handler.H(t.Context(), query{}) }()
+
+		<-probing
+
+		_, err := handler.H(t.Context(), query{})
+		assert.ErrorIs(t, err, app.ErrCircuitOpen, "only one probe at a time")
+
+		close(release)
+		assert.Eventually(t, func() bool { return cb.State() == app.BreakerClosed }, time.Second, time.Millisecond)
+	})
+
+	t.Run("panicking probe counts as failure", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Now()
+		cb := app.NewCircuitBreaker(alog.Test(t), noop.NewMeterProvider(), "some-dependency",
+			app.WithFailureThreshold(1),
+			app.WithOpenTimeout(time.Minute),
+			app.WithBreakerClock(func() time.Time { return now }),
+		)
+
+		_ = app.NewCircuitBreakerCommand(cb, app.TestFailureCommandHandler[command]()).H(t.Context(), command{})
+		now = now.Add(time.Minute)
+
+		handler := app.NewCircuitBreakerCommand(cb, app.TestCommandHandler(func(context.Context, command) error {
+			panic("probe panics")
+		}))
+
+		assert.Panics(t, func() { _ = handler.H(t.Context(), command{}) }, "panic is passed on")
+		assert.Equal(t, app.BreakerOpen, cb.State())
+
+		now = now.Add(time.Minute)
+		assert.Equal(t, app.BreakerHalfOpen, cb.State(), "probe is not in flight anymore")
+	})
+
+	t.Run("ignore errors not counted as failure", func(t *testing.T) {
+		t.Parallel()
+
+		cb := app.NewCircuitBreaker(alog.Test(t), noop.NewMeterProvider(), "some-dependency",
+			app.WithFailureThreshold(1),
+			app.WithFailureIf(app.IsTransient),
+		)
+		handler := app.NewCircuitBreakerRequest(cb, app.TestFailureRequestHandler[request, response]())
+
+		_, _ = handler.H(t.Context(), request{})
+		assert.Equal(t, app.BreakerClosed, cb.State())
+	})
+}
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/alog/logging"
+)
+
+// ErrBulkheadFull is returned by the bulkhead decorators, if too many use cases run already.
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// BulkheadOption configures a Bulkhead.
+type BulkheadOption func(*Bulkhead)
+
+// WithMaxWait sets how long a use case waits for a free slot, before it fails with ErrBulkheadFull.
+// Without it, the use case fails immediately.
+func WithMaxWait(wait time.Duration) BulkheadOption {
+	return func(b *Bulkhead) {
+		b.maxWait = max(wait, 0)
+	}
+}
+
+// Bulkhead limits how many use cases run at the same time, so a slow dependency
+// cannot use up all resources, e.g. connections or goroutines, of the application.
+// One Bulkhead can be shared by all use cases depending on the same dependency.
+type Bulkhead struct {
+	name    string
+	logger  alog.Logger
+	slots   chan struct{}
+	maxWait time.Duration
+
+	inFlight   metric.Int64UpDownCounter
+	rejections metric.Int64Counter
+}
+
+// NewBulkhead returns a Bulkhead running at most limit use cases at the same time.
+// The name identifies it in the logs and metrics, e.g. the name of the dependency it protects.
+func NewBulkhead(
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	name string,
+	limit int,
+	opts ...BulkheadOption,
+) *Bulkhead {
+	meter := meterProvider.Meter("arrower.application")
+
+	inFlight, _ := meter.Int64UpDownCounter("usecases_bulkhead_in_flight",
+		metric.WithDescription("number of use cases running in a bulkhead"))
+	rejections, _ := meter.Int64Counter("usecases_bulkhead_rejections",
+		metric.WithDescription("number of use cases not called, because the bulkhead was full"))
+
+	b := &Bulkhead{
+		name:       name,
+		logger:     logger,
+		slots:      make(chan struct{}, max(limit, 1)),
+		maxWait:    0,
+		inFlight:   inFlight,
+		rejections: rejections,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// acquire reserves a slot and reports if it succeeded. The slot has to be freed with release.
+func (b *Bulkhead) acquire(ctx context.Context) bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if b.maxWait <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (b *Bulkhead) release() {
+	<-b.slots
+}
+
+func bulkhead[Res any](ctx context.Context, b *Bulkhead, cmdName string, fn func(ctx context.Context) (Res, error)) (Res, error) {
+	attrs := metric.WithAttributes(attribute.String("bulkhead", b.name))
+
+	if !b.acquire(ctx) {
+		b.rejections.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("bulkhead", b.name),
+			attribute.String("command", cmdName),
+		))
+
+		b.logger.DebugContext(ctx, "bulkhead is full",
+			logging.Bulkhead(b.name),
+			logging.Command(cmdName),
+		)
+
+		return *new(Res), fmt.Errorf("%w: %s", ErrBulkheadFull, b.name)
+	}
+
+	b.inFlight.Add(ctx, 1, attrs)
+
+	defer func() {
+		b.release()
+		b.inFlight.Add(ctx, -1, attrs)
+	}()
+
+	return fn(ctx)
+}
+
+// NewBulkheadRequest calls the request only, if the Bulkhead has a free slot,
+// otherwise it returns ErrBulkheadFull.
+func NewBulkheadRequest[Req any, Res any](b *Bulkhead, req Request[Req, Res]) Request[Req, Res] {
+	return &requestBulkheadDecorator[Req, Res]{
+		bulkhead: b,
+		base:     req,
+	}
+}
+
+type requestBulkheadDecorator[Req any, Res any] struct {
+	bulkhead *Bulkhead
+	base     Request[Req, Res]
+}
+
+func (d *requestBulkheadDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	return bulkhead(ctx, d.bulkhead, commandName(req), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, req)
+	})
+}
+
+// NewBulkheadCommand calls the command only, if the Bulkhead has a free slot, see NewBulkheadRequest.
+func NewBulkheadCommand[C any](b *Bulkhead, cmd Command[C]) Command[C] {
+	return &commandBulkheadDecorator[C]{
+		bulkhead: b,
+		base:     cmd,
+	}
+}
+
+type commandBulkheadDecorator[C any] struct {
+	bulkhead *Bulkhead
+	base     Command[C]
+}
+
+func (d *commandBulkheadDecorator[C]) H(ctx context.Context, cmd C) error {
+	_, err := bulkhead(ctx, d.bulkhead, commandName(cmd), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, cmd)
+	})
+
+	return err
+}
+
+// NewBulkheadQuery calls the query only, if the Bulkhead has a free slot, see NewBulkheadRequest.
+func NewBulkheadQuery[Q any, Res any](b *Bulkhead, query Query[Q, Res]) Query[Q, Res] {
+	return &queryBulkheadDecorator[Q, Res]{
+		bulkhead: b,
+		base:     query,
+	}
+}
+
+type queryBulkheadDecorator[Q any, Res any] struct {
+	bulkhead *Bulkhead
+	base     Query[Q, Res]
+}
+
+func (d *queryBulkheadDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
+	return bulkhead(ctx, d.bulkhead, commandName(query), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, query)
+	})
+}
+
+// NewBulkheadJob calls the job only, if the Bulkhead has a free slot, see NewBulkheadRequest.
+// A rejected job fails and is rescheduled by the job queue.
+func NewBulkheadJob[J any](b *Bulkhead, job Job[J]) Job[J] {
+	return &jobBulkheadDecorator[J]{
+		bulkhead: b,
+		base:     job,
+	}
+}
+
+type jobBulkheadDecorator[J any] struct {
+	bulkhead *Bulkhead
+	base     Job[J]
+}
+
+func (d *jobBulkheadDecorator[J]) H(ctx context.Context, job J) error {
+	_, err := bulkhead(ctx, d.bulkhead, commandName(job), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, job)
+	})
+
+	return err
+}
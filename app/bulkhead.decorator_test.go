@@ -0,0 +1,92 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/app"
+)
+
+func TestBulkhead(t *testing.T) {
+	t.Parallel()
+
+	blocking := func(running chan<- struct{}, release <-chan struct{}) app.Command[command] {
+		return app.TestCommandHandler(func(_ context.Context, _ command) error {
+			running <- struct{}{}
+			<-release
+
+			return nil
+		})
+	}
+
+	t.Run("reject when full", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		b := app.NewBulkhead(logger, noop.NewMeterProvider(), "some-dependency", 1)
+
+		running, release := make(chan struct{}), make(chan struct{})
+		handler := app.NewBulkheadCommand(b, blocking(running, release))
+
+		go func() { _ = handler.H(t.Context(), command{}) }()
+
+		<-running
+
+		err := handler.H(t.Context(), command{})
+		assert.ErrorIs(t, err, app.ErrBulkheadFull)
+		logger.Contains(`msg="bulkhead is full"`)
+		logger.Contains(`bulkhead=some-dependency`)
+
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			_, err := app.NewBulkheadRequest(b, app.TestSuccessRequestHandler[request, response]()).H(t.Context(), request{})
+			return err == nil
+		}, time.Second, time.Millisecond, "slot is freed")
+	})
+
+	t.Run("wait for a free slot", func(t *testing.T) {
+		t.Parallel()
+
+		b := app.NewBulkhead(alog.Test(t), noop.NewMeterProvider(), "some-dependency", 1, app.WithMaxWait(time.Second))
+
+		running, release := make(chan struct{}), make(chan struct{})
+		handler := app.NewBulkheadCommand(b, blocking(running, release))
+
+		go func() { _ = handler.H(t.Context(), command{}) }()
+
+		<-running
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+		}()
+
+		_, err := app.NewBulkheadQuery(b, app.TestSuccessQueryHandler[query, response]()).H(t.Context(), query{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("stop waiting when ctx is done", func(t *testing.T) {
+		t.Parallel()
+
+		b := app.NewBulkhead(alog.Test(t), noop.NewMeterProvider(), "some-dependency", 1, app.WithMaxWait(time.Hour))
+
+		running, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+
+		go func() { _ = app.NewBulkheadCommand(b, blocking(running, release)).H(t.Context(), command{}) }()
+
+		<-running
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+
+		err := app.NewBulkheadJob(b, app.TestSuccessJobHandler[job]()).H(ctx, job{})
+		assert.ErrorIs(t, err, app.ErrBulkheadFull)
+	})
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewEndpoints returns Endpoints mounting the use cases on router.
+func NewEndpoints(router *echo.Group) *Endpoints {
+	return &Endpoints{
+		router:    router,
+		mu:        sync.Mutex{},
+		endpoints: []endpoint{},
+	}
+}
+
+// Endpoints mounts use cases as HTTP endpoints, so they don't need a hand-written controller,
+// see MountRequest, MountCommand, and MountQuery. All mounted use cases are documented in an OpenAPI document.
+type Endpoints struct {
+	router *echo.Group
+
+	mu        sync.Mutex
+	endpoints []endpoint
+}
+
+type endpoint struct {
+	method  string
+	path    string
+	useCase UseCase
+	status  int
+	summary string
+}
+
+// EndpointOption configures a mounted use case.
+type EndpointOption func(*endpoint)
+
+// WithStatusCode sets the status code of a successful response.
+// Without it, commands respond with 204 No Content and all other use cases with 200 OK.
+func WithStatusCode(code int) EndpointOption {
+	return func(e *endpoint) {
+		e.status = code
+	}
+}
+
+// WithSummary sets the summary of the endpoint in the OpenAPI document.
+func WithSummary(summary string) EndpointOption {
+	return func(e *endpoint) {
+		e.summary = summary
+	}
+}
+
+// MountRequest mounts the request on method and path of the router, e.g. POST /users.
+// The request is bound from the path parameters and the JSON body, for GET, DELETE, and HEAD
+// from the path and query parameters instead, see echo.DefaultBinder. The result is returned as JSON.
+//
+// Errors are returned as JSON problem, see RFC 9457: a ValidationError and ErrIdempotencyMismatch
+// with 422 Unprocessable Entity, ErrForbidden with 403 Forbidden, ErrIdempotencyConflict with 409 Conflict,
+// and ErrCircuitOpen or ErrBulkheadFull with 503 Service Unavailable. All other errors are passed to the error handler of echo.
+func MountRequest[Req any, Res any](e *Endpoints, method string, path string, req Request[Req, Res], opts ...EndpointOption) {
+	mount(e, method, path, useCaseOf[Req, Res](KindRequest), http.StatusOK, opts, func(ctx context.Context, in Req) (any, error) {
+		return req.H(ctx, in)
+	})
+}
+
+// MountCommand mounts the command on method and path of the router, see MountRequest.
+func MountCommand[C any](e *Endpoints, method string, path string, cmd Command[C], opts ...EndpointOption) {
+	mount(e, method, path, useCaseOf[C, any](KindCommand), http.StatusNoContent, opts, func(ctx context.Context, in C) (any, error) {
+		return nil, cmd.H(ctx, in)
+	})
+}
+
+// MountQuery mounts the query on method and path of the router, e.g. GET /users/:id, see MountRequest.
+func MountQuery[Q any, Res any](e *Endpoints, method string, path string, query Query[Q, Res], opts ...EndpointOption) {
+	mount(e, method, path, useCaseOf[Q, Res](KindQuery), http.StatusOK, opts, func(ctx context.Context, in Q) (any, error) {
+		return query.H(ctx, in)
+	})
+}
+
+func mount[In any](
+	e *Endpoints,
+	method string,
+	path string,
+	useCase UseCase,
+	status int,
+	opts []EndpointOption,
+	fn func(ctx context.Context, in In) (any, error),
+) {
+	ep := endpoint{method: method, path: path, useCase: useCase, status: status, summary: ""}
+	for _, opt := range opts {
+		opt(&ep)
+	}
+
+	route := e.router.Add(method, path, func(c echo.Context) error {
+		var in In
+
+		if err := c.Bind(&in); err != nil {
+			detail := err.Error()
+
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				detail = fmt.Sprint(httpErr.Message)
+			}
+
+			return problem(c, http.StatusBadRequest, map[string]any{"detail": detail})
+		}
+
+		res, err := fn(c.Request().Context(), in)
+		if err != nil {
+			return endpointError(c, err)
+		}
+
+		if ep.status == http.StatusNoContent {
+			return c.NoContent(ep.status) //nolint:wrapcheck // write the response only
+		}
+
+		return c.JSON(ep.status, res) //nolint:wrapcheck // write the response only
+	})
+
+	ep.path = route.Path
+
+	e.mu.Lock()
+	e.endpoints = append(e.endpoints, ep)
+	e.mu.Unlock()
+}
+
+// errorStatus returns the status code for the errors of the decorators.
+func errorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrIdempotencyMismatch):
+		return http.StatusUnprocessableEntity, true
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden, true
+	case errors.Is(err, ErrIdempotencyConflict):
+		return http.StatusConflict, true
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrBulkheadFull):
+		return http.StatusServiceUnavailable, true
+	default:
+		return 0, false
+	}
+}
+
+func endpointError(c echo.Context, err error) error {
+	if errors.Is(err, ErrValidation) {
+		return ValidationProblem(c, err)
+	}
+
+	status, ok := errorStatus(err)
+	if !ok {
+		return err
+	}
+
+	return problem(c, status, nil)
+}
+
+// problem writes a JSON problem, see RFC 9457, with the fields added.
+func problem(c echo.Context, status int, fields map[string]any) error {
+	body := map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"status": status,
+	}
+
+	for k, v := range fields {
+		body[k] = v
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/problem+json")
+
+	return c.JSON(status, body) //nolint:wrapcheck // write the response only
+}
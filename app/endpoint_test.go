@@ -0,0 +1,328 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/app"
+	"github.com/go-arrower/arrower/contexts/auth"
+)
+
+type (
+	showUserQuery struct {
+		ID      string `param:"id"`
+		Details bool   `query:"details"`
+	}
+	createUserRequest struct {
+		Name  string `json:"name"  validate:"required,min=2"`
+		Email string `json:"email" validate:"email"`
+		Role  string `json:"role"  validate:"oneof=admin user"`
+	}
+	user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	deleteUserCommand struct {
+		ID string `param:"id"`
+	}
+)
+
+func TestMountRequest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bind and respond with JSON", func(t *testing.T) {
+		t.Parallel()
+
+		e := echo.New()
+		endpoints := app.NewEndpoints(e.Group("/api"))
+
+		app.MountRequest(endpoints, http.MethodPost, "/users", app.NewValidatedRequest(nil,
+			app.TestRequestHandler(func(_ context.Context, req createUserRequest) (user, error) {
+				return user{ID: "1", Name: req.Name}, nil
+			}),
+		))
+
+		rec := serve(e, http.MethodPost, "/api/users", `{"name": "arrower", "email": "a@b.c", "role": "user"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"id": "1", "name": "arrower"}`, rec.Body.String())
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		t.Parallel()
+
+		e := echo.New()
+		endpoints := app.NewEndpoints(e.Group("/api"))
+
+		app.MountRequest(endpoints, http.MethodPost, "/users", app.NewValidatedRequest(nil,
+			app.TestSuccessRequestHandler[createUserRequest, user](),
+		))
+
+		rec := serve(e, http.MethodPost, "/api/users", `{"email": "a@b.c", "role": "user"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, rec.Body.String(), `"field":"Name"`)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		t.Parallel()
+
+		e := echo.New()
+		endpoints := app.NewEndpoints(e.Group("/api"))
+
+		app.MountRequest(endpoints, http.MethodPost, "/users", app.TestSuccessRequestHandler[createUserRequest, user]())
+
+		rec := serve(e, http.MethodPost, "/api/users", `{"name": `)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+
+		e := echo.New()
+		endpoints := app.NewEndpoints(e.Group("/api"))
+
+		app.MountRequest(endpoints, http.MethodPost, "/users",
+			app.TestRequestHandler(func(_ context.Context, _ createUserRequest) (user, error) {
+				return user{}, &app.ForbiddenError{UserID: auth.UserID(""), Command: "createUserRequest"}
+			}),
+		)
+
+		rec := serve(e, http.MethodPost, "/api/users", `{}`)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestMountQuery(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	endpoints := app.NewEndpoints(e.Group("/api"))
+
+	app.MountQuery(endpoints, http.MethodGet, "/users/:id",
+		app.TestQueryHandler(func(_ context.Context, query showUserQuery) (user, error) {
+			assert.True(t, query.Details)
+			return user{ID: query.ID, Name: "arrower"}, nil
+		}),
+	)
+
+	rec := serve(e, http.MethodGet, "/api/users/1337?details=true", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id": "1337", "name": "arrower"}`, rec.Body.String())
+}
+
+func TestMountCommand(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	endpoints := app.NewEndpoints(e.Group("/api"))
+
+	var deleted string
+
+	app.MountCommand(endpoints, http.MethodDelete, "/users/:id",
+		app.TestCommandHandler(func(_ context.Context, cmd deleteUserCommand) error {
+			deleted = cmd.ID
+			return nil
+		}),
+	)
+
+	rec := serve(e, http.MethodDelete, "/api/users/1337", "")
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "1337", deleted)
+}
+
+func TestEndpoints_OpenAPI(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	endpoints := app.NewEndpoints(e.Group("/api"))
+
+	app.MountRequest(endpoints, http.MethodPost, "/users", app.TestSuccessRequestHandler[createUserRequest, user](),
+		app.WithStatusCode(http.StatusCreated), app.WithSummary("create a user"))
+	app.MountQuery(endpoints, http.MethodGet, "/users/:id", app.TestSuccessQueryHandler[showUserQuery, user]())
+	app.MountCommand(endpoints, http.MethodDelete, "/users/:id", app.TestSuccessCommandHandler[deleteUserCommand]())
+
+	e.GET("/openapi.json", endpoints.OpenAPIHandler(app.OpenAPIInfo{Title: "test", Version: "v1"}))
+
+	rec := serve(e, http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var doc app.OpenAPI
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, app.OpenAPIInfo{Title: "test", Version: "v1"}, doc.Info)
+
+	t.Run("request", func(t *testing.T) {
+		t.Parallel()
+
+		op := doc.Paths["/api/users"]["post"]
+		assert.Equal(t, "app_test.createUserRequest", op.OperationID)
+		assert.Equal(t, "create a user", op.Summary)
+
+		body := op.RequestBody.Content["application/json"].Schema
+		assert.Equal(t, []string{"name"}, body.Required)
+		assert.Equal(t, 2, *body.Properties["name"].MinLength)
+		assert.Equal(t, "email", body.Properties["email"].Format)
+		assert.Equal(t, []string{"admin", "user"}, body.Properties["role"].Enum)
+
+		assert.Equal(t, "#/components/schemas/user", op.Responses["201"].Content["application/json"].Schema.Ref)
+		for _, status := range []string{"400", "403", "409", "422", "503"} {
+			assert.Equal(t, "#/components/schemas/Problem", op.Responses[status].Content["application/problem+json"].Schema.Ref)
+		}
+		assert.Contains(t, doc.Components.Schemas["user"].Properties, "id")
+	})
+
+	t.Run("query", func(t *testing.T) {
+		t.Parallel()
+
+		op := doc.Paths["/api/users/{id}"]["get"]
+		assert.Nil(t, op.RequestBody)
+		assert.Equal(t, []app.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &app.Schema{Type: "string"}},
+			{Name: "details", In: "query", Required: false, Schema: &app.Schema{Type: "boolean"}},
+		}, op.Parameters)
+	})
+
+	t.Run("command", func(t *testing.T) {
+		t.Parallel()
+
+		op := doc.Paths["/api/users/{id}"]["delete"]
+		assert.Contains(t, op.Responses, "204")
+		assert.Nil(t, op.Responses["204"].Content)
+	})
+}
+
+func TestEndpoints_OpenAPI_SchemaNames(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		Login string `json:"login"`
+	}
+
+	type Problem struct {
+		Reason string `json:"reason"`
+	}
+
+	endpoints := app.NewEndpoints(echo.New().Group("/api"))
+	app.MountQuery(endpoints, http.MethodGet, "/users/:id", app.TestSuccessQueryHandler[showUserQuery, user]())
+	app.MountQuery(endpoints, http.MethodGet, "/accounts/:id", app.TestSuccessQueryHandler[showUserQuery, user]())
+	app.MountRequest(endpoints, http.MethodPost, "/users", app.TestSuccessRequestHandler[createUserRequest, userOfPackage]())
+	app.MountRequest(endpoints, http.MethodPost, "/problems", app.TestSuccessRequestHandler[createUserRequest, Problem]())
+
+	doc := endpoints.OpenAPI(app.OpenAPIInfo{Title: "test", Version: "v1"})
+
+	ref := func(method string, path string) string {
+		return doc.Paths[path][method].Responses["200"].Content["application/json"].Schema.Ref
+	}
+
+	assert.Equal(t, "#/components/schemas/user", ref("get", "/api/users/{id}"))
+	assert.Equal(t, "#/components/schemas/user", ref("get", "/api/accounts/{id}"), "same type has the same name")
+	assert.Equal(t, "#/components/schemas/app_test.user", ref("post", "/api/users"))
+	assert.Equal(t, "#/components/schemas/app_test.Problem", ref("post", "/api/problems"))
+
+	assert.Contains(t, doc.Components.Schemas["user"].Properties, "login")
+	assert.Contains(t, doc.Components.Schemas["app_test.user"].Properties, "id")
+	assert.Contains(t, doc.Components.Schemas["app_test.Problem"].Properties, "reason")
+	assert.Contains(t, doc.Components.Schemas["Problem"].Properties, "status", "built-in schema is kept")
+}
+
+// userOfPackage refers to the package level user in functions declaring their own user.
+type userOfPackage = user
+
+func TestMountJSONRPC(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	registry := app.NewRegistry()
+
+	app.RegisterQuery(registry, app.TestQueryHandler(func(_ context.Context, query showUserQuery) (user, error) {
+		return user{ID: query.ID, Name: "arrower"}, nil
+	}))
+	app.RegisterRequest(registry, app.NewValidatedRequest(nil, app.TestSuccessRequestHandler[createUserRequest, user]()))
+	app.RegisterCommand(registry, app.TestFailureCommandHandler[deleteUserCommand]())
+
+	app.MountJSONRPC(e.Group("/api"), "/rpc", registry)
+
+	tests := map[string]struct {
+		body     string
+		expected string
+		status   int
+	}{
+		"call method": {
+			body:     `{"jsonrpc": "2.0", "method": "app_test.showUserQuery", "params": {"ID": "1337"}, "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "result": {"id": "1337", "name": "arrower"}, "id": 1}`,
+			status:   http.StatusOK,
+		},
+		"notification": {
+			body:     `{"jsonrpc": "2.0", "method": "app_test.showUserQuery", "params": {"ID": "1337"}}`,
+			expected: ``,
+			status:   http.StatusNoContent,
+		},
+		"parse error": {
+			body:     `{"jsonrpc": `,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "parse error"}, "id": null}`,
+			status:   http.StatusOK,
+		},
+		"invalid request": {
+			body:     `{"method": "app_test.showUserQuery", "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "invalid request"}, "id": 1}`,
+			status:   http.StatusOK,
+		},
+		"method not found": {
+			body:     `{"jsonrpc": "2.0", "method": "unknown", "id": "a"}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32601, "message": "method not found"}, "id": "a"}`,
+			status:   http.StatusOK,
+		},
+		"validation error": {
+			body: `{"jsonrpc": "2.0", "method": "app_test.createUserRequest", "params": {"name": "arrower", "email": "a@b.c", "role": "x"}, "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32602, "message": "validation failed", "data": [
+				{"field": "Role", "code": "oneof", "param": "admin user", "message": "must be one of: admin user"}
+			]}, "id": 1}`,
+			status: http.StatusOK,
+		},
+		"internal error": {
+			body:     `{"jsonrpc": "2.0", "method": "app_test.deleteUserCommand", "params": {"ID": "1337"}, "id": 1}`,
+			expected: `{"jsonrpc": "2.0", "error": {"code": -32603, "message": "internal error"}, "id": 1}`,
+			status:   http.StatusOK,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := serve(e, http.MethodPost, "/api/rpc", tt.body)
+
+			assert.Equal(t, tt.status, rec.Code)
+
+			if tt.expected != "" {
+				assert.JSONEq(t, tt.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+func serve(e *echo.Echo, method string, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
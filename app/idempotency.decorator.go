@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/go-arrower/arrower/contexts/auth"
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+const (
+	// CtxIdempotencyKey contains the idempotency key of the request, see IdempotencyKeyMiddleware.
+	CtxIdempotencyKey ctx2.CTXKey = "arrower.idempotency_key"
+
+	// IdempotencyKeyHeader is the header the IdempotencyKeyMiddleware reads the key from.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	// ErrIdempotencyConflict is returned, if a use case with the same idempotency key is still running.
+	ErrIdempotencyConflict = errors.New("request with the same idempotency key is in progress")
+	// ErrIdempotencyMismatch is returned, if an idempotency key is used again with a different request.
+	ErrIdempotencyMismatch = errors.New("idempotency key is used with a different request")
+	// ErrReplayed wraps the error of a use case, that is returned again for a duplicate idempotency key.
+	// The original error type is lost, only its message is stored.
+	ErrReplayed = errors.New("replayed")
+)
+
+// WithIdempotencyKey returns a ctx with the idempotency key set.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, CtxIdempotencyKey, key)
+}
+
+// IdempotencyKey returns the idempotency key of ctx, if one is set.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(CtxIdempotencyKey).(string)
+
+	return key, ok && key != ""
+}
+
+// IdempotencyKeyMiddleware puts the value of the Idempotency-Key header into the ctx of the request,
+// so the idempotent decorators can use it.
+func IdempotencyKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return next(c)
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "idempotency key is too long")
+		}
+
+		c.SetRequest(c.Request().WithContext(WithIdempotencyKey(c.Request().Context(), key)))
+
+		return next(c)
+	}
+}
+
+// IdempotencyRecord identifies a call of a use case with an idempotency key.
+// Keys are scoped to the user, so users cannot see the outcomes of each other by guessing a key.
+type IdempotencyRecord struct {
+	Key     string
+	Command string
+	// UserID is the user calling the use case, see auth.CurrentUserID, empty for anonymous calls.
+	UserID auth.UserID
+	// RequestHash is the hash of the request, to detect a key that is used again for a different request.
+	RequestHash string
+}
+
+// IdempotentOutcome is the stored outcome of a use case.
+type IdempotentOutcome struct {
+	// Result is the result of the use case as JSON, empty for commands.
+	Result json.RawMessage
+	// Error is the message of the error of the use case, empty if it succeeded.
+	Error string
+}
+
+// IdempotencyStore keeps the outcome of use cases called with an idempotency key.
+type IdempotencyStore interface {
+	// Begin reserves the record for a call of the use case.
+	// If the use case was called with the key by the same user before, within the retention of the store,
+	// the outcome of that call is returned. If the call is still running, ErrIdempotencyConflict is returned.
+	// If the call had a different RequestHash, ErrIdempotencyMismatch is returned.
+	Begin(ctx context.Context, record IdempotencyRecord) (*IdempotentOutcome, error)
+	// Complete stores the outcome of the call reserved by Begin.
+	Complete(ctx context.Context, record IdempotencyRecord, outcome IdempotentOutcome) error
+	// Release removes the reservation of Begin, so the use case can be called again with the key.
+	Release(ctx context.Context, record IdempotencyRecord) error
+}
+
+// NewIdempotentRequest calls the request only once for each idempotency key in ctx, see IdempotencyKey.
+// Calls with the same key by the same user return the stored result or error of the first call,
+// without calling the request again. Calls without a key are always passed through.
+// Reusing a key for a different request returns ErrIdempotencyMismatch.
+//
+// Errors, that are transient, see IsTransient, are not stored, so the client can retry.
+// Use it as the outermost decorator, in particular outside of NewTxRequest,
+// so the outcome is stored after the transaction is committed.
+func NewIdempotentRequest[Req any, Res any](store IdempotencyStore, req Request[Req, Res]) Request[Req, Res] {
+	return &requestIdempotencyDecorator[Req, Res]{
+		store: store,
+		base:  req,
+	}
+}
+
+type requestIdempotencyDecorator[Req any, Res any] struct {
+	store IdempotencyStore
+	base  Request[Req, Res]
+}
+
+func (d *requestIdempotencyDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	return idempotent(ctx, d.store, req, func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, req)
+	})
+}
+
+// NewIdempotentCommand calls the command only once for each idempotency key in ctx, see NewIdempotentRequest.
+func NewIdempotentCommand[C any](store IdempotencyStore, cmd Command[C]) Command[C] {
+	return &commandIdempotencyDecorator[C]{
+		store: store,
+		base:  cmd,
+	}
+}
+
+type commandIdempotencyDecorator[C any] struct {
+	store IdempotencyStore
+	base  Command[C]
+}
+
+func (d *commandIdempotencyDecorator[C]) H(ctx context.Context, cmd C) error {
+	_, err := idempotent(ctx, d.store, cmd, func(ctx context.Context) (*struct{}, error) {
+		return nil, d.base.H(ctx, cmd)
+	})
+
+	return err
+}
+
+func idempotent[Res any](
+	ctx context.Context,
+	store IdempotencyStore,
+	in any,
+	fn func(ctx context.Context) (Res, error),
+) (Res, error) {
+	var result Res
+
+	key, ok := IdempotencyKey(ctx)
+	if !ok {
+		return fn(ctx)
+	}
+
+	hash, err := requestHash(in)
+	if err != nil {
+		return result, err
+	}
+
+	record := IdempotencyRecord{
+		Key:         key,
+		Command:     commandName(in),
+		UserID:      auth.CurrentUserID(ctx),
+		RequestHash: hash,
+	}
+
+	outcome, err := store.Begin(ctx, record)
+	if errors.Is(err, ErrIdempotencyConflict) || errors.Is(err, ErrIdempotencyMismatch) {
+		return result, err
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("could not check idempotency key: %w", err)
+	}
+
+	if outcome != nil {
+		return replay[Res](*outcome)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = store.Release(context.WithoutCancel(ctx), record)
+			panic(r)
+		}
+	}()
+
+	result, err = fn(ctx)
+	if err != nil && IsTransient(err) {
+		_ = store.Release(context.WithoutCancel(ctx), record)
+		return result, err
+	}
+
+	outcome = &IdempotentOutcome{Result: nil, Error: ""}
+	if err != nil {
+		outcome.Error = err.Error()
+	} else if outcome.Result, err = json.Marshal(result); err != nil {
+		_ = store.Release(context.WithoutCancel(ctx), record)
+		return result, fmt.Errorf("could not store result for idempotency key: %w", err)
+	}
+
+	// the use case is done, so its outcome is returned, even if it cannot be stored.
+	// Duplicates get ErrIdempotencyConflict, until the store considers the reservation abandoned.
+	_ = store.Complete(context.WithoutCancel(ctx), record, *outcome)
+
+	return result, err
+}
+
+// requestHash returns the hash of the JSON representation of in.
+func requestHash(in any) (string, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return "", fmt.Errorf("could not hash request for idempotency key: %w", err)
+	}
+
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func replay[Res any](outcome IdempotentOutcome) (Res, error) {
+	var result Res
+
+	if outcome.Error != "" {
+		return result, fmt.Errorf("%w: %s", ErrReplayed, outcome.Error) //nolint:err113 // only the message is stored
+	}
+
+	if len(outcome.Result) != 0 {
+		if err := json.Unmarshal(outcome.Result, &result); err != nil {
+			return result, fmt.Errorf("could not load result for idempotency key: %w", err)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/app"
+	"github.com/go-arrower/arrower/contexts/auth"
+)
+
+func TestRequestIdempotencyDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	type counted struct {
+		Calls int
+	}
+
+	t.Run("replay result", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (counted, error) {
+				calls++
+				return counted{Calls: calls}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		res, err := handler.H(ctx, request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Calls)
+
+		res, err = handler.H(ctx, request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Calls, "result should be replayed")
+		assert.Equal(t, 1, calls, "request should be called once")
+
+		res, err = handler.H(app.WithIdempotencyKey(t.Context(), "other-key"), request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Calls)
+	})
+
+	t.Run("replay error", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				return response{}, errTemporary
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		_, err := handler.H(ctx, request{})
+		assert.ErrorIs(t, err, errTemporary)
+
+		_, err = handler.H(ctx, request{})
+		assert.ErrorIs(t, err, app.ErrReplayed)
+		assert.Contains(t, err.Error(), errTemporary.Error())
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				return response{}, nil
+			}),
+		)
+
+		_, _ = handler.H(t.Context(), request{})
+		_, _ = handler.H(t.Context(), request{})
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("transient error is not stored", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				if calls == 1 {
+					return response{}, app.Transient(errTemporary)
+				}
+
+				return response{}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		_, err := handler.H(ctx, request{})
+		assert.ErrorIs(t, err, errTemporary)
+
+		_, err = handler.H(ctx, request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("expired outcome", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(0),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				return response{}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		_, _ = handler.H(ctx, request{})
+		_, _ = handler.H(ctx, request{})
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("key is scoped to the user", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (counted, error) {
+				calls++
+				return counted{Calls: calls}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		res, err := handler.H(context.WithValue(ctx, auth.CtxUserID, auth.UserID("user-1")), request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, res.Calls)
+
+		res, err = handler.H(context.WithValue(ctx, auth.CtxUserID, auth.UserID("user-2")), request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, res.Calls, "other user should not get the result of user-1")
+	})
+
+	t.Run("key reused for a different request", func(t *testing.T) {
+		t.Parallel()
+
+		calls := 0
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ createUserRequest) (response, error) {
+				calls++
+				return response{}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+		_, err := handler.H(ctx, createUserRequest{Name: "arrower"})
+		assert.NoError(t, err)
+
+		_, err = handler.H(ctx, createUserRequest{Name: "other"})
+		assert.ErrorIs(t, err, app.ErrIdempotencyMismatch)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("conflict while in progress", func(t *testing.T) {
+		t.Parallel()
+
+		running := make(chan struct{})
+		release := make(chan struct{})
+
+		handler := app.NewIdempotentRequest(app.NewInMemoryIdempotencyStore(time.Hour),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				running <- struct{}{}
+				<-release
+
+				return response{}, nil
+			}),
+		)
+
+		ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+		done := make(chan error)
+
+		go func() {
+			_, err := handler.H(ctx, request{})
+			done <- err
+		}()
+
+		<-running
+
+		_, err := handler.H(ctx, request{})
+		assert.ErrorIs(t, err, app.ErrIdempotencyConflict)
+
+		close(release)
+		assert.NoError(t, <-done)
+	})
+}
+
+func TestCommandIdempotencyDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	handler := app.NewIdempotentCommand(app.NewInMemoryIdempotencyStore(time.Hour),
+		app.TestCommandHandler(func(_ context.Context, _ command) error {
+			calls++
+			return nil
+		}),
+	)
+
+	ctx := app.WithIdempotencyKey(t.Context(), "some-key")
+
+	assert.NoError(t, handler.H(ctx, command{}))
+	assert.NoError(t, handler.H(ctx, command{}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyKeyMiddleware(t *testing.T) {
+	t.Parallel()
+
+	t.Run("set key", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(app.IdempotencyKeyHeader, "some-key")
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		err := app.IdempotencyKeyMiddleware(func(c echo.Context) error {
+			key, ok := app.IdempotencyKey(c.Request().Context())
+			assert.True(t, ok)
+			assert.Equal(t, "some-key", key)
+
+			return nil
+		})(c)
+		assert.NoError(t, err)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		t.Parallel()
+
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+		err := app.IdempotencyKeyMiddleware(func(c echo.Context) error {
+			_, ok := app.IdempotencyKey(c.Request().Context())
+			assert.False(t, ok)
+
+			return nil
+		})(c)
+		assert.NoError(t, err)
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(app.IdempotencyKeyHeader, strings.Repeat("k", 256))
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		err := app.IdempotencyKeyMiddleware(func(_ echo.Context) error { return nil })(c)
+
+		var httpErr *echo.HTTPError
+		assert.True(t, errors.As(err, &httpErr))
+		assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	})
+}
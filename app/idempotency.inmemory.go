@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyRetention is how long the outcome of a use case is kept by the IdempotencyStores.
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+// abandonedAfter is the time after which a reservation, that is not completed, is considered abandoned,
+// e.g. because the instance crashed, and the use case can be called again.
+const abandonedAfter = time.Minute
+
+// NewInMemoryIdempotencyStore returns an IdempotencyStore keeping the outcomes in memory, e.g. for tests.
+// Outcomes older than retention are removed.
+func NewInMemoryIdempotencyStore(retention time.Duration) *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		retention: retention,
+		mu:        sync.Mutex{},
+		entries:   map[idempotencyScope]idempotencyEntry{},
+	}
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore for a single instance, see NewInMemoryIdempotencyStore.
+type InMemoryIdempotencyStore struct {
+	retention time.Duration
+
+	mu      sync.Mutex
+	entries map[idempotencyScope]idempotencyEntry
+}
+
+var _ IdempotencyStore = (*InMemoryIdempotencyStore)(nil)
+
+// idempotencyScope is the part of an IdempotencyRecord that identifies it.
+type idempotencyScope struct {
+	key     string
+	command string
+	userID  string
+}
+
+func scopeOf(record IdempotencyRecord) idempotencyScope {
+	return idempotencyScope{key: record.Key, command: record.Command, userID: string(record.UserID)}
+}
+
+type idempotencyEntry struct {
+	createdAt   time.Time
+	completedAt time.Time
+	requestHash string
+	outcome     IdempotentOutcome
+}
+
+func (s *InMemoryIdempotencyStore) Begin(_ context.Context, record IdempotencyRecord) (*IdempotentOutcome, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	entry, exists := s.entries[scopeOf(record)]
+	if exists {
+		completed := !entry.completedAt.IsZero()
+		valid := completed && now.Sub(entry.completedAt) < s.retention
+		running := !completed && now.Sub(entry.createdAt) < abandonedAfter
+
+		if (valid || running) && entry.requestHash != record.RequestHash {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyMismatch, record.Key)
+		}
+
+		if valid {
+			outcome := entry.outcome
+			return &outcome, nil
+		}
+
+		if running {
+			return nil, fmt.Errorf("%w: %s", ErrIdempotencyConflict, record.Key)
+		}
+	}
+
+	s.entries[scopeOf(record)] = idempotencyEntry{
+		createdAt:   now,
+		completedAt: time.Time{},
+		requestHash: record.RequestHash,
+		outcome:     IdempotentOutcome{},
+	}
+
+	return nil, nil //nolint:nilnil // no outcome means the use case has to be called
+}
+
+func (s *InMemoryIdempotencyStore) Complete(_ context.Context, record IdempotencyRecord, outcome IdempotentOutcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[scopeOf(record)]
+	entry.completedAt = time.Now()
+	entry.outcome = outcome
+	s.entries[scopeOf(record)] = entry
+
+	return nil
+}
+
+func (s *InMemoryIdempotencyStore) Release(_ context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, scopeOf(record))
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewPostgresIdempotencyStore returns an IdempotencyStore keeping the outcomes in the table arrower.idempotency_key,
+// so duplicates are detected across all instances. Outcomes older than retention are ignored,
+// use Prune to delete them, e.g. in a scheduled job.
+func NewPostgresIdempotencyStore(pgx *pgxpool.Pool, retention time.Duration) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{
+		pgx:       pgx,
+		retention: retention,
+	}
+}
+
+// PostgresIdempotencyStore is an IdempotencyStore, see NewPostgresIdempotencyStore.
+type PostgresIdempotencyStore struct {
+	pgx       *pgxpool.Pool
+	retention time.Duration
+}
+
+var _ IdempotencyStore = (*PostgresIdempotencyStore)(nil)
+
+func (s *PostgresIdempotencyStore) Begin(ctx context.Context, record IdempotencyRecord) (*IdempotentOutcome, error) {
+	// reserve the key, if it is new, its outcome expired, or its reservation is abandoned
+	var reserved bool
+
+	err := s.pgx.QueryRow(ctx, `
+		INSERT INTO arrower.idempotency_key (key, command, user_id, request_hash) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key, command, user_id) DO UPDATE
+			SET created_at = NOW(), completed_at = NULL, request_hash = $4, result = NULL, error = NULL
+			WHERE (idempotency_key.completed_at IS NULL AND idempotency_key.created_at < NOW() - make_interval(secs => $5))
+			   OR idempotency_key.completed_at < NOW() - make_interval(secs => $6)
+		RETURNING TRUE;`,
+		record.Key, record.Command, record.UserID, record.RequestHash, abandonedAfter.Seconds(), s.retention.Seconds(),
+	).Scan(&reserved)
+	if err == nil {
+		return nil, nil //nolint:nilnil // no outcome means the use case has to be called
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("could not reserve idempotency key: %w", err)
+	}
+
+	var (
+		completedAt *time.Time
+		hash        string
+		result      []byte
+		errMsg      *string
+	)
+
+	err = s.pgx.QueryRow(ctx, `
+		SELECT completed_at, request_hash, result, error FROM arrower.idempotency_key
+		WHERE key = $1 AND command = $2 AND user_id = $3;`,
+		record.Key, record.Command, record.UserID,
+	).Scan(&completedAt, &hash, &result, &errMsg)
+	if err != nil {
+		return nil, fmt.Errorf("could not load idempotency key: %w", err)
+	}
+
+	if hash != record.RequestHash {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyMismatch, record.Key)
+	}
+
+	if completedAt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyConflict, record.Key)
+	}
+
+	outcome := &IdempotentOutcome{Result: result, Error: ""}
+	if errMsg != nil {
+		outcome.Error = *errMsg
+	}
+
+	return outcome, nil
+}
+
+func (s *PostgresIdempotencyStore) Complete(ctx context.Context, record IdempotencyRecord, outcome IdempotentOutcome) error {
+	var errMsg *string
+	if outcome.Error != "" {
+		errMsg = &outcome.Error
+	}
+
+	var result []byte
+	if len(outcome.Result) != 0 {
+		result = outcome.Result
+	}
+
+	_, err := s.pgx.Exec(ctx, `
+		UPDATE arrower.idempotency_key SET completed_at = NOW(), result = $4, error = $5
+		WHERE key = $1 AND command = $2 AND user_id = $3;`,
+		record.Key, record.Command, record.UserID, result, errMsg,
+	)
+	if err != nil {
+		return fmt.Errorf("could not complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresIdempotencyStore) Release(ctx context.Context, record IdempotencyRecord) error {
+	_, err := s.pgx.Exec(ctx, `DELETE FROM arrower.idempotency_key WHERE key = $1 AND command = $2 AND user_id = $3;`,
+		record.Key, record.Command, record.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("could not release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Prune deletes all outcomes older than the retention.
+func (s *PostgresIdempotencyStore) Prune(ctx context.Context) error {
+	_, err := s.pgx.Exec(ctx, `DELETE FROM arrower.idempotency_key WHERE created_at < NOW() - make_interval(secs => $1);`,
+		max(s.retention, abandonedAfter).Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("could not prune idempotency keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+//go:build integration
+
+package app_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/app"
+)
+
+func TestPostgresIdempotencyStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("replay outcome", func(t *testing.T) {
+		t.Parallel()
+
+		store := app.NewPostgresIdempotencyStore(pgHandler.NewTestDatabase(), time.Hour)
+		record := app.IdempotencyRecord{Key: "some-key", Command: "app_test.request", UserID: "", RequestHash: "hash"}
+
+		outcome, err := store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+		assert.Nil(t, outcome)
+
+		_, err = store.Begin(t.Context(), record)
+		assert.ErrorIs(t, err, app.ErrIdempotencyConflict)
+
+		err = store.Complete(t.Context(), record, app.IdempotentOutcome{Result: json.RawMessage(`{"Calls":1}`), Error: ""})
+		assert.NoError(t, err)
+
+		outcome, err = store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"Calls":1}`, string(outcome.Result))
+		assert.Empty(t, outcome.Error)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		t.Parallel()
+
+		store := app.NewPostgresIdempotencyStore(pgHandler.NewTestDatabase(), time.Hour)
+		record := app.IdempotencyRecord{Key: "some-key", Command: "app_test.request", UserID: "", RequestHash: "hash"}
+
+		_, err := store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+
+		err = store.Release(t.Context(), record)
+		assert.NoError(t, err)
+
+		outcome, err := store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+		assert.Nil(t, outcome)
+	})
+
+	t.Run("expired outcome", func(t *testing.T) {
+		t.Parallel()
+
+		store := app.NewPostgresIdempotencyStore(pgHandler.NewTestDatabase(), 0)
+		record := app.IdempotencyRecord{Key: "some-key", Command: "app_test.request", UserID: "", RequestHash: "hash"}
+
+		_, _ = store.Begin(t.Context(), record)
+		_ = store.Complete(t.Context(), record, app.IdempotentOutcome{Result: nil, Error: "some error"})
+
+		outcome, err := store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+		assert.Nil(t, outcome, "outcome should be expired")
+
+		assert.NoError(t, store.Prune(t.Context()))
+	})
+
+	t.Run("scoped to the user", func(t *testing.T) {
+		t.Parallel()
+
+		store := app.NewPostgresIdempotencyStore(pgHandler.NewTestDatabase(), time.Hour)
+		record := app.IdempotencyRecord{Key: "some-key", Command: "app_test.request", UserID: "user-1", RequestHash: "hash"}
+
+		_, _ = store.Begin(t.Context(), record)
+		_ = store.Complete(t.Context(), record, app.IdempotentOutcome{Result: json.RawMessage(`{}`), Error: ""})
+
+		record.UserID = "user-2"
+
+		outcome, err := store.Begin(t.Context(), record)
+		assert.NoError(t, err)
+		assert.Nil(t, outcome, "other user should not get the outcome of user-1")
+	})
+
+	t.Run("different request", func(t *testing.T) {
+		t.Parallel()
+
+		store := app.NewPostgresIdempotencyStore(pgHandler.NewTestDatabase(), time.Hour)
+		record := app.IdempotencyRecord{Key: "some-key", Command: "app_test.request", UserID: "", RequestHash: "hash"}
+
+		_, _ = store.Begin(t.Context(), record)
+		_ = store.Complete(t.Context(), record, app.IdempotentOutcome{Result: json.RawMessage(`{}`), Error: ""})
+
+		record.RequestHash = "other-hash"
+
+		_, err := store.Begin(t.Context(), record)
+		assert.ErrorIs(t, err, app.ErrIdempotencyMismatch)
+	})
+}
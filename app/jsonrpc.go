@@ -0,0 +1,103 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes of JSON-RPC 2.0, see https://www.jsonrpc.org/specification#error_object.
+const (
+	jsonRPCParseError     = -32700
+	jsonRPCInvalidRequest = -32600
+	jsonRPCMethodNotFound = -32601
+	jsonRPCInvalidParams  = -32602
+	jsonRPCInternalError  = -32603
+	jsonRPCUseCaseError   = -32000
+)
+
+// MountJSONRPC mounts a JSON-RPC 2.0 endpoint on path of the router, calling the use cases of registry.
+// The method is the name of the use case, see UseCase, and the params are its message as JSON object, e.g.:
+//
+//	{"jsonrpc": "2.0", "method": "auth/application.ShowUserQuery", "params": {"UserID": "..."}, "id": 1}
+//
+// All use cases of the registry can be called, so use a Registry holding only the use cases to expose.
+// Errors of the use cases, that are not returned by the decorators, are hidden from the caller,
+// log them with the decorators of the registry, e.g. Instrumented. Batch requests are not supported.
+func MountJSONRPC(router *echo.Group, path string, registry *Registry) {
+	router.POST(path, func(c echo.Context) error {
+		var req struct {
+			JSONRPC string          `json:"jsonrpc"`
+			Method  string          `json:"method"`
+			Params  json.RawMessage `json:"params"`
+			ID      json.RawMessage `json:"id"`
+		}
+
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return jsonRPCError(c, nil, jsonRPCParseError, "parse error", nil)
+		}
+
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			return jsonRPCError(c, req.ID, jsonRPCInvalidRequest, "invalid request", nil)
+		}
+
+		uc, ok := registry.lookup(req.Method)
+		if !ok {
+			return jsonRPCError(c, req.ID, jsonRPCMethodNotFound, "method not found", nil)
+		}
+
+		in := reflect.New(uc.useCase.In)
+		if len(req.Params) != 0 {
+			if err := json.Unmarshal(req.Params, in.Interface()); err != nil {
+				return jsonRPCError(c, req.ID, jsonRPCInvalidParams, "invalid params", nil)
+			}
+		}
+
+		res, err := uc.handler.H(c.Request().Context(), in.Elem().Interface())
+		if err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				return jsonRPCError(c, req.ID, jsonRPCInvalidParams, ErrValidation.Error(), validationErr.Fields)
+			}
+
+			if status, ok := errorStatus(err); ok {
+				return jsonRPCError(c, req.ID, jsonRPCUseCaseError, http.StatusText(status), nil)
+			}
+
+			return jsonRPCError(c, req.ID, jsonRPCInternalError, "internal error", nil)
+		}
+
+		if len(req.ID) == 0 { // notification
+			return c.NoContent(http.StatusNoContent) //nolint:wrapcheck // write the response only
+		}
+
+		return c.JSON(http.StatusOK, map[string]any{ //nolint:wrapcheck // write the response only
+			"jsonrpc": "2.0",
+			"result":  res,
+			"id":      req.ID,
+		})
+	})
+}
+
+func jsonRPCError(c echo.Context, id json.RawMessage, code int, message string, data any) error {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	rpcErr := map[string]any{
+		"code":    code,
+		"message": message,
+	}
+	if data != nil {
+		rpcErr["data"] = data
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{ //nolint:wrapcheck // write the response only
+		"jsonrpc": "2.0",
+		"error":   rpcErr,
+		"id":      id,
+	})
+}
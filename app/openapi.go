@@ -0,0 +1,455 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+	mimeJSON       = "application/json"
+	mimeProblem    = "application/problem+json"
+)
+
+// OpenAPI is an OpenAPI 3 document, see https://spec.openapis.org/oas/v3.0.3.
+// Only the parts required to describe the mounted use cases are supported.
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is the JSON schema of a type.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// OpenAPI returns the document describing all mounted use cases.
+// The schemas are generated from the types of the use cases, using the tags
+// json, param, and query for the names, and validate for the constraints, e.g. required or min.
+func (e *Endpoints) OpenAPI(info OpenAPIInfo) *OpenAPI {
+	e.mu.Lock()
+	endpoints := append([]endpoint(nil), e.endpoints...)
+	e.mu.Unlock()
+
+	gen := &schemaGenerator{
+		schemas: map[string]*Schema{
+			"Problem": problemSchema(),
+		},
+		names: map[reflect.Type]string{},
+	}
+
+	doc := &OpenAPI{
+		OpenAPI:    openAPIVersion,
+		Info:       info,
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: gen.schemas},
+	}
+
+	for _, ep := range endpoints {
+		apiPath := openAPIPath(ep.path)
+		if doc.Paths[apiPath] == nil {
+			doc.Paths[apiPath] = map[string]*Operation{}
+		}
+
+		doc.Paths[apiPath][strings.ToLower(ep.method)] = gen.operation(ep)
+	}
+
+	return doc
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON, e.g.:
+//
+//	router.GET("/openapi.json", endpoints.OpenAPIHandler(app.OpenAPIInfo{Title: "My API", Version: "v1"}))
+func (e *Endpoints) OpenAPIHandler(info OpenAPIInfo) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, e.OpenAPI(info)) //nolint:wrapcheck // write the response only
+	}
+}
+
+var pathParam = regexp.MustCompile(`:([^/]+)`)
+
+// openAPIPath converts the path parameters of echo, e.g. /users/:id, to /users/{id}.
+func openAPIPath(path string) string {
+	return pathParam.ReplaceAllString(path, "{$1}")
+}
+
+func problemSchema() *Schema {
+	return &Schema{ //nolint:exhaustruct // only the properties are relevant
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":   {Type: "string"},
+			"title":  {Type: "string"},
+			"status": {Type: "integer"},
+			"detail": {Type: "string"},
+			"errors": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"field":   {Type: "string"},
+					"code":    {Type: "string"},
+					"message": {Type: "string"},
+				},
+			}},
+		},
+	}
+}
+
+type schemaGenerator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) operation(ep endpoint) *Operation {
+	op := &Operation{
+		OperationID: ep.useCase.Name,
+		Summary:     ep.summary,
+		Tags:        nil,
+		Parameters:  nil,
+		RequestBody: nil,
+		Responses:   map[string]Response{},
+	}
+
+	if contextName, _, found := strings.Cut(ep.useCase.Name, "/"); found {
+		op.Tags = []string{contextName}
+	}
+
+	in := derefType(ep.useCase.In)
+	if in.Kind() == reflect.Struct {
+		op.Parameters = g.parameters(in, !hasBody(ep.method))
+
+		if body := g.object(in, isBodyField); len(body.Properties) > 0 && hasBody(ep.method) {
+			op.RequestBody = &RequestBody{
+				Required: len(body.Required) > 0,
+				Content:  map[string]MediaType{mimeJSON: {Schema: body}},
+			}
+		}
+	}
+
+	ok := Response{Description: http.StatusText(ep.status), Content: nil}
+	if ep.useCase.Out != nil && ep.status != http.StatusNoContent {
+		ok.Content = map[string]MediaType{mimeJSON: {Schema: g.schema(ep.useCase.Out)}}
+	}
+
+	problem := map[string]MediaType{mimeProblem: {Schema: &Schema{Ref: "#/components/schemas/Problem"}}} //nolint:exhaustruct,lll // reference only
+
+	op.Responses[strconv.Itoa(ep.status)] = ok
+	op.Responses[strconv.Itoa(http.StatusBadRequest)] = Response{Description: "invalid request", Content: problem}
+	op.Responses[strconv.Itoa(http.StatusForbidden)] = Response{Description: "forbidden", Content: problem}
+	op.Responses[strconv.Itoa(http.StatusConflict)] = Response{Description: "idempotency key in use", Content: problem}
+	op.Responses[strconv.Itoa(http.StatusUnprocessableEntity)] = Response{
+		Description: "validation failed or idempotency key reused for a different request",
+		Content:     problem,
+	}
+	op.Responses[strconv.Itoa(http.StatusServiceUnavailable)] = Response{Description: "service unavailable", Content: problem}
+
+	return op
+}
+
+// hasBody reports if the body is bound for the method, otherwise the query parameters are bound, see echo.DefaultBinder.
+func hasBody(method string) bool {
+	return method != http.MethodGet && method != http.MethodDelete && method != http.MethodHead
+}
+
+func (g *schemaGenerator) parameters(t reflect.Type, withQuery bool) []Parameter {
+	var params []Parameter
+
+	locations := []string{"param"}
+	if withQuery {
+		locations = append(locations, "query")
+	}
+
+	for _, f := range fields(t) {
+		for _, in := range locations {
+			name := tagName(f.Tag.Get(in))
+			if name == "" {
+				continue
+			}
+
+			location := "query"
+			if in == "param" {
+				location = "path"
+			}
+
+			schema := g.schema(f.Type)
+			if schema.Ref == "" {
+				applyValidation(schema, f)
+			}
+
+			params = append(params, Parameter{
+				Name:     name,
+				In:       location,
+				Required: location == "path" || isRequired(f),
+				Schema:   schema,
+			})
+		}
+	}
+
+	return params
+}
+
+// isBodyField reports if the field is bound from the JSON body.
+func isBodyField(f reflect.StructField) bool {
+	_, hasJSON := f.Tag.Lookup("json")
+	_, hasParam := f.Tag.Lookup("param")
+	_, hasQuery := f.Tag.Lookup("query")
+
+	return hasJSON || (!hasParam && !hasQuery)
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	t = derefType(t)
+
+	if t == reflect.TypeFor[time.Time]() {
+		return &Schema{Type: "string", Format: "date-time"} //nolint:exhaustruct // only type is relevant
+	}
+
+	switch t.Kind() { //nolint:exhaustive // all other kinds are described by an empty schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"} //nolint:exhaustruct // only type is relevant
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"} //nolint:exhaustruct // only type is relevant
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"} //nolint:exhaustruct // only type is relevant
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"} //nolint:exhaustruct // only type is relevant
+	case reflect.String:
+		return &Schema{Type: "string"} //nolint:exhaustruct // only type is relevant
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"} //nolint:exhaustruct // only type is relevant
+		}
+
+		return &Schema{Type: "array", Items: g.schema(t.Elem())} //nolint:exhaustruct // only type is relevant
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())} //nolint:exhaustruct // only type is relevant
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, isBodyField)
+		}
+
+		name, exists := g.names[t]
+		if !exists {
+			name = g.name(t)
+			g.names[t] = name
+			g.schemas[name] = &Schema{} //nolint:exhaustruct // placeholder for recursive types
+			g.schemas[name] = g.object(t, isBodyField)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name} //nolint:exhaustruct // reference only
+	default:
+		return &Schema{} //nolint:exhaustruct // any value
+	}
+}
+
+// name returns the name of t in the components.
+// If the name is taken, e.g. by a type of another package, it is prefixed with the package, e.g. app.User,
+// and numbered if that is taken as well.
+func (g *schemaGenerator) name(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+
+	pkg := path.Base(t.PkgPath())
+
+	name = pkg + "." + t.Name()
+	for i := 2; g.schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%s.%s%d", pkg, t.Name(), i)
+	}
+
+	return name
+}
+
+// object returns the schema of the struct t with all fields, that match the filter.
+func (g *schemaGenerator) object(t reflect.Type, filter func(f reflect.StructField) bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}} //nolint:exhaustruct // only properties are relevant
+
+	for _, f := range fields(t) {
+		if !filter(f) {
+			continue
+		}
+
+		name, omitEmpty := jsonName(f)
+		if name == "" {
+			continue
+		}
+
+		prop := g.schema(f.Type)
+		if prop.Ref == "" {
+			applyValidation(prop, f)
+		}
+
+		schema.Properties[name] = prop
+
+		if isRequired(f) && !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// fields returns the exported fields of t, including the ones of embedded structs, as encoding/json flattens them.
+func fields(t reflect.Type) []reflect.StructField {
+	var fs []reflect.StructField
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		if f.Anonymous && derefType(f.Type).Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			fs = append(fs, fields(derefType(f.Type))...)
+			continue
+		}
+
+		if f.IsExported() {
+			fs = append(fs, f)
+		}
+	}
+
+	return fs
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, strings.Contains(opts, "omitempty")
+}
+
+func tagName(tag string) string {
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyValidation adds the constraints of the validate tag of f to the schema.
+func applyValidation(schema *Schema, f reflect.StructField) {
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "gte":
+			applyBound(schema, param, &schema.MinLength, &schema.Minimum, &schema.MinItems)
+		case "max", "lte":
+			applyBound(schema, param, &schema.MaxLength, &schema.Maximum, &schema.MaxItems)
+		case "len":
+			applyBound(schema, param, &schema.MinLength, &schema.Minimum, &schema.MinItems)
+			applyBound(schema, param, &schema.MaxLength, &schema.Maximum, &schema.MaxItems)
+		}
+	}
+}
+
+func applyBound(schema *Schema, param string, length **int, number **float64, items **int) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch schema.Type {
+	case "string":
+		l := int(value)
+		*length = &l
+	case "integer", "number":
+		*number = &value
+	case "array":
+		l := int(value)
+		*items = &l
+	}
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/go-arrower/arrower/alog"
+)
+
+// ErrNotRegistered is returned by Dispatch, if no use case is registered for the message.
+var ErrNotRegistered = errors.New("use case not registered")
+
+// Kind is the kind of use case, e.g. a Request or a Command.
+type Kind string
+
+const (
+	KindRequest Kind = "request"
+	KindCommand Kind = "command"
+	KindQuery   Kind = "query"
+	KindJob     Kind = "job"
+)
+
+// UseCase describes a use case registered in a Registry.
+type UseCase struct {
+	// Name is the name of the use case, as it is used in the logs and metrics.
+	Name string
+	Kind Kind
+	// In is the type of the message the use case is registered under.
+	In reflect.Type
+	// Out is the type of the result, nil for commands and jobs.
+	Out reflect.Type
+}
+
+// Decorator wraps a use case of the Registry, e.g. with NewLoggedRequest.
+// The use case is passed as Request of any type, independent of its Kind,
+// so one Decorator can be applied to all use cases.
+type Decorator func(useCase UseCase, next Request[any, any]) Request[any, any]
+
+// Instrumented is a Decorator adding tracing, metrics, and logging to all use cases,
+// the same as NewInstrumentedRequest and its siblings.
+func Instrumented(traceProvider trace.TracerProvider, meterProvider metric.MeterProvider, logger alog.Logger) Decorator {
+	return func(useCase UseCase, next Request[any, any]) Request[any, any] {
+		switch useCase.Kind {
+		case KindCommand:
+			return &unaryRequest{base: NewInstrumentedCommand[any](traceProvider, meterProvider, logger, &unaryHandler{base: next})}
+		case KindQuery:
+			return NewInstrumentedQuery[any, any](traceProvider, meterProvider, logger, next)
+		case KindJob:
+			return &unaryRequest{base: NewInstrumentedJob[any](traceProvider, meterProvider, logger, &unaryHandler{base: next})}
+		case KindRequest:
+			return NewInstrumentedRequest(traceProvider, meterProvider, logger, next)
+		default:
+			return next
+		}
+	}
+}
+
+// NewRegistry returns an empty Registry.
+// The decorators are applied to each use case registered, the first decorator is called first.
+func NewRegistry(decorators ...Decorator) *Registry {
+	return &Registry{
+		decorators: decorators,
+		mu:         sync.RWMutex{},
+		useCases:   map[reflect.Type]registered{},
+	}
+}
+
+// Registry holds the use cases of the application under the type of their message,
+// so they can be called by Dispatch and listed by UseCases.
+// Register use cases with RegisterRequest, RegisterCommand, RegisterQuery, and RegisterJob.
+type Registry struct {
+	decorators []Decorator
+
+	mu       sync.RWMutex
+	useCases map[reflect.Type]registered
+}
+
+type registered struct {
+	useCase UseCase
+	handler Request[any, any]
+}
+
+// Dispatch calls the use case registered for the type of msg and returns its result.
+// Commands and jobs return a nil result.
+func (r *Registry) Dispatch(ctx context.Context, msg any) (any, error) {
+	r.mu.RLock()
+	uc, ok := r.useCases[reflect.TypeOf(msg)]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotRegistered, msg)
+	}
+
+	return uc.handler.H(ctx, msg) //nolint:wrapcheck // return the original error of the use case
+}
+
+// UseCases returns all registered use cases sorted by name.
+func (r *Registry) UseCases() []UseCase {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	useCases := make([]UseCase, 0, len(r.useCases))
+	for _, uc := range r.useCases {
+		useCases = append(useCases, uc.useCase)
+	}
+
+	slices.SortFunc(useCases, func(a, b UseCase) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return useCases
+}
+
+// lookup returns the use case registered with the name, see UseCase.
+func (r *Registry) lookup(name string) (registered, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, uc := range r.useCases {
+		if uc.useCase.Name == name {
+			return uc, true
+		}
+	}
+
+	return registered{}, false //nolint:exhaustruct // not found
+}
+
+// register decorates the handler and adds it to the registry.
+// It panics, if a use case is registered for the same message type already.
+func (r *Registry) register(useCase UseCase, handler Request[any, any]) Request[any, any] {
+	for i := len(r.decorators) - 1; i >= 0; i-- {
+		handler = r.decorators[i](useCase, handler)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if uc, exists := r.useCases[useCase.In]; exists {
+		panic(fmt.Sprintf("arrower: use case %s registered twice for %s", uc.useCase.Name, useCase.In))
+	}
+
+	r.useCases[useCase.In] = registered{useCase: useCase, handler: handler}
+
+	return handler
+}
+
+// Dispatch calls the use case registered for the type of msg and returns its result as Res,
+// see Registry.Dispatch.
+func Dispatch[Res any](ctx context.Context, r *Registry, msg any) (Res, error) {
+	res, err := r.Dispatch(ctx, msg)
+
+	result, _ := res.(Res)
+
+	return result, err
+}
+
+// RegisterRequest registers the request in r and returns it with the decorators of r applied.
+// It panics, if a use case is registered for Req already.
+func RegisterRequest[Req any, Res any](r *Registry, req Request[Req, Res]) Request[Req, Res] {
+	handler := r.register(useCaseOf[Req, Res](KindRequest), &anyRequest[Req, Res]{base: req})
+
+	return &typedRequest[Req, Res]{base: handler}
+}
+
+// RegisterCommand registers the command in r and returns it with the decorators of r applied.
+// It panics, if a use case is registered for C already.
+func RegisterCommand[C any](r *Registry, cmd Command[C]) Command[C] {
+	handler := r.register(useCaseOf[C, any](KindCommand), &anyUnary[C]{base: cmd})
+
+	return &typedUnary[C]{base: handler}
+}
+
+// RegisterQuery registers the query in r and returns it with the decorators of r applied.
+// It panics, if a use case is registered for Q already.
+func RegisterQuery[Q any, Res any](r *Registry, query Query[Q, Res]) Query[Q, Res] {
+	handler := r.register(useCaseOf[Q, Res](KindQuery), &anyRequest[Q, Res]{base: query})
+
+	return &typedRequest[Q, Res]{base: handler}
+}
+
+// RegisterJob registers the job in r and returns it with the decorators of r applied.
+// It panics, if a use case is registered for J already.
+func RegisterJob[J any](r *Registry, job Job[J]) Job[J] {
+	handler := r.register(useCaseOf[J, any](KindJob), &anyUnary[J]{base: job})
+
+	return &typedUnary[J]{base: handler}
+}
+
+func useCaseOf[In any, Out any](kind Kind) UseCase {
+	var out reflect.Type
+	if kind == KindRequest || kind == KindQuery {
+		out = reflect.TypeFor[Out]()
+	}
+
+	return UseCase{
+		Name: commandName(*new(In)),
+		Kind: kind,
+		In:   reflect.TypeFor[In](),
+		Out:  out,
+	}
+}
+
+// anyRequest erases the types of a use case, so it can be decorated and dispatched.
+type anyRequest[Req any, Res any] struct {
+	base Request[Req, Res]
+}
+
+func (h *anyRequest[Req, Res]) H(ctx context.Context, req any) (any, error) {
+	in, ok := req.(Req)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotRegistered, req)
+	}
+
+	return h.base.H(ctx, in) //nolint:wrapcheck // return the original error of the use case
+}
+
+// anyUnary erases the type of a command or job, so it can be decorated and dispatched.
+type anyUnary[C any] struct {
+	base Command[C]
+}
+
+func (h *anyUnary[C]) H(ctx context.Context, cmd any) (any, error) {
+	in, ok := cmd.(C)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotRegistered, cmd)
+	}
+
+	return nil, h.base.H(ctx, in)
+}
+
+// typedRequest restores the types of a use case erased by anyRequest.
+type typedRequest[Req any, Res any] struct {
+	base Request[any, any]
+}
+
+func (h *typedRequest[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	res, err := h.base.H(ctx, req)
+
+	result, _ := res.(Res)
+
+	return result, err //nolint:wrapcheck // return the original error of the use case
+}
+
+// typedUnary restores the type of a command or job erased by anyRequest.
+type typedUnary[C any] struct {
+	base Request[any, any]
+}
+
+func (h *typedUnary[C]) H(ctx context.Context, cmd C) error {
+	_, err := h.base.H(ctx, cmd)
+
+	return err //nolint:wrapcheck // return the original error of the use case
+}
+
+// unaryHandler calls a Request as command or job, so the decorators of commands and jobs can wrap it.
+type unaryHandler struct {
+	base Request[any, any]
+}
+
+func (h *unaryHandler) H(ctx context.Context, cmd any) error {
+	_, err := h.base.H(ctx, cmd)
+
+	return err //nolint:wrapcheck // return the original error of the use case
+}
+
+// unaryRequest calls a command or job as Request again, after it is decorated.
+type unaryRequest struct {
+	base Command[any]
+}
+
+func (h *unaryRequest) H(ctx context.Context, cmd any) (any, error) {
+	return nil, h.base.H(ctx, cmd)
+}
@@ -0,0 +1,145 @@
+package app_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/app"
+)
+
+func TestRegistry_Dispatch(t *testing.T) {
+	t.Parallel()
+
+	t.Run("dispatch to registered use cases", func(t *testing.T) {
+		t.Parallel()
+
+		type result struct{ Value string }
+
+		registry := app.NewRegistry()
+
+		app.RegisterRequest(registry, app.TestRequestHandler(func(_ context.Context, _ request) (result, error) {
+			return result{Value: "request"}, nil
+		}))
+
+		commandCalled := false
+		app.RegisterCommand(registry, app.TestCommandHandler(func(_ context.Context, _ command) error {
+			commandCalled = true
+			return nil
+		}))
+
+		res, err := app.Dispatch[result](t.Context(), registry, request{})
+		assert.NoError(t, err)
+		assert.Equal(t, "request", res.Value)
+
+		res2, err := registry.Dispatch(t.Context(), command{})
+		assert.NoError(t, err)
+		assert.Nil(t, res2)
+		assert.True(t, commandCalled)
+	})
+
+	t.Run("return error of use case", func(t *testing.T) {
+		t.Parallel()
+
+		registry := app.NewRegistry()
+		app.RegisterJob(registry, app.TestJobHandler(func(_ context.Context, _ job) error {
+			return errTemporary
+		}))
+
+		_, err := registry.Dispatch(t.Context(), job{})
+		assert.ErrorIs(t, err, errTemporary)
+	})
+
+	t.Run("not registered", func(t *testing.T) {
+		t.Parallel()
+
+		registry := app.NewRegistry()
+
+		_, err := registry.Dispatch(t.Context(), query{})
+		assert.ErrorIs(t, err, app.ErrNotRegistered)
+	})
+
+	t.Run("register twice", func(t *testing.T) {
+		t.Parallel()
+
+		registry := app.NewRegistry()
+		app.RegisterCommand(registry, app.TestSuccessCommandHandler[command]())
+
+		assert.Panics(t, func() {
+			app.RegisterCommand(registry, app.TestSuccessCommandHandler[command]())
+		})
+	})
+}
+
+func TestRegistry_Decorators(t *testing.T) {
+	t.Parallel()
+
+	t.Run("apply decorators in order", func(t *testing.T) {
+		t.Parallel()
+
+		var calls []string
+
+		decorator := func(name string) app.Decorator {
+			return func(_ app.UseCase, next app.Request[any, any]) app.Request[any, any] {
+				return app.TestRequestHandler(func(ctx context.Context, in any) (any, error) {
+					calls = append(calls, name)
+					return next.H(ctx, in)
+				})
+			}
+		}
+
+		registry := app.NewRegistry(decorator("first"), decorator("second"))
+		cmd := app.RegisterCommand(registry, app.TestCommandHandler(func(_ context.Context, _ command) error {
+			calls = append(calls, "use case")
+			return nil
+		}))
+
+		err := cmd.H(t.Context(), command{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "use case"}, calls, "returned use case should be decorated")
+
+		calls = nil
+
+		_, err = registry.Dispatch(t.Context(), command{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second", "use case"}, calls, "dispatched use case should be decorated")
+	})
+
+	t.Run("instrumented", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		registry := app.NewRegistry(app.Instrumented(tracenoop.NewTracerProvider(), noop.NewMeterProvider(), logger))
+
+		app.RegisterQuery(registry, app.TestSuccessQueryHandler[query, response]())
+		app.RegisterJob(registry, app.TestFailureJobHandler[job]())
+
+		_, err := registry.Dispatch(t.Context(), query{})
+		assert.NoError(t, err)
+		logger.Contains(`msg="query executed successfully"`)
+		logger.Contains(`command=app_test.query`)
+
+		_, err = registry.Dispatch(t.Context(), job{})
+		assert.Error(t, err)
+		logger.Contains(`msg="failed to execute job"`)
+	})
+}
+
+func TestRegistry_UseCases(t *testing.T) {
+	t.Parallel()
+
+	registry := app.NewRegistry()
+	app.RegisterRequest(registry, app.TestSuccessRequestHandler[request, response]())
+	app.RegisterCommand(registry, app.TestSuccessCommandHandler[command]())
+
+	useCases := registry.UseCases()
+	assert.Equal(t, []app.UseCase{
+		{Name: "app_test.command", Kind: app.KindCommand, In: reflect.TypeFor[command](), Out: nil},
+		{Name: "app_test.request", Kind: app.KindRequest, In: reflect.TypeFor[request](), Out: reflect.TypeFor[response]()},
+	}, useCases)
+}
@@ -0,0 +1,286 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/alog/logging"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 50 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+)
+
+// ErrTransient marks errors, that can succeed if the use case is called again, see Transient.
+var ErrTransient = errors.New("transient error")
+
+// Transient marks err as transient, so the retry decorators retry the use case.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &transientError{err: err}
+}
+
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string   { return e.err.Error() }
+func (e *transientError) Unwrap() []error { return []error{ErrTransient, e.err} }
+
+// IsTransient reports if err can succeed, if the use case is called again:
+// errors marked with Transient, timeouts of the network, and postgres errors for
+// serialization failures, deadlocks, and lost connections.
+// A done ctx is never transient, as the caller is gone.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		const connectionException = "08"
+
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" || strings.HasPrefix(pgErr.Code, connectionException)
+	}
+
+	return pgconn.SafeToRetry(err)
+}
+
+// RetryOption configures the retry decorators.
+type RetryOption func(*retryConfig)
+
+type retryConfig struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	retryIf    func(err error) bool
+}
+
+// WithAttempts sets how often the use case is called at most, including the first call.
+func WithAttempts(attempts int) RetryOption {
+	return func(c *retryConfig) {
+		c.attempts = max(attempts, 1)
+	}
+}
+
+// WithBackoff sets the time to wait before the first retry. It doubles with each retry up to maxBackoff.
+func WithBackoff(backoff time.Duration, maxBackoff time.Duration) RetryOption {
+	return func(c *retryConfig) {
+		c.backoff = max(backoff, 0)
+		c.maxBackoff = max(maxBackoff, c.backoff)
+	}
+}
+
+// WithRetryIf sets which errors are retried. Without it, IsTransient is used.
+func WithRetryIf(retryIf func(err error) bool) RetryOption {
+	return func(c *retryConfig) {
+		c.retryIf = retryIf
+	}
+}
+
+// NewRetryRequest calls the request again, if it fails with a transient error, see IsTransient.
+// It waits with an exponential backoff between the attempts and stops, if ctx is done.
+// Only use it for requests, that are safe to call multiple times.
+func NewRetryRequest[Req any, Res any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	req Request[Req, Res],
+	opts ...RetryOption,
+) Request[Req, Res] {
+	return &requestRetryDecorator[Req, Res]{
+		retrier: newRetrier(logger, meterProvider, opts),
+		base:    req,
+	}
+}
+
+type requestRetryDecorator[Req any, Res any] struct {
+	retrier *retrier
+	base    Request[Req, Res]
+}
+
+func (d *requestRetryDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
+	return retry(ctx, d.retrier, commandName(req), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, req)
+	})
+}
+
+// NewRetryCommand calls the command again, if it fails with a transient error, see NewRetryRequest.
+func NewRetryCommand[C any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	cmd Command[C],
+	opts ...RetryOption,
+) Command[C] {
+	return &commandRetryDecorator[C]{
+		retrier: newRetrier(logger, meterProvider, opts),
+		base:    cmd,
+	}
+}
+
+type commandRetryDecorator[C any] struct {
+	retrier *retrier
+	base    Command[C]
+}
+
+func (d *commandRetryDecorator[C]) H(ctx context.Context, cmd C) error {
+	_, err := retry(ctx, d.retrier, commandName(cmd), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, cmd)
+	})
+
+	return err
+}
+
+// NewRetryQuery calls the query again, if it fails with a transient error, see NewRetryRequest.
+func NewRetryQuery[Q any, Res any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	query Query[Q, Res],
+	opts ...RetryOption,
+) Query[Q, Res] {
+	return &queryRetryDecorator[Q, Res]{
+		retrier: newRetrier(logger, meterProvider, opts),
+		base:    query,
+	}
+}
+
+type queryRetryDecorator[Q any, Res any] struct {
+	retrier *retrier
+	base    Query[Q, Res]
+}
+
+func (d *queryRetryDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
+	return retry(ctx, d.retrier, commandName(query), func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, query)
+	})
+}
+
+// NewRetryJob calls the job again, if it fails with a transient error, see NewRetryRequest.
+// The attempts are independent of the retries of the job queue,
+// they help to not reschedule a job for errors, that go away in a few milliseconds.
+func NewRetryJob[J any](
+	logger alog.Logger,
+	meterProvider metric.MeterProvider,
+	job Job[J],
+	opts ...RetryOption,
+) Job[J] {
+	return &jobRetryDecorator[J]{
+		retrier: newRetrier(logger, meterProvider, opts),
+		base:    job,
+	}
+}
+
+type jobRetryDecorator[J any] struct {
+	retrier *retrier
+	base    Job[J]
+}
+
+func (d *jobRetryDecorator[J]) H(ctx context.Context, job J) error {
+	_, err := retry(ctx, d.retrier, commandName(job), func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, d.base.H(ctx, job)
+	})
+
+	return err
+}
+
+type retrier struct {
+	config  retryConfig
+	logger  alog.Logger
+	retries metric.Int64Counter
+}
+
+func newRetrier(logger alog.Logger, meterProvider metric.MeterProvider, opts []RetryOption) *retrier {
+	config := retryConfig{
+		attempts:   defaultRetryAttempts,
+		backoff:    defaultRetryBackoff,
+		maxBackoff: defaultRetryMaxBackoff,
+		retryIf:    IsTransient,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	meter := meterProvider.Meter("arrower.application")
+	retries, _ := meter.Int64Counter("usecases_retries",
+		metric.WithDescription("number of use cases called again by result: success or failure"))
+
+	return &retrier{
+		config:  config,
+		logger:  logger,
+		retries: retries,
+	}
+}
+
+func retry[Res any](ctx context.Context, r *retrier, cmdName string, fn func(ctx context.Context) (Res, error)) (Res, error) {
+	backoff := r.config.backoff
+
+	for attempt := 1; ; attempt++ {
+		res, err := fn(ctx)
+
+		if attempt > 1 {
+			status := "success"
+			if err != nil {
+				status = "failure"
+			}
+
+			r.retries.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("command", cmdName),
+				attribute.String("status", status),
+			))
+		}
+
+		if err == nil || !r.config.retryIf(err) {
+			return res, err
+		}
+
+		if attempt >= r.config.attempts {
+			r.logger.InfoContext(ctx, "giving up retrying",
+				logging.Command(cmdName),
+				logging.Attempt(attempt),
+				logging.Error(err),
+			)
+
+			return res, err
+		}
+
+		wait := backoff + time.Duration(rand.Int64N(int64(backoff)/2+1)) //nolint:gosec // spread retries, no crypto required
+
+		r.logger.DebugContext(ctx, "retrying",
+			logging.Command(cmdName),
+			logging.Attempt(attempt),
+			logging.Backoff(wait),
+			logging.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return res, err
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, r.config.maxBackoff)
+	}
+}
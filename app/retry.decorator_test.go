@@ -0,0 +1,174 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/go-arrower/arrower/alog"
+	"github.com/go-arrower/arrower/app"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err       error
+		transient bool
+	}{
+		"nil":              {nil, false},
+		"other":            {errTemporary, false},
+		"marked":           {app.Transient(errTemporary), true},
+		"wrapped marked":   {errors.Join(errTemporary, app.Transient(errTemporary)), true},
+		"cancelled":        {app.Transient(context.Canceled), false},
+		"deadlock":         {&pgconn.PgError{Code: "40P01"}, true},
+		"serialization":    {&pgconn.PgError{Code: "40001"}, true},
+		"connection":       {&pgconn.PgError{Code: "08006"}, true},
+		"unique violation": {&pgconn.PgError{Code: "23505"}, false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.transient, app.IsTransient(tt.err))
+		})
+	}
+
+	assert.ErrorIs(t, app.Transient(errTemporary), errTemporary, "original error is kept")
+	assert.ErrorIs(t, app.Transient(errTemporary), app.ErrTransient)
+}
+
+func TestRequestRetryDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	t.Run("retry transient error", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		calls := 0
+
+		handler := app.NewRetryRequest(logger, noop.NewMeterProvider(),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				if calls < 3 {
+					return response{}, app.Transient(errTemporary)
+				}
+
+				return response{}, nil
+			}),
+			app.WithBackoff(time.Millisecond, time.Millisecond),
+		)
+
+		_, err := handler.H(t.Context(), request{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		logger.Contains(`msg=retrying`)
+		logger.Contains(`command=app_test.request`)
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		t.Parallel()
+
+		logger := alog.Test(t)
+		calls := 0
+
+		handler := app.NewRetryRequest(logger, noop.NewMeterProvider(),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				return response{}, app.Transient(errTemporary)
+			}),
+			app.WithAttempts(2),
+			app.WithBackoff(time.Millisecond, time.Millisecond),
+		)
+
+		_, err := handler.H(t.Context(), request{})
+		assert.ErrorIs(t, err, errTemporary)
+		assert.Equal(t, 2, calls)
+		logger.Contains(`msg="giving up retrying"`)
+	})
+
+	t.Run("do not retry other errors", func(t *testing.T) {
+		t.Parallel()
+
+		handler := app.NewRetryRequest(alog.Test(t), noop.NewMeterProvider(), app.TestFailureRequestHandler[request, response]())
+
+		_, err := handler.H(t.Context(), request{})
+		assert.Error(t, err)
+	})
+
+	t.Run("stop when ctx is done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(t.Context())
+		calls := 0
+
+		handler := app.NewRetryRequest(alog.Test(t), noop.NewMeterProvider(),
+			app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				calls++
+				cancel()
+
+				return response{}, app.Transient(errTemporary)
+			}),
+			app.WithBackoff(time.Hour, time.Hour),
+		)
+
+		_, err := handler.H(ctx, request{})
+		assert.ErrorIs(t, err, errTemporary)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestCommandRetryDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	handler := app.NewRetryCommand(alog.Test(t), noop.NewMeterProvider(),
+		app.TestCommandHandler(func(_ context.Context, _ command) error {
+			calls++
+			return app.Transient(errTemporary)
+		}),
+		app.WithRetryIf(func(error) bool { return false }),
+	)
+
+	err := handler.H(t.Context(), command{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "custom classification")
+}
+
+func TestQueryRetryDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	handler := app.NewRetryQuery(alog.Test(t), noop.NewMeterProvider(),
+		app.TestQueryHandler(func(_ context.Context, _ query) (response, error) {
+			calls++
+			if calls == 1 {
+				return response{}, &pgconn.PgError{Code: "40001"}
+			}
+
+			return response{}, nil
+		}),
+		app.WithBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	_, err := handler.H(t.Context(), query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestJobRetryDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	handler := app.NewRetryJob(alog.Test(t), noop.NewMeterProvider(), app.TestSuccessJobHandler[job]())
+
+	err := handler.H(t.Context(), job{})
+	assert.NoError(t, err)
+}
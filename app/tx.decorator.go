@@ -2,85 +2,103 @@ package app
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/go-arrower/arrower/postgres"
 )
 
-func NewTxRequest[Req any, Res any](pgx *pgxpool.Pool, req Request[Req, Res]) Request[Req, Res] {
+// NewTxRequest runs the request in a transaction, see postgres.InTx.
+// If the ctx contains a transaction already, e.g. because the request is called by another
+// transactional use case, a savepoint is used, so the outer use case stays atomic.
+//
+// Use the opts to set the isolation level and the retries on serialization failures and deadlocks.
+// Side effects, that must only happen if the transaction commits, e.g. sending emails,
+// can be registered with postgres.AfterCommit in the use case.
+func NewTxRequest[Req any, Res any](pgx *pgxpool.Pool, req Request[Req, Res], opts ...postgres.TxOption) Request[Req, Res] {
 	return &requestTxDecorator[Req, Res]{
 		pgx:  pgx,
 		base: req,
+		opts: opts,
 	}
 }
 
 type requestTxDecorator[Req any, Res any] struct {
 	pgx  *pgxpool.Pool
 	base Request[Req, Res]
+	opts []postgres.TxOption
 }
 
 func (d *requestTxDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
-	tx, err := d.pgx.Begin(ctx)
-	if err != nil {
-		return *new(Res), fmt.Errorf("could not start transaction: %w", err)
-	}
-
-	ctx = context.WithValue(ctx, postgres.CtxTX, tx)
-
-	res, err := d.base.H(ctx, req)
-	if err != nil {
-		rb := tx.Rollback(ctx)
-		if rb != nil {
-			return *new(Res), fmt.Errorf("could not rollback transaction: %w", rb)
-		}
-
-		return res, err //nolint:wrapcheck // decorate but not change anything
-	}
-
-	err = tx.Commit(ctx)
-	if err != nil {
-		return *new(Res), fmt.Errorf("could not commit transaction: %w", err)
-	}
-
-	return res, err //nolint:wrapcheck // decorate but not change anything
+	return inTx(ctx, d.pgx, d.opts, func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, req)
+	})
 }
 
-func NewTxCommand[C any](pgx *pgxpool.Pool, cmd Command[C]) Command[C] {
+// NewTxCommand runs the command in a transaction, see NewTxRequest.
+func NewTxCommand[C any](pgx *pgxpool.Pool, cmd Command[C], opts ...postgres.TxOption) Command[C] {
 	return &commandTxDecorator[C]{
 		pgx:  pgx,
 		base: cmd,
+		opts: opts,
 	}
 }
 
 type commandTxDecorator[C any] struct {
 	pgx  *pgxpool.Pool
 	base Command[C]
+	opts []postgres.TxOption
 }
 
 func (d *commandTxDecorator[C]) H(ctx context.Context, cmd C) error {
-	tx, err := d.pgx.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("could not start transaction: %w", err)
-	}
+	return postgres.InTx(ctx, d.pgx, func(ctx context.Context) error { //nolint:wrapcheck // decorate but not change anything
+		return d.base.H(ctx, cmd)
+	}, d.opts...)
+}
 
-	ctx = context.WithValue(ctx, postgres.CtxTX, tx)
+// NewTxQuery runs the query in a read only transaction, see NewTxRequest.
+// All reads of the query see the same snapshot of the data, if it is used
+// with the isolation level pgx.RepeatableRead or pgx.Serializable.
+func NewTxQuery[Q any, Res any](pgx *pgxpool.Pool, query Query[Q, Res], opts ...postgres.TxOption) Query[Q, Res] {
+	return &queryTxDecorator[Q, Res]{
+		pgx:  pgx,
+		base: query,
+		opts: append([]postgres.TxOption{postgres.WithReadOnly()}, opts...),
+	}
+}
 
-	err = d.base.H(ctx, cmd)
-	if err != nil {
-		rb := tx.Rollback(ctx)
-		if rb != nil {
-			return fmt.Errorf("could not rollback transaction: %w", rb)
-		}
+type queryTxDecorator[Q any, Res any] struct {
+	pgx  *pgxpool.Pool
+	base Query[Q, Res]
+	opts []postgres.TxOption
+}
 
-		return err //nolint:wrapcheck // decorate but not change anything
-	}
+func (d *queryTxDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
+	return inTx(ctx, d.pgx, d.opts, func(ctx context.Context) (Res, error) {
+		return d.base.H(ctx, query)
+	})
+}
 
-	err = tx.Commit(ctx)
-	if err != nil {
-		return fmt.Errorf("could not commit transaction: %w", err)
+// inTx runs fn in a transaction and returns its result.
+// If fn fails, its result is returned together with its error, as without the decorator.
+func inTx[Res any](
+	ctx context.Context,
+	pgx *pgxpool.Pool,
+	opts []postgres.TxOption,
+	fn func(ctx context.Context) (Res, error),
+) (Res, error) {
+	var (
+		res   Res
+		fnErr error
+	)
+
+	err := postgres.InTx(ctx, pgx, func(ctx context.Context) error {
+		res, fnErr = fn(ctx)
+		return fnErr
+	}, opts...)
+	if err != nil && err != fnErr { //nolint:errorlint // the transaction failed, if it is not the error of the use case
+		return *new(Res), err //nolint:wrapcheck // decorate but not change anything
 	}
 
-	return err //nolint:wrapcheck // decorate but not change anything
+	return res, err //nolint:wrapcheck // decorate but not change anything
 }
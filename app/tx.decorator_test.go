@@ -149,6 +149,96 @@ func TestRequestTxDecorator_H(t *testing.T) {
 		assert.NoError(t, err, "table should exist")
 		assert.Len(t, ids, 1)
 	})
+
+	t.Run("nested request - inner fails and outer returns the error", func(t *testing.T) {
+		t.Parallel()
+
+		pgHandler := pgHandler.NewTestDatabase()
+		_, err := pgHandler.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS some_table(id SERIAL PRIMARY KEY);`)
+		assert.NoError(t, err)
+
+		handler := app.NewTxRequest(pgHandler, app.TestRequestHandler(func(ctx context.Context, req request) (response, error) {
+			tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+			_, err := tx.Exec(ctx, `INSERT INTO some_table(id) VALUES (DEFAULT);`)
+			assert.NoError(t, err)
+
+			innerHandler := app.NewTxRequest(pgHandler, app.TestRequestHandler(func(_ context.Context, _ request) (response, error) {
+				return response{}, errUseCaseFailed
+			}))
+
+			return innerHandler.H(ctx, req)
+		}))
+
+		_, err = handler.H(t.Context(), request{})
+		assert.ErrorIs(t, err, errUseCaseFailed)
+
+		var ids []int
+		err = pgxscan.Select(t.Context(), pgHandler, &ids, `SELECT * FROM some_table;`) //nolint:wsl_v5
+		assert.NoError(t, err)
+		assert.Empty(t, ids, "all changes are rolled back")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		t.Parallel()
+
+		pgHandler := pgHandler.NewTestDatabase()
+		_, err := pgHandler.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS some_table(id SERIAL PRIMARY KEY);`)
+		assert.NoError(t, err)
+
+		handler := app.NewTxRequest(pgHandler, app.TestRequestHandler(func(ctx context.Context, _ request) (response, error) {
+			tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+			_, err := tx.Exec(ctx, `INSERT INTO some_table(id) VALUES (DEFAULT);`)
+			assert.NoError(t, err)
+
+			panic("use case panics")
+		}))
+
+		assert.Panics(t, func() { _, _ = handler.H(t.Context(), request{}) })
+
+		var ids []int
+		err = pgxscan.Select(t.Context(), pgHandler, &ids, `SELECT * FROM some_table;`) //nolint:wsl_v5
+		assert.NoError(t, err)
+		assert.Empty(t, ids, "changes are rolled back")
+	})
+}
+
+func TestRequestTxDecorator_AfterCommit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("commit", func(t *testing.T) {
+		t.Parallel()
+
+		committed := false
+		handler := app.NewTxRequest(pgHandler.NewTestDatabase(), app.TestRequestHandler(func(ctx context.Context, _ request) (response, error) {
+			postgres.AfterCommit(ctx, func(context.Context) { committed = true })
+			assert.False(t, committed)
+
+			return response{}, nil
+		}))
+
+		_, err := handler.H(t.Context(), request{})
+		assert.NoError(t, err)
+		assert.True(t, committed)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		t.Parallel()
+
+		committed, rolledBack := false, false
+		handler := app.NewTxRequest(pgHandler.NewTestDatabase(), app.TestRequestHandler(func(ctx context.Context, _ request) (response, error) {
+			postgres.AfterCommit(ctx, func(context.Context) { committed = true })
+			postgres.AfterRollback(ctx, func(context.Context) { rolledBack = true })
+
+			return response{}, errUseCaseFailed
+		}))
+
+		_, err := handler.H(t.Context(), request{})
+		assert.Error(t, err)
+		assert.False(t, committed)
+		assert.True(t, rolledBack)
+	})
 }
 
 func TestCommandTxDecorator_H(t *testing.T) {
@@ -268,3 +358,43 @@ func TestCommandTxDecorator_H(t *testing.T) {
 		assert.Len(t, ids, 1)
 	})
 }
+
+func TestQueryTxDecorator_H(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read only", func(t *testing.T) {
+		t.Parallel()
+
+		pgHandler := pgHandler.NewTestDatabase()
+		handler := app.NewTxQuery(pgHandler, app.TestQueryHandler(func(ctx context.Context, _ query) (response, error) {
+			tx, txOk := ctx.Value(postgres.CtxTX).(pgx.Tx)
+			assert.True(t, txOk)
+
+			_, err := tx.Exec(ctx, `CREATE TABLE some_table(id SERIAL PRIMARY KEY);`)
+
+			return response{}, err
+		}))
+
+		_, err := handler.H(t.Context(), query{})
+		assert.Error(t, err, "writes fail")
+	})
+
+	t.Run("isolation level", func(t *testing.T) {
+		t.Parallel()
+
+		pgHandler := pgHandler.NewTestDatabase()
+		handler := app.NewTxQuery(pgHandler, app.TestQueryHandler(func(ctx context.Context, _ query) (response, error) {
+			tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+			var level string
+			err := pgxscan.Get(ctx, tx, &level, `SHOW transaction_isolation;`)
+			assert.NoError(t, err)
+			assert.Equal(t, "repeatable read", level)
+
+			return response{}, nil
+		}), postgres.WithIsolation(pgx.RepeatableRead))
+
+		_, err := handler.H(t.Context(), query{})
+		assert.NoError(t, err)
+	})
+}
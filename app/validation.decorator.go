@@ -22,6 +22,10 @@ func PassedValidation(ctx context.Context) bool {
 	return false
 }
 
+// NewValidatedRequest calls the request only, if req passes the validation tags of validate
+// and its CrossFieldValidator, if it implements one. Otherwise, it returns a ValidationError,
+// with the messages translated into the language of the ctx, see renderer.CtxI18n.
+// If validate is nil, a default validator is used.
 func NewValidatedRequest[Req any, Res any](validate *validator.Validate, req Request[Req, Res]) Request[Req, Res] {
 	if validate == nil {
 		validate = validator.New()
@@ -39,7 +43,7 @@ type requestValidatingDecorator[Req any, Res any] struct {
 }
 
 func (d *requestValidatingDecorator[Req, Res]) H(ctx context.Context, req Req) (Res, error) {
-	err := d.validate.Struct(req)
+	err := validate(ctx, d.validate, req)
 	if err != nil {
 		return *new(Res), err //nolint:wrapcheck // validation error is returned on purpose
 	}
@@ -47,6 +51,7 @@ func (d *requestValidatingDecorator[Req, Res]) H(ctx context.Context, req Req) (
 	return d.base.H(context.WithValue(ctx, CtxValidated, true), req) //nolint:wrapcheck // decorate but not change anything
 }
 
+// NewValidatedCommand calls the command only, if cmd is valid, see NewValidatedRequest.
 func NewValidatedCommand[C any](validate *validator.Validate, cmd Command[C]) Command[C] {
 	if validate == nil {
 		validate = validator.New()
@@ -64,7 +69,7 @@ type commandValidatingDecorator[C any] struct {
 }
 
 func (d *commandValidatingDecorator[C]) H(ctx context.Context, cmd C) error {
-	err := d.validate.Struct(cmd)
+	err := validate(ctx, d.validate, cmd)
 	if err != nil {
 		return err //nolint:wrapcheck // validation error is returned on purpose
 	}
@@ -72,6 +77,7 @@ func (d *commandValidatingDecorator[C]) H(ctx context.Context, cmd C) error {
 	return d.base.H(context.WithValue(ctx, CtxValidated, true), cmd) //nolint:wrapcheck // decorate but not change anything
 }
 
+// NewValidatedQuery calls the query only, if query is valid, see NewValidatedRequest.
 func NewValidatedQuery[Q any, Res any](validate *validator.Validate, query Query[Q, Res]) Query[Q, Res] {
 	if validate == nil {
 		validate = validator.New()
@@ -89,7 +95,7 @@ type queryValidatingDecorator[Q any, Res any] struct {
 }
 
 func (d *queryValidatingDecorator[Q, Res]) H(ctx context.Context, query Q) (Res, error) {
-	err := d.validate.Struct(query)
+	err := validate(ctx, d.validate, query)
 	if err != nil {
 		return *new(Res), err //nolint:wrapcheck // validation error is returned on purpose
 	}
@@ -97,6 +103,7 @@ func (d *queryValidatingDecorator[Q, Res]) H(ctx context.Context, query Q) (Res,
 	return d.base.H(context.WithValue(ctx, CtxValidated, true), query) //nolint:wrapcheck,lll // decorate but not change anything
 }
 
+// NewValidatedJob calls the job only, if job is valid, see NewValidatedRequest.
 func NewValidatedJob[J any](validate *validator.Validate, job Job[J]) Job[J] {
 	if validate == nil {
 		validate = validator.New()
@@ -114,7 +121,7 @@ type jobValidatingDecorator[J any] struct {
 }
 
 func (d *jobValidatingDecorator[J]) H(ctx context.Context, job J) error {
-	err := d.validate.Struct(job)
+	err := validate(ctx, d.validate, job)
 	if err != nil {
 		return err //nolint:wrapcheck // validation error is returned on purpose
 	}
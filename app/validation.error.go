@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/go-arrower/arrower/renderer"
+)
+
+// ErrValidation is matched by all ValidationErrors, use errors.Is(err, ErrValidation).
+var ErrValidation = errors.New("validation failed")
+
+// defaultLanguage is used to translate the messages, if the ctx contains no language, see renderer.CtxI18n.
+var defaultLanguage = language.English //nolint:gochecknoglobals // used as constant
+
+// validationMessages are the messages for the validation tags of the validator.
+// The messages are the keys for translations, register them with message.SetString, e.g.:
+//
+//	message.SetString(language.German, "is required", "ist erforderlich")
+//	message.SetString(language.German, "must be at least %s", "muss mindestens %s sein")
+//
+//nolint:gochecknoglobals // used as constant
+var validationMessages = map[string]string{
+	"required": "is required",
+	"email":    "must be a valid email address",
+	"url":      "must be a valid URL",
+	"uuid":     "must be a valid UUID",
+	"uuid4":    "must be a valid UUID",
+	"min":      "must be at least %s",
+	"max":      "must be at most %s",
+	"len":      "must have a length of %s",
+	"eq":       "must be %s",
+	"ne":       "must not be %s",
+	"gt":       "must be greater than %s",
+	"gte":      "must be at least %s",
+	"lt":       "must be less than %s",
+	"lte":      "must be at most %s",
+	"oneof":    "must be one of: %s",
+	"eqfield":  "must match %s",
+	"nefield":  "must not match %s",
+}
+
+const invalidMessage = "is invalid"
+
+// FieldError is the failed validation of a single field.
+type FieldError struct {
+	// Field is the name of the struct field, nested fields are separated by a dot, e.g. Address.Street.
+	Field string `json:"field"`
+	// Code identifies the failed rule, e.g. the validation tag "required".
+	Code string `json:"code"`
+	// Param is the parameter of the rule, e.g. "8" for "min=8".
+	Param string `json:"param,omitempty"`
+	// Message is the message for the user, translated into the language of the ctx.
+	// Validators return the untranslated message, it is used as key for the translation.
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by the validation decorators, if the value is invalid.
+// It contains one FieldError for each failed rule.
+// The errors of the validator are still available with errors.As(err, &validator.ValidationErrors{}).
+type ValidationError struct {
+	Fields []FieldError
+
+	err error
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		fields = append(fields, f.Field+": "+f.Message)
+	}
+
+	return ErrValidation.Error() + ": " + strings.Join(fields, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation //nolint:errorlint // compare to the sentinel only
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// Messages returns the message of the first failed rule for each field.
+func (e *ValidationError) Messages() map[string]string {
+	messages := make(map[string]string, len(e.Fields))
+
+	for _, f := range e.Fields {
+		if _, exists := messages[f.Field]; !exists {
+			messages[f.Field] = f.Message
+		}
+	}
+
+	return messages
+}
+
+// CrossFieldValidator is implemented by values with rules spanning multiple fields,
+// that cannot be expressed by validation tags, e.g. a start date before an end date.
+// The validation decorators call Validate in addition to the tags of the validator.
+type CrossFieldValidator interface {
+	// Validate returns a FieldError for each failed rule, or nil if the value is valid.
+	// The Message is used as key for the translation, see FieldError.
+	Validate(ctx context.Context) []FieldError
+}
+
+// validate validates in with the tags of v and as CrossFieldValidator.
+// Messages are translated into the language of ctx, see renderer.CtxI18n.
+func validate(ctx context.Context, v *validator.Validate, in any) error {
+	err := v.Struct(in)
+
+	var validationErrors validator.ValidationErrors
+	if err != nil && !errors.As(err, &validationErrors) {
+		return err //nolint:wrapcheck // return the error of the validator, e.g. for an invalid value
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, e := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldName(e),
+			Code:    e.Tag(),
+			Param:   e.Param(),
+			Message: "",
+		})
+	}
+
+	if cross, ok := in.(CrossFieldValidator); ok {
+		fields = append(fields, cross.Validate(ctx)...)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tag, ok := ctx.Value(renderer.CtxI18n).(language.Tag)
+	if !ok {
+		tag = defaultLanguage
+	}
+
+	printer := message.NewPrinter(tag)
+
+	for i, f := range fields {
+		fields[i].Message = translate(printer, f)
+	}
+
+	return &ValidationError{Fields: fields, err: err}
+}
+
+// fieldName returns the name of the field without the name of the validated struct.
+func fieldName(e validator.FieldError) string {
+	_, name, found := strings.Cut(e.StructNamespace(), ".")
+	if !found {
+		return e.StructField()
+	}
+
+	return name
+}
+
+func translate(printer *message.Printer, f FieldError) string {
+	key := f.Message
+	if key == "" {
+		key = validationMessages[f.Code]
+	}
+
+	if key == "" {
+		key = invalidMessage
+	}
+
+	if strings.Contains(key, "%") {
+		return printer.Sprintf(key, f.Param)
+	}
+
+	return printer.Sprintf(key)
+}
+
+// FormErrors returns the messages of err for each field, to show them in an HTML form.
+// If err is not a ValidationError, the map is empty, so the caller can add its own messages.
+func FormErrors(err error) map[string]string {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return map[string]string{}
+	}
+
+	return validationErr.Messages()
+}
+
+// ValidationProblem writes err as JSON problem, see RFC 9457, with status 422 Unprocessable Entity.
+// If err is not a ValidationError, it is returned, so the caller can handle it, e.g.:
+//
+//	res, err := uc.H(c.Request().Context(), req)
+//	if err != nil {
+//		return app.ValidationProblem(c, err)
+//	}
+func ValidationProblem(c echo.Context, err error) error {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	type fieldProblem struct {
+		Field   string `json:"field"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	problems := make([]fieldProblem, 0, len(validationErr.Fields))
+	for _, f := range validationErr.Fields {
+		problems = append(problems, fieldProblem{Field: f.Field, Code: f.Code, Message: f.Message})
+	}
+
+	return problem(c, http.StatusUnprocessableEntity, map[string]any{
+		"title":  ErrValidation.Error(),
+		"errors": problems,
+	})
+}
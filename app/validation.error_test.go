@@ -0,0 +1,139 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/go-arrower/arrower/app"
+	"github.com/go-arrower/arrower/renderer"
+)
+
+func TestValidationError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("field errors", func(t *testing.T) {
+		t.Parallel()
+
+		handler := app.NewValidatedCommand(nil, app.TestSuccessCommandHandler[structWithValidationTags]())
+
+		err := handler.H(t.Context(), structWithValidationTags{Val0: "", Val1: "a"})
+		assert.ErrorIs(t, err, app.ErrValidation)
+
+		var validationErr *app.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []app.FieldError{
+			{Field: "Val0", Code: "required", Param: "", Message: "is required"},
+			{Field: "Val1", Code: "min", Param: "2", Message: "must be at least 2"},
+		}, validationErr.Fields)
+
+		var validationErrors validator.ValidationErrors
+		assert.ErrorAs(t, err, &validationErrors, "errors of the validator should be available")
+	})
+
+	t.Run("cross field errors", func(t *testing.T) {
+		t.Parallel()
+
+		handler := app.NewValidatedCommand(nil, app.TestSuccessCommandHandler[dateRange]())
+
+		err := handler.H(t.Context(), dateRange{From: 2, To: 1})
+
+		var validationErr *app.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []app.FieldError{
+			{Field: "To", Code: "after_from", Param: "", Message: "must be after the start"},
+		}, validationErr.Fields)
+
+		err = handler.H(t.Context(), dateRange{From: 1, To: 2})
+		assert.NoError(t, err)
+	})
+
+	t.Run("translate messages", func(t *testing.T) {
+		t.Parallel()
+
+		_ = message.SetString(language.German, "must be at least %s", "muss mindestens %s sein")
+		ctx := context.WithValue(t.Context(), renderer.CtxI18n, language.German)
+
+		handler := app.NewValidatedCommand(nil, app.TestSuccessCommandHandler[structWithValidationTags]())
+
+		err := handler.H(ctx, structWithValidationTags{Val0: "value", Val1: "a"})
+		assert.Equal(t, map[string]string{"Val1": "muss mindestens 2 sein"}, app.FormErrors(err))
+	})
+}
+
+func TestFormErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validation error", func(t *testing.T) {
+		t.Parallel()
+
+		err := &app.ValidationError{Fields: []app.FieldError{
+			{Field: "Email", Code: "required", Param: "", Message: "is required"},
+			{Field: "Email", Code: "email", Param: "", Message: "must be a valid email address"},
+		}}
+
+		assert.Equal(t, map[string]string{"Email": "is required"}, app.FormErrors(err))
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		t.Parallel()
+
+		errs := app.FormErrors(errTemporary)
+		assert.Empty(t, errs)
+		assert.NotNil(t, errs)
+	})
+}
+
+func TestValidationProblem(t *testing.T) {
+	t.Parallel()
+
+	t.Run("validation error", func(t *testing.T) {
+		t.Parallel()
+
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+
+		err := app.ValidationProblem(c, &app.ValidationError{Fields: []app.FieldError{
+			{Field: "Email", Code: "required", Param: "", Message: "is required"},
+		}})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+
+		var problem map[string]any
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, "validation failed", problem["title"])
+		assert.Equal(t, []any{map[string]any{"field": "Email", "code": "required", "message": "is required"}}, problem["errors"])
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		t.Parallel()
+
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+
+		err := app.ValidationProblem(c, errTemporary)
+		assert.ErrorIs(t, err, errTemporary)
+	})
+}
+
+type dateRange struct {
+	From int
+	To   int
+}
+
+func (r dateRange) Validate(_ context.Context) []app.FieldError {
+	if r.To <= r.From {
+		return []app.FieldError{{Field: "To", Code: "after_from", Param: "", Message: "must be after the start"}}
+	}
+
+	return nil
+}
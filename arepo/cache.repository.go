@@ -0,0 +1,443 @@
+package arepo
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/go-arrower/arrower/arepo/q"
+	"github.com/go-arrower/arrower/postgres"
+)
+
+const (
+	defaultCacheTTL  = 5 * time.Minute
+	defaultCacheSize = 1000
+)
+
+// WithCacheTTL sets how long an entity is cached, before it is read from the repository again.
+// ONLY applies to the CachedRepository.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(*cacheConfig); ok && ttl > 0 {
+			repo.ttl = ttl
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithCacheTTL can only be used with a CachedRepository and a positive ttl", errInvalidOption)
+	}
+}
+
+// WithCacheSize sets the maximum number of cached entities.
+// If the cache is full, the least recently used entity is evicted.
+// ONLY applies to the CachedRepository.
+func WithCacheSize(size int) Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(*cacheConfig); ok && size > 0 {
+			repo.size = size
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithCacheSize can only be used with a CachedRepository and a positive size", errInvalidOption)
+	}
+}
+
+// WithTxAwareCache prevents the cache from seeing data, that is not committed yet.
+// Inside a postgres.CtxTX, reads bypass the cache and writes only invalidate the cached entities,
+// so no other request is served an entity from a transaction that might still roll back.
+// If the transaction is started by postgres.InTx, e.g. by the app Tx decorators,
+// the entities are invalidated again after the commit, see postgres.AfterCommit.
+// For other transactions, e.g. one without hooks of postgres.WithTxHooks, they are only invalidated during the write,
+// so an entity read concurrently before the commit can stay cached until its TTL.
+// ONLY applies to the CachedRepository.
+func WithTxAwareCache() Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(*cacheConfig); ok {
+			repo.txAware = true
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithTxAwareCache can only be used with a CachedRepository", errInvalidOption)
+	}
+}
+
+// NewCachedRepository returns a read-through cache in front of repo.
+// Entities read by their ID are cached for a TTL in a size-bound LRU, see WithCacheTTL and WithCacheSize.
+// All writes through the CachedRepository invalidate the affected entities.
+// Writes to the underlying repository, that bypass the CachedRepository, are only visible after the TTL.
+//
+// Hits, misses, and evictions are reported as metrics via meterProvider.
+// It is expected that E has a field called `ID`, that can be overwritten by WithIDField.
+func NewCachedRepository[E any, ID id](
+	meterProvider metric.MeterProvider,
+	repo Repository[E, ID],
+	opts ...Option,
+) (*CachedRepository[E, ID], error) {
+	config := cacheConfig{
+		IDFieldName: "ID",
+		ttl:         defaultCacheTTL,
+		size:        defaultCacheSize,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := reflect.TypeFor[E]().FieldByName(config.IDFieldName); !ok {
+		return nil, fmt.Errorf("%w: entity does not have the ID field: %s", errInvalidOption, config.IDFieldName)
+	}
+
+	meter := meterProvider.Meter("arrower.repository")
+
+	lookups, err := meter.Int64Counter("repository_cache_lookups",
+		metric.WithDescription("number of cache lookups by result: hit or miss"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache metric: %w", err)
+	}
+
+	evictions, err := meter.Int64Counter("repository_cache_evictions",
+		metric.WithDescription("number of entities evicted because the cache was full"))
+	if err != nil {
+		return nil, fmt.Errorf("could not create cache metric: %w", err)
+	}
+
+	entity := attribute.String("entity", reflect.TypeFor[E]().Name())
+
+	return &CachedRepository[E, ID]{
+		Repository: repo,
+		config:     config,
+		mu:         sync.Mutex{},
+		entries:    make(map[ID]*list.Element),
+		lru:        list.New(),
+		lookups:    lookups,
+		evictions:  evictions,
+		hit:        metric.WithAttributes(entity, attribute.String("result", "hit")),
+		miss:       metric.WithAttributes(entity, attribute.String("result", "miss")),
+		evicted:    metric.WithAttributes(entity),
+		now:        time.Now,
+	}, nil
+}
+
+// CachedRepository caches the entities of a Repository, see NewCachedRepository.
+// All methods not reading or writing entities by their ID are passed through to the Repository.
+type CachedRepository[E any, ID id] struct {
+	Repository[E, ID]
+
+	config cacheConfig
+
+	mu      sync.Mutex
+	entries map[ID]*list.Element
+	// lru has the most recently used entry at the front.
+	lru *list.List
+	// epoch is increased on each invalidation,
+	// so a read started before an invalidation does not cache an outdated entity.
+	epoch uint64
+
+	lookups   metric.Int64Counter
+	evictions metric.Int64Counter
+	hit       metric.MeasurementOption
+	miss      metric.MeasurementOption
+	evicted   metric.MeasurementOption
+
+	now func() time.Time
+}
+
+type cacheConfig struct {
+	IDFieldName string
+	ttl         time.Duration
+	size        int
+	txAware     bool
+}
+
+type cacheEntry[E any, ID id] struct {
+	id      ID
+	entity  E
+	expires time.Time
+}
+
+func (repo *CachedRepository[E, ID]) Read(ctx context.Context, id ID) (E, error) { //nolint:ireturn,lll // valid use of generics
+	return repo.FindByID(ctx, id)
+}
+
+func (repo *CachedRepository[E, ID]) FindByID(ctx context.Context, id ID) (E, error) { //nolint:ireturn,lll // valid use of generics
+	if repo.bypass(ctx) {
+		return repo.Repository.FindByID(ctx, id) //nolint:wrapcheck // decorate but not change anything
+	}
+
+	if entity, ok := repo.get(ctx, id); ok {
+		return entity, nil
+	}
+
+	epoch := repo.currentEpoch()
+
+	entity, err := repo.Repository.FindByID(ctx, id)
+	if err != nil {
+		return entity, err //nolint:wrapcheck // decorate but not change anything
+	}
+
+	repo.set(ctx, epoch, entity)
+
+	return entity, nil
+}
+
+// AllByIDs returns the entities from the cache, if all of them are cached.
+// Otherwise, all entities are read from the repository.
+func (repo *CachedRepository[E, ID]) AllByIDs(ctx context.Context, ids []ID) ([]E, error) {
+	if repo.bypass(ctx) {
+		return repo.Repository.AllByIDs(ctx, ids) //nolint:wrapcheck // decorate but not change anything
+	}
+
+	entities := make([]E, 0, len(ids))
+
+	for _, id := range ids {
+		entity, ok := repo.get(ctx, id)
+		if !ok {
+			break
+		}
+
+		entities = append(entities, entity)
+	}
+
+	if len(entities) == len(ids) {
+		return entities, nil
+	}
+
+	epoch := repo.currentEpoch()
+
+	entities, err := repo.Repository.AllByIDs(ctx, ids)
+	if err != nil {
+		return entities, err //nolint:wrapcheck // decorate but not change anything
+	}
+
+	for _, entity := range entities {
+		repo.set(ctx, epoch, entity)
+	}
+
+	return entities, nil
+}
+
+func (repo *CachedRepository[E, ID]) Create(ctx context.Context, entity E) error {
+	defer repo.invalidate(ctx, repo.getID(entity))
+
+	return repo.Repository.Create(ctx, entity) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) Update(ctx context.Context, entity E) error {
+	defer repo.invalidate(ctx, repo.getID(entity))
+
+	return repo.Repository.Update(ctx, entity) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) Delete(ctx context.Context, entity E) error {
+	defer repo.invalidate(ctx, repo.getID(entity))
+
+	return repo.Repository.Delete(ctx, entity) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) Save(ctx context.Context, entity E) error {
+	defer repo.invalidate(ctx, repo.getID(entity))
+
+	return repo.Repository.Save(ctx, entity) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) Add(ctx context.Context, entity E) error {
+	defer repo.invalidate(ctx, repo.getID(entity))
+
+	return repo.Repository.Add(ctx, entity) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) CreateAll(ctx context.Context, entities []E) error {
+	defer repo.invalidate(ctx, repo.getIDs(entities)...)
+
+	return repo.Repository.CreateAll(ctx, entities) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) SaveAll(ctx context.Context, entities []E) error {
+	defer repo.invalidate(ctx, repo.getIDs(entities)...)
+
+	return repo.Repository.SaveAll(ctx, entities) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) UpdateAll(ctx context.Context, entities []E) error {
+	defer repo.invalidate(ctx, repo.getIDs(entities)...)
+
+	return repo.Repository.UpdateAll(ctx, entities) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) AddAll(ctx context.Context, entities []E) error {
+	defer repo.invalidate(ctx, repo.getIDs(entities)...)
+
+	return repo.Repository.AddAll(ctx, entities) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) DeleteByID(ctx context.Context, id ID) error {
+	defer repo.invalidate(ctx, id)
+
+	return repo.Repository.DeleteByID(ctx, id) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) DeleteByIDs(ctx context.Context, ids []ID) error {
+	defer repo.invalidate(ctx, ids...)
+
+	return repo.Repository.DeleteByIDs(ctx, ids) //nolint:wrapcheck // decorate but not change anything
+}
+
+// DeleteBy removes all entities from the cache, as it is not known which entities match the query.
+func (repo *CachedRepository[E, ID]) DeleteBy(ctx context.Context, query q.Query) error {
+	defer repo.purge(ctx)
+
+	return repo.Repository.DeleteBy(ctx, query) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) DeleteAll(ctx context.Context) error {
+	defer repo.purge(ctx)
+
+	return repo.Repository.DeleteAll(ctx) //nolint:wrapcheck // decorate but not change anything
+}
+
+func (repo *CachedRepository[E, ID]) Clear(ctx context.Context) error {
+	defer repo.purge(ctx)
+
+	return repo.Repository.Clear(ctx) //nolint:wrapcheck // decorate but not change anything
+}
+
+// Purge removes all entities from the cache.
+func (repo *CachedRepository[E, ID]) Purge() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.epoch++
+
+	clear(repo.entries)
+	repo.lru.Init()
+}
+
+// bypass reports if the cache is not used, because ctx contains a transaction
+// and the repository is tx aware.
+func (repo *CachedRepository[E, ID]) bypass(ctx context.Context) bool {
+	if !repo.config.txAware {
+		return false
+	}
+
+	_, ok := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+	return ok
+}
+
+func (repo *CachedRepository[E, ID]) get(ctx context.Context, id ID) (E, bool) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	elem, ok := repo.entries[id]
+	if ok && repo.now().After(elem.Value.(*cacheEntry[E, ID]).expires) { //nolint:forcetypeassert // only entries are stored
+		repo.remove(elem)
+
+		ok = false
+	}
+
+	if !ok {
+		repo.lookups.Add(ctx, 1, repo.miss)
+		return *new(E), false
+	}
+
+	repo.lookups.Add(ctx, 1, repo.hit)
+	repo.lru.MoveToFront(elem)
+
+	return elem.Value.(*cacheEntry[E, ID]).entity, true //nolint:forcetypeassert // only entries are stored
+}
+
+// set caches entity, if the cache was not invalidated since epoch.
+func (repo *CachedRepository[E, ID]) set(ctx context.Context, epoch uint64, entity E) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.epoch != epoch {
+		return
+	}
+
+	id := repo.getID(entity)
+
+	entry := &cacheEntry[E, ID]{id: id, entity: entity, expires: repo.now().Add(repo.config.ttl)}
+
+	if elem, ok := repo.entries[id]; ok {
+		elem.Value = entry
+		repo.lru.MoveToFront(elem)
+
+		return
+	}
+
+	repo.entries[id] = repo.lru.PushFront(entry)
+
+	for repo.lru.Len() > repo.config.size {
+		repo.remove(repo.lru.Back())
+		repo.evictions.Add(ctx, 1, repo.evicted)
+	}
+}
+
+// invalidate removes the entities with ids from the cache.
+// In a tx aware cache, they are removed again, after the transaction in ctx is committed,
+// as reads outside the transaction might have cached the old entities in the meantime.
+func (repo *CachedRepository[E, ID]) invalidate(ctx context.Context, ids ...ID) {
+	if repo.bypass(ctx) {
+		postgres.AfterCommit(ctx, func(context.Context) { repo.drop(ids...) })
+	}
+
+	repo.drop(ids...)
+}
+
+// purge is like invalidate for all entities.
+func (repo *CachedRepository[E, ID]) purge(ctx context.Context) {
+	if repo.bypass(ctx) {
+		postgres.AfterCommit(ctx, func(context.Context) { repo.Purge() })
+	}
+
+	repo.Purge()
+}
+
+func (repo *CachedRepository[E, ID]) drop(ids ...ID) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.epoch++
+
+	for _, id := range ids {
+		if elem, ok := repo.entries[id]; ok {
+			repo.remove(elem)
+		}
+	}
+}
+
+func (repo *CachedRepository[E, ID]) currentEpoch() uint64 {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.epoch
+}
+
+// remove removes the entry from the cache. The caller has to hold the lock.
+func (repo *CachedRepository[E, ID]) remove(elem *list.Element) {
+	id := repo.lru.Remove(elem).(*cacheEntry[E, ID]).id //nolint:forcetypeassert // only entries are stored
+
+	delete(repo.entries, id)
+}
+
+func (repo *CachedRepository[E, ID]) getID(entity E) ID { //nolint:ireturn // fp, as it is not recognised even with "generic" setting
+	return reflect.ValueOf(entity).FieldByName(repo.config.IDFieldName).Interface().(ID) //nolint:forcetypeassert,lll // ID is the type of the field
+}
+
+func (repo *CachedRepository[E, ID]) getIDs(entities []E) []ID {
+	ids := make([]ID, len(entities))
+	for i, e := range entities {
+		ids[i] = repo.getID(e)
+	}
+
+	return ids
+}
@@ -0,0 +1,267 @@
+package arepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/go-arrower/arrower/arepo"
+	"github.com/go-arrower/arrower/arepo/testdata"
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestCachedRepository(t *testing.T) {
+	t.Parallel()
+
+	newRepo := func(t *testing.T, opts ...arepo.Option) (
+		*arepo.CachedRepository[testdata.Entity, testdata.EntityID],
+		*arepo.MemoryRepository[testdata.Entity, testdata.EntityID],
+	) {
+		t.Helper()
+
+		base := arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID]()
+
+		repo, err := arepo.NewCachedRepository(noop.NewMeterProvider(), arepo.Repository[testdata.Entity, testdata.EntityID](base), opts...)
+		assert.NoError(t, err)
+
+		return repo, base
+	}
+
+	arepo.TestConformance(t,
+		func() arepo.Repository[testdata.Entity, testdata.EntityID] {
+			repo, _ := newRepo(t)
+			return repo
+		},
+		testdata.RandomEntity,
+	)
+
+	t.Run("read through", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t)
+		entity := testdata.RandomEntity()
+
+		_ = base.Create(t.Context(), entity)
+
+		got, err := repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, entity, got)
+
+		_ = base.Update(t.Context(), testdata.Entity{ID: entity.ID, Name: "changed"})
+
+		got, err = repo.Read(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, entity, got, "served from the cache")
+
+		all, err := repo.AllByIDs(t.Context(), []testdata.EntityID{entity.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, []testdata.Entity{entity}, all, "served from the cache")
+	})
+
+	t.Run("not found is not cached", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t)
+		entity := testdata.RandomEntity()
+
+		_, err := repo.FindByID(t.Context(), entity.ID)
+		assert.ErrorIs(t, err, arepo.ErrNotFound)
+
+		_ = base.Create(t.Context(), entity)
+
+		got, err := repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, entity, got)
+	})
+
+	t.Run("writes invalidate", func(t *testing.T) {
+		t.Parallel()
+
+		repo, _ := newRepo(t)
+		entity := testdata.RandomEntity()
+
+		_ = repo.Create(t.Context(), entity)
+		_, _ = repo.FindByID(t.Context(), entity.ID)
+
+		changed := testdata.Entity{ID: entity.ID, Name: "changed"}
+		err := repo.Update(t.Context(), changed)
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changed, got)
+
+		err = repo.DeleteAll(t.Context())
+		assert.NoError(t, err)
+
+		_, err = repo.FindByID(t.Context(), entity.ID)
+		assert.ErrorIs(t, err, arepo.ErrNotFound)
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t, arepo.WithCacheTTL(10*time.Millisecond))
+		entity := testdata.RandomEntity()
+
+		_ = base.Create(t.Context(), entity)
+		_, _ = repo.FindByID(t.Context(), entity.ID)
+
+		changed := testdata.Entity{ID: entity.ID, Name: "changed"}
+		_ = base.Update(t.Context(), changed)
+
+		time.Sleep(20 * time.Millisecond)
+
+		got, err := repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changed, got, "expired")
+	})
+
+	t.Run("evict least recently used", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t, arepo.WithCacheSize(2))
+		e0, e1, e2 := testdata.RandomEntity(), testdata.RandomEntity(), testdata.RandomEntity()
+
+		_ = base.AddAll(t.Context(), []testdata.Entity{e0, e1, e2})
+
+		for _, e := range []testdata.Entity{e0, e1, e2} {
+			_, _ = repo.FindByID(t.Context(), e.ID)
+		}
+
+		_ = base.UpdateAll(t.Context(), []testdata.Entity{
+			{ID: e0.ID, Name: "changed"},
+			{ID: e2.ID, Name: "changed"},
+		})
+
+		got, _ := repo.FindByID(t.Context(), e0.ID)
+		assert.Equal(t, "changed", got.Name, "evicted")
+
+		got, _ = repo.FindByID(t.Context(), e2.ID)
+		assert.Equal(t, e2.Name, got.Name, "still cached")
+	})
+
+	t.Run("tx aware", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t, arepo.WithTxAwareCache())
+		entity := testdata.RandomEntity()
+		txCtx := context.WithValue(t.Context(), postgres.CtxTX, pgx.Tx(fakeTx{}))
+
+		_ = base.Create(t.Context(), entity)
+		_, _ = repo.FindByID(t.Context(), entity.ID)
+
+		changed := testdata.Entity{ID: entity.ID, Name: "changed"}
+		_ = base.Update(t.Context(), changed)
+
+		got, err := repo.FindByID(txCtx, entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changed, got, "reads in a transaction bypass the cache")
+
+		got, err = repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, entity, got, "cache is not changed by the transaction")
+	})
+
+	t.Run("tx aware invalidates after commit", func(t *testing.T) {
+		t.Parallel()
+
+		repo, base := newRepo(t, arepo.WithTxAwareCache())
+		entity := testdata.RandomEntity()
+		changed := testdata.Entity{ID: entity.ID, Name: "changed"}
+
+		_ = base.Create(t.Context(), entity)
+
+		err := postgres.InTx(t.Context(), fakeBeginner{}, func(ctx context.Context) error {
+			err := repo.Update(ctx, changed)
+			assert.NoError(t, err)
+
+			// a read outside the transaction caches the entity before the change is committed
+			_ = base.Update(t.Context(), entity)
+			_, _ = repo.FindByID(t.Context(), entity.ID)
+			_ = base.Update(t.Context(), changed)
+
+			return nil
+		})
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(t.Context(), entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, changed, got, "invalidated after the commit")
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		t.Parallel()
+
+		reader := metric.NewManualReader()
+		base := arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID]()
+
+		repo, err := arepo.NewCachedRepository(metric.NewMeterProvider(metric.WithReader(reader)),
+			arepo.Repository[testdata.Entity, testdata.EntityID](base), arepo.WithCacheSize(1))
+		assert.NoError(t, err)
+
+		e0, e1 := testdata.RandomEntity(), testdata.RandomEntity()
+		_ = base.AddAll(t.Context(), []testdata.Entity{e0, e1})
+
+		_, _ = repo.FindByID(t.Context(), e0.ID) // miss
+		_, _ = repo.FindByID(t.Context(), e0.ID) // hit
+		_, _ = repo.FindByID(t.Context(), e1.ID) // miss & evict
+
+		rm := metricdata.ResourceMetrics{}
+		err = reader.Collect(t.Context(), &rm)
+		assert.NoError(t, err)
+
+		sums := map[string]int64{}
+
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints { //nolint:forcetypeassert // counters only
+				result, _ := dp.Attributes.Value("result")
+				sums[m.Name+result.AsString()] += dp.Value
+			}
+		}
+
+		assert.Equal(t, map[string]int64{
+			"repository_cache_lookupshit":  1,
+			"repository_cache_lookupsmiss": 2,
+			"repository_cache_evictions":   1,
+		}, sums)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := arepo.NewCachedRepository(noop.NewMeterProvider(),
+			arepo.Repository[testdata.Entity, testdata.EntityID](arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID]()),
+			arepo.WithStore(arepo.NoopStore),
+		)
+		assert.Error(t, err)
+
+		_, err = arepo.NewCachedRepository(noop.NewMeterProvider(),
+			arepo.Repository[testdata.Entity, testdata.EntityID](arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID]()),
+			arepo.WithCacheSize(0),
+		)
+		assert.Error(t, err)
+	})
+}
+
+// fakeTx marks a context as being inside a transaction, that does nothing.
+type fakeTx struct {
+	pgx.Tx
+}
+
+func (fakeTx) Begin(context.Context) (pgx.Tx, error) { return fakeTx{}, nil }
+func (fakeTx) Commit(context.Context) error          { return nil }
+func (fakeTx) Rollback(context.Context) error        { return nil }
+
+// fakeBeginner starts transactions that do nothing.
+type fakeBeginner struct{}
+
+func (fakeBeginner) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return fakeTx{}, nil
+}
@@ -0,0 +1,72 @@
+package arepo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+// Operation is the kind of change recorded in the history of an entity.
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+// Change is a single entry in the history of an entity.
+type Change[E any, ID id] struct {
+	EntityID  ID
+	Operation Operation
+	// Old is the entity before the change. It is nil for OperationCreate.
+	Old *E
+	// New is the entity after the change. It is nil for OperationDelete.
+	New       *E
+	ChangedAt time.Time
+	// ChangedBy is the id of the user that was logged in, see auth.CurrentUserID.
+	ChangedBy string
+}
+
+// HistoryReader is implemented by repositories recording the changes of their entities, see WithHistory.
+type HistoryReader[E any, ID id] interface {
+	// History returns all changes of the entity with the given id, the oldest change first.
+	History(ctx context.Context, id ID) ([]Change[E, ID], error)
+}
+
+// WithHistory records every create, update, and delete made through the repository,
+// including the old and new value of the entity, the time, and the acting user.
+// Read the changes of an entity with History.
+//
+// The PostgresRepository writes the changes into the history table, see PostgresRepository.HistoryTable,
+// in the same transaction as the change itself.
+// The MemoryRepository keeps the changes in memory.
+func WithHistory() Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(interface{ enableHistory() }); ok {
+			repo.enableHistory()
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithHistory is not supported by this repository", errInvalidOption)
+	}
+}
+
+// ctxUserID is the key the auth Context stores the id of the logged-in user under.
+// It is not imported from the auth package, as that would result in an import cycle.
+const ctxUserID ctx2.CTXKey = "auth.user_id"
+
+// currentUserID returns the id of the logged-in user, same as auth.CurrentUserID.
+func currentUserID(ctx context.Context) string {
+	v := reflect.ValueOf(ctx.Value(ctxUserID))
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+
+	return ""
+}
+
+var errHistoryDisabled = fmt.Errorf("%w: history is not recorded, use WithHistory", ErrStorage)
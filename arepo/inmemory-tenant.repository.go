@@ -5,11 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"reflect"
 	"sync"
 
 	"github.com/google/uuid"
+
+	"github.com/go-arrower/arrower/arepo/q"
 )
 
 var ErrSaveFailed = errors.New("") // TODO REMOVE: only use the errors defined in repository.go
@@ -247,6 +250,25 @@ func (repo *MemoryTenantRepository[tID, E, eID]) AllOfTenant(_ context.Context,
 	return result, nil
 }
 
+// AllByIter iterates over all entities of the tenant matching query.
+// The entities are taken from the repository when the iteration starts,
+// so the repository can be changed while iterating.
+func (repo *MemoryTenantRepository[tID, E, eID]) AllByIter(_ context.Context, tenantID tID, query q.Query) Iterator[E, eID] {
+	return MemoryIterator[E, eID]{
+		entities: func() ([]E, error) {
+			repo.Lock()
+			defer repo.Unlock()
+
+			entities, err := filterEntities(maps.Values(repo.Data[tenantID]), query, repo.searchFields)
+			if err != nil {
+				return nil, err
+			}
+
+			return orderAndPage(entities, query)
+		},
+	}
+}
+
 func (repo *MemoryTenantRepository[tID, E, eID]) AllByIDs(_ context.Context, tenantID tID, ids []eID) ([]E, error) {
 	repo.Lock()
 	defer repo.Unlock()
@@ -1,5 +1,15 @@
 package arepo_test
 
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo"
+	"github.com/go-arrower/arrower/arepo/q"
+	"github.com/go-arrower/arrower/arepo/testdata"
+)
+
 //
 // import (
 //	"testing"
@@ -321,3 +331,22 @@ package arepo_test
 //	assert.NoError(t, err)
 //	assert.False(t, ex)
 // }
+
+func TestMemoryTenantRepository_AllByIter(t *testing.T) {
+	t.Parallel()
+
+	repo := arepo.NewMemoryTenantRepository[string, testdata.Entity, testdata.EntityID]()
+	_ = repo.Add(t.Context(), "tenant", testdata.Entity{ID: "1", Name: "b"})
+	_ = repo.Add(t.Context(), "tenant", testdata.Entity{ID: "2", Name: "a"})
+	_ = repo.Add(t.Context(), "other", testdata.Entity{ID: "3", Name: "a"})
+
+	names := []string{}
+
+	for e, err := range repo.AllByIter(t.Context(), "tenant", q.Query{}.OrderBy("name").Ascending()).Next() {
+		assert.NoError(t, err)
+
+		names = append(names, e.Name)
+	}
+
+	assert.Equal(t, []string{"a", "b"}, names)
+}
@@ -1,13 +1,18 @@
 package arepo
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"maps"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/georgysavva/scany/v2/dbscan"
 	"github.com/google/uuid"
@@ -17,6 +22,7 @@ import (
 
 // WithStore sets a Store used to persist the Repository.
 // ONLY applies to the in memory implementations.
+// If store is an AppendStore, e.g. a LogStore, only the changed entities are persisted.
 //
 // There are no transactions or any consistency guarantees at all! For example, if a store fails,
 // the collection is still changed in memory of the repository.
@@ -102,13 +108,87 @@ type MemoryRepository[E any, ID id] struct {
 	Data map[ID]E
 
 	currentIntID ID
+	history      []Change[E, ID]
 	memoryRepoConfig
 }
 
 type memoryRepoConfig struct {
-	IDFieldName string
-	store       Store
-	filename    string
+	IDFieldName   string
+	store         Store
+	filename      string
+	recordHistory bool
+	searchFields  map[string]SearchWeight
+	timestamps
+}
+
+func (c *memoryRepoConfig) setSearchFields(fields map[string]SearchWeight) {
+	c.searchFields = fields
+}
+
+func (c *memoryRepoConfig) enableHistory() {
+	c.recordHistory = true
+}
+
+// record appends a change to the history, if WithHistory is used.
+// The caller has to hold the lock.
+func (repo *MemoryRepository[E, ID]) record(ctx context.Context, op Operation, oldEntity *E, newEntity *E) {
+	if !repo.recordHistory {
+		return
+	}
+
+	entity := newEntity
+	if entity == nil {
+		entity = oldEntity
+	}
+
+	repo.history = append(repo.history, Change[E, ID]{
+		EntityID:  repo.getID(*entity),
+		Operation: op,
+		Old:       oldEntity,
+		New:       newEntity,
+		ChangedAt: repo.clock(),
+		ChangedBy: currentUserID(ctx),
+	})
+}
+
+// History returns all changes of the entity with the given id, the oldest change first.
+// It requires the repository to be created WithHistory.
+func (repo *MemoryRepository[E, ID]) History(_ context.Context, id ID) ([]Change[E, ID], error) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	if !repo.recordHistory {
+		return nil, errHistoryDisabled
+	}
+
+	changes := []Change[E, ID]{}
+
+	for _, c := range repo.history {
+		if c.EntityID == id {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, nil
+}
+
+// persist persists the entities with the given ids, after they are changed in Data.
+// If the Store is an AppendStore, only these entities are appended, otherwise all Data is stored.
+// The caller has to hold the lock.
+func (repo *MemoryRepository[E, ID]) persist(ids ...ID) error {
+	store, ok := repo.store.(AppendStore)
+	if !ok {
+		return repo.store.Store(repo.filename, repo.Data) //nolint:wrapcheck // wrapped by the caller
+	}
+
+	changes := make([]StoreChange, len(ids))
+
+	for i, id := range ids {
+		entity, found := repo.Data[id]
+		changes[i] = StoreChange{ID: id, Entity: entity, Deleted: !found}
+	}
+
+	return store.Append(repo.filename, changes...) //nolint:wrapcheck // wrapped by the caller
 }
 
 func defaultFileName(entity any) string {
@@ -136,6 +216,15 @@ func (repo *MemoryRepository[E, ID]) getID(t any) ID { //nolint:ireturn,lll // n
 	return id
 }
 
+func (repo *MemoryRepository[E, ID]) getIDs(entities []E) []ID {
+	ids := make([]ID, len(entities))
+	for i, e := range entities {
+		ids[i] = repo.getID(e)
+	}
+
+	return ids
+}
+
 // NextID returns a new ID. It can be of the underlying type of string or integer.
 func (repo *MemoryRepository[E, ID]) NextID(_ context.Context) (ID, error) { //nolint:ireturn,lll // fp, as it is not recognised even with "generic" setting
 	var id ID
@@ -175,7 +264,7 @@ func (repo *MemoryRepository[E, ID]) NextID(_ context.Context) (ID, error) { //n
 	return id, nil
 }
 
-func (repo *MemoryRepository[E, ID]) Create(_ context.Context, entity E) error {
+func (repo *MemoryRepository[E, ID]) Create(ctx context.Context, entity E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -188,14 +277,17 @@ func (repo *MemoryRepository[E, ID]) Create(_ context.Context, entity E) error {
 		return ErrAlreadyExists
 	}
 
+	entity = stamp(ctx, &repo.timestamps, repo.clock(), entity, true)
 	repo.Data[id] = entity
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(id)
 	if err != nil {
 		delete(repo.Data, id)
 		return fmt.Errorf("%w: could not store: %w", errCreateFailed, err)
 	}
 
+	repo.record(ctx, OperationCreate, nil, &entity)
+
 	return nil
 }
 
@@ -203,7 +295,7 @@ func (repo *MemoryRepository[E, ID]) Read(ctx context.Context, id ID) (E, error)
 	return repo.FindByID(ctx, id)
 }
 
-func (repo *MemoryRepository[E, ID]) Update(_ context.Context, entity E) error {
+func (repo *MemoryRepository[E, ID]) Update(ctx context.Context, entity E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -217,32 +309,39 @@ func (repo *MemoryRepository[E, ID]) Update(_ context.Context, entity E) error {
 	}
 
 	oldEntity := repo.Data[id]
+	entity = keepCreated(&repo.timestamps, stamp(ctx, &repo.timestamps, repo.clock(), entity, false), oldEntity)
 	repo.Data[id] = entity
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(id)
 	if err != nil {
 		repo.Data[id] = oldEntity
 		return fmt.Errorf("%w: could not store: %w", errUpdateFailed, err)
 	}
 
+	repo.record(ctx, OperationUpdate, &oldEntity, &entity)
+
 	return nil
 }
 
-func (repo *MemoryRepository[E, ID]) Delete(_ context.Context, entity E) error {
+func (repo *MemoryRepository[E, ID]) Delete(ctx context.Context, entity E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
 	id := repo.getID(entity)
-	oldEntity := repo.Data[id]
+	oldEntity, found := repo.Data[id]
 
 	delete(repo.Data, id)
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(id)
 	if err != nil {
 		repo.Data[id] = oldEntity
 		return fmt.Errorf("%w: could not store: %w", errDeleteFailed, err)
 	}
 
+	if found {
+		repo.record(ctx, OperationDelete, &oldEntity, nil)
+	}
+
 	return nil
 }
 
@@ -263,16 +362,41 @@ func (repo *MemoryRepository[E, ID]) AllBy(_ context.Context, query q.Query) ([]
 	repo.Lock()
 	defer repo.Unlock()
 
+	entities, err := repo.filter(query)
+	if err != nil {
+		return []E{}, err
+	}
+
+	return orderAndPage(entities, query)
+}
+
+// Search returns all entities containing text in one of the fields, the best match first.
+// Each word of text has to be contained in one of the fields.
+// Without fields, the fields set by WithSearchFields or all string fields are searched.
+func (repo *MemoryRepository[E, ID]) Search(ctx context.Context, text string, fields ...string) ([]E, error) {
+	return repo.AllBy(ctx, q.Search(text, fields...))
+}
+
+// filter returns all entities matching query.
+// The caller has to hold the lock.
+func (repo *MemoryRepository[E, ID]) filter(query q.Query) ([]E, error) {
+	return filterEntities(maps.Values(repo.Data), query, repo.searchFields)
+}
+
+// filterEntities returns all entities matching the conditions and the search of query.
+func filterEntities[E any](entities iter.Seq[E], query q.Query, searchFields map[string]SearchWeight) ([]E, error) {
+	for _, cond := range query.Conditions.Conditions {
+		if cond.Value == nil {
+			return []E{}, fmt.Errorf("%w: value can not be nil", errInvalidQuery)
+		}
+	}
+
 	filteredEntities := []E{}
 
-	for _, entity := range repo.Data {
+	for entity := range entities {
 		fieldsThatMatch := 0
 
 		for _, cond := range query.Conditions.Conditions {
-			if cond.Value == nil {
-				return []E{}, fmt.Errorf("%w: value can not be nil", errInvalidQuery)
-			}
-
 			name := fieldName(reflect.TypeOf(entity), cond.Field)
 			if !reflect.ValueOf(entity).FieldByName(name).IsValid() {
 				return []E{}, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, cond.Field)
@@ -299,7 +423,51 @@ func (repo *MemoryRepository[E, ID]) AllBy(_ context.Context, query q.Query) ([]
 		}
 	}
 
-	return filteredEntities, nil
+	return searchEntities(filteredEntities, query, searchFields)
+}
+
+// orderAndPage sorts the entities by the orders of query and applies its limit and offset.
+func orderAndPage[E any](entities []E, query q.Query) ([]E, error) {
+	entityType := reflect.TypeFor[E]()
+
+	for _, order := range query.Orders() {
+		if _, ok := entityType.FieldByName(fieldName(entityType, order.Field)); !ok {
+			return []E{}, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, order.Field)
+		}
+	}
+
+	var compareErr error
+
+	slices.SortStableFunc(entities, func(a, b E) int {
+		for _, order := range query.Orders() {
+			name := fieldName(entityType, order.Field)
+
+			c, ok := compareOrder(reflect.ValueOf(a).FieldByName(name), reflect.ValueOf(b).FieldByName(name), order)
+			if !ok {
+				compareErr = fmt.Errorf("%w: field %s can not be ordered", errInvalidQuery, order.Field)
+				return 0
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+
+		return 0
+	})
+
+	if compareErr != nil {
+		return []E{}, compareErr
+	}
+
+	limit, offset := query.Pagination()
+	entities = entities[min(max(offset, 0), len(entities)):]
+
+	if limit > 0 && limit < len(entities) {
+		entities = entities[:limit]
+	}
+
+	return entities, nil
 }
 
 // fieldName returns the case-insensitive name of the field. Except is the field being quoted.
@@ -319,7 +487,7 @@ func fieldName(entity reflect.Type, name string) string {
 		}
 
 		var condName string
-		if dbTag := entity.Field(i).Tag.Get("db"); dbTag != "" {
+		if dbTag, _, _ := strings.Cut(entity.Field(i).Tag.Get("db"), ","); dbTag != "" {
 			condName = dbTag
 			if strings.Contains(condName, ".") {
 				condName = fmt.Sprintf(`"%s"`, dbTag)
@@ -396,6 +564,15 @@ func (repo *MemoryRepository[E, ID]) Exist(_ context.Context, id ID) (bool, erro
 	return false, nil
 }
 
+func (repo *MemoryRepository[E, ID]) ExistBy(ctx context.Context, query q.Query) (bool, error) {
+	count, err := repo.CountBy(ctx, query)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", errExistsFailed, err)
+	}
+
+	return count > 0, nil
+}
+
 func (repo *MemoryRepository[E, ID]) ExistByID(ctx context.Context, id ID) (bool, error) {
 	return repo.Exist(ctx, id)
 }
@@ -441,7 +618,7 @@ func (repo *MemoryRepository[E, ID]) CreateAll(ctx context.Context, entities []E
 	return repo.AddAll(ctx, entities)
 }
 
-func (repo *MemoryRepository[E, ID]) Save(_ context.Context, entity E) error {
+func (repo *MemoryRepository[E, ID]) Save(ctx context.Context, entity E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -450,18 +627,41 @@ func (repo *MemoryRepository[E, ID]) Save(_ context.Context, entity E) error {
 		return fmt.Errorf("%w: %s is empty", errSaveFailed, repo.IDFieldName)
 	}
 
+	oldEntity, found := repo.Data[id]
+	entity = repo.stampSave(ctx, repo.clock(), entity, oldEntity, found)
 	repo.Data[id] = entity
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(id)
 	if err != nil {
 		delete(repo.Data, id)
 		return fmt.Errorf("%w: could not store: %w", errSaveFailed, err)
 	}
 
+	repo.recordSave(ctx, found, oldEntity, entity)
+
 	return nil
 }
 
-func (repo *MemoryRepository[E, ID]) SaveAll(_ context.Context, entities []E) error {
+// stampSave stamps entity as created or as updated, depending on the entity existing before.
+func (repo *MemoryRepository[E, ID]) stampSave(ctx context.Context, now time.Time, entity E, oldEntity E, existed bool) E {
+	if existed {
+		return keepCreated(&repo.timestamps, stamp(ctx, &repo.timestamps, now, entity, false), oldEntity)
+	}
+
+	return stamp(ctx, &repo.timestamps, now, entity, true)
+}
+
+// recordSave records a save either as create or as update, depending on the entity existing before.
+func (repo *MemoryRepository[E, ID]) recordSave(ctx context.Context, existed bool, oldEntity E, newEntity E) {
+	if existed {
+		repo.record(ctx, OperationUpdate, &oldEntity, &newEntity)
+		return
+	}
+
+	repo.record(ctx, OperationCreate, nil, &newEntity)
+}
+
+func (repo *MemoryRepository[E, ID]) SaveAll(ctx context.Context, entities []E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -472,13 +672,19 @@ func (repo *MemoryRepository[E, ID]) SaveAll(_ context.Context, entities []E) er
 	}
 
 	oldEntities := []E{}
-
-	for _, e := range entities {
-		oldEntities = append(oldEntities, repo.Data[repo.getID(e)])
-		repo.Data[repo.getID(e)] = e
+	existed := []bool{}
+	entities = slices.Clone(entities)
+	now := repo.clock()
+
+	for i, e := range entities {
+		old, found := repo.Data[repo.getID(e)]
+		oldEntities = append(oldEntities, old)
+		existed = append(existed, found)
+		entities[i] = repo.stampSave(ctx, now, e, old, found)
+		repo.Data[repo.getID(e)] = entities[i]
 	}
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(repo.getIDs(entities)...)
 	if err != nil {
 		for _, e := range oldEntities {
 			repo.Data[repo.getID(e)] = e
@@ -487,10 +693,14 @@ func (repo *MemoryRepository[E, ID]) SaveAll(_ context.Context, entities []E) er
 		return fmt.Errorf("%w: could not store: %w", errSaveFailed, err)
 	}
 
+	for i, e := range entities {
+		repo.recordSave(ctx, existed[i], oldEntities[i], e)
+	}
+
 	return nil
 }
 
-func (repo *MemoryRepository[E, ID]) UpdateAll(_ context.Context, entities []E) error {
+func (repo *MemoryRepository[E, ID]) UpdateAll(ctx context.Context, entities []E) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -502,21 +712,23 @@ func (repo *MemoryRepository[E, ID]) UpdateAll(_ context.Context, entities []E)
 
 	oldEntities := []E{}
 	updatedEntities := []E{}
+	now := repo.clock()
 
 	for _, e := range entities {
 		if _, found := repo.Data[repo.getID(e)]; !found {
 			return fmt.Errorf("%w: at least one entity %w", errUpdateFailed, ErrNotFound)
 		}
 
-		oldEntities = append(oldEntities, repo.Data[repo.getID(e)])
-		updatedEntities = append(updatedEntities, e)
+		old := repo.Data[repo.getID(e)]
+		oldEntities = append(oldEntities, old)
+		updatedEntities = append(updatedEntities, keepCreated(&repo.timestamps, stamp(ctx, &repo.timestamps, now, e, false), old))
 	}
 
 	for _, e := range updatedEntities {
 		repo.Data[repo.getID(e)] = e
 	}
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(repo.getIDs(updatedEntities)...)
 	if err != nil {
 		for _, e := range oldEntities {
 			repo.Data[repo.getID(e)] = e
@@ -525,6 +737,10 @@ func (repo *MemoryRepository[E, ID]) UpdateAll(_ context.Context, entities []E)
 		return fmt.Errorf("%w: could not store: %w", errUpdateFailed, err)
 	}
 
+	for i := range updatedEntities {
+		repo.record(ctx, OperationUpdate, &oldEntities[i], &updatedEntities[i])
+	}
+
 	return nil
 }
 
@@ -550,26 +766,284 @@ func (repo *MemoryRepository[E, ID]) Count(_ context.Context) (int, error) {
 	return len(repo.Data), nil
 }
 
+func (repo *MemoryRepository[E, ID]) CountBy(_ context.Context, query q.Query) (int, error) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	entities, err := repo.filter(query)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errCountFailed, err)
+	}
+
+	return len(entities), nil
+}
+
 func (repo *MemoryRepository[E, ID]) Length(ctx context.Context) (int, error) {
 	return repo.Count(ctx)
 }
 
+func (repo *MemoryRepository[E, ID]) Sum(_ context.Context, field string, query q.Query) (float64, error) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	values, err := repo.fieldValues(field, query)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errAggregateFailed, err)
+	}
+
+	// like in Postgres, the type of the field is checked, even if no entity matches
+	entityType := reflect.TypeOf(*new(E))
+	structField, _ := entityType.FieldByName(fieldName(entityType, field))
+
+	fieldType := structField.Type
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	var toFloat func(v reflect.Value) float64
+
+	switch fieldType.Kind() { //nolint:exhaustive // all other kinds are not numeric
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		toFloat = func(v reflect.Value) float64 { return float64(v.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		toFloat = func(v reflect.Value) float64 { return float64(v.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		toFloat = reflect.Value.Float
+	default:
+		return 0, fmt.Errorf("%w: %w: field %s is not numeric", errAggregateFailed, errInvalidQuery, field)
+	}
+
+	var sum float64
+
+	for _, v := range values {
+		for v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+
+		if v.Kind() == reflect.Pointer {
+			continue // like in Postgres, NULL is skipped
+		}
+
+		sum += toFloat(v)
+	}
+
+	return sum, nil
+}
+
+func (repo *MemoryRepository[E, ID]) Min(_ context.Context, field string, query q.Query) (any, error) {
+	return repo.aggregate(field, query, -1)
+}
+
+func (repo *MemoryRepository[E, ID]) Max(_ context.Context, field string, query q.Query) (any, error) {
+	return repo.aggregate(field, query, 1)
+}
+
+// aggregate returns the value of field, that compares to all others with the given direction:
+// -1 returns the smallest value and 1 the biggest.
+func (repo *MemoryRepository[E, ID]) aggregate(field string, query q.Query, direction int) (any, error) {
+	repo.Lock()
+	defer repo.Unlock()
+
+	values, err := repo.fieldValues(field, query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errAggregateFailed, err)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("entities %w", ErrNotFound)
+	}
+
+	result := values[0]
+
+	for _, v := range values[1:] {
+		c, ok := compareValues(v, result)
+		if !ok {
+			return nil, fmt.Errorf("%w: %w: field %s is not comparable", errAggregateFailed, errInvalidQuery, field)
+		}
+
+		if c == direction {
+			result = v
+		}
+	}
+
+	return result.Interface(), nil
+}
+
+// fieldValues returns the values of field of all entities matching query.
+// The caller has to hold the lock.
+func (repo *MemoryRepository[E, ID]) fieldValues(field string, query q.Query) ([]reflect.Value, error) {
+	name := fieldName(reflect.TypeOf(*new(E)), field)
+	if _, ok := reflect.TypeOf(*new(E)).FieldByName(name); !ok {
+		return nil, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, field)
+	}
+
+	entities, err := repo.filter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]reflect.Value, len(entities))
+	for i, e := range entities {
+		values[i] = reflect.ValueOf(e).FieldByName(name)
+	}
+
+	return values, nil
+}
+
+// compareOrder returns -1 if a is sorted before b, 0 if their order is equal, and 1 if a is sorted after b.
+// Like in Postgres, nil pointers are larger than any value, unless order defines otherwise.
+// If the values can not be compared, false is returned.
+func compareOrder(a, b reflect.Value, order q.Order) (int, bool) {
+	aNull := a.Kind() == reflect.Pointer && a.IsNil()
+	bNull := b.Kind() == reflect.Pointer && b.IsNil()
+
+	if aNull || bNull {
+		nullsFirst := order.Descending
+		if order.Nulls != q.NullsDefault {
+			nullsFirst = order.Nulls == q.NullsFirst
+		}
+
+		switch {
+		case aNull && bNull:
+			return 0, true
+		case aNull == nullsFirst:
+			return -1, true
+		default:
+			return 1, true
+		}
+	}
+
+	c, ok := compareValues(reflect.Indirect(a), reflect.Indirect(b))
+	if order.Descending {
+		c = -c
+	}
+
+	return c, ok
+}
+
+// compareValues returns -1 if a is less than b, 0 if they are equal, and 1 if a is bigger than b.
+// If the values can not be compared, false is returned.
+func compareValues(a, b reflect.Value) (int, bool) {
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Compare(b.Interface().(time.Time)), true //nolint:forcetypeassert // a and b are of the same field
+	}
+
+	switch a.Kind() { //nolint:exhaustive // all other kinds are not ordered
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float()), true
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String()), true
+	default:
+		return 0, false
+	}
+}
+
+func (repo *MemoryRepository[E, ID]) Project(_ context.Context, query q.Query, dst any) error {
+	projection, err := projectionType(dst)
+	if err != nil {
+		return err
+	}
+
+	entityType := reflect.TypeOf(*new(E))
+
+	// map each field of the projection to the field of the entity with the same column, same as the PostgresRepository
+	fields := make([]string, projection.NumField())
+
+	for i := range projection.NumField() {
+		field := projection.Field(i)
+
+		column, opts := parseDBTag(field)
+		if !field.IsExported() || column == "-" || opts.children {
+			continue
+		}
+
+		column = columnName(field)
+
+		entityField, ok := columnField(entityType, column)
+		if !ok || !entityField.Type.AssignableTo(field.Type) {
+			return fmt.Errorf("%w: entity does not have column: %s of type %s",
+				errInvalidQuery, column, field.Type.String())
+		}
+
+		fields[i] = entityField.Name
+	}
+
+	repo.Lock()
+	defer repo.Unlock()
+
+	entities, err := repo.filter(query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFindFailed, err)
+	}
+
+	entities, err = orderAndPage(entities, query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFindFailed, err)
+	}
+
+	result := reflect.MakeSlice(reflect.SliceOf(projection), 0, len(entities))
+
+	for _, e := range entities {
+		entity := reflect.ValueOf(e)
+		p := reflect.New(projection).Elem()
+
+		for i, name := range fields {
+			if name != "" {
+				p.Field(i).Set(entity.FieldByName(name))
+			}
+		}
+
+		result = reflect.Append(result, p)
+	}
+
+	reflect.ValueOf(dst).Elem().Set(result)
+
+	return nil
+}
+
+// columnField returns the field of the entity, that is stored in column.
+func columnField(entity reflect.Type, column string) (reflect.StructField, bool) {
+	for i := range entity.NumField() {
+		if f := entity.Field(i); f.IsExported() && columnName(f) == column {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// columnName returns the column of the field, from its db tag or its name in snake case.
+func columnName(f reflect.StructField) string {
+	if column, _ := parseDBTag(f); column != "" {
+		return column
+	}
+
+	return dbscan.SnakeCaseMapper(f.Name)
+}
+
 func (repo *MemoryRepository[E, ID]) DeleteByID(ctx context.Context, id ID) error {
 	return repo.DeleteByIDs(ctx, []ID{id})
 }
 
-func (repo *MemoryRepository[E, ID]) DeleteByIDs(_ context.Context, ids []ID) error {
+func (repo *MemoryRepository[E, ID]) DeleteByIDs(ctx context.Context, ids []ID) error {
 	repo.Lock()
 	defer repo.Unlock()
 
 	oldEntities := []E{}
 
 	for _, id := range ids {
-		oldEntities = append(oldEntities, repo.Data[id])
+		if e, found := repo.Data[id]; found {
+			oldEntities = append(oldEntities, e)
+		}
+
 		delete(repo.Data, id)
 	}
 
-	err := repo.store.Store(repo.filename, repo.Data)
+	err := repo.persist(ids...)
 	if err != nil {
 		for _, e := range oldEntities {
 			repo.Data[repo.getID(e)] = e
@@ -578,10 +1052,43 @@ func (repo *MemoryRepository[E, ID]) DeleteByIDs(_ context.Context, ids []ID) er
 		return fmt.Errorf("%w: could not store: %w", errDeleteFailed, err)
 	}
 
+	for i := range oldEntities {
+		repo.record(ctx, OperationDelete, &oldEntities[i], nil)
+	}
+
 	return nil
 }
 
-func (repo *MemoryRepository[E, ID]) DeleteAll(_ context.Context) error {
+func (repo *MemoryRepository[E, ID]) DeleteBy(ctx context.Context, query q.Query) error {
+	repo.Lock()
+	defer repo.Unlock()
+
+	entities, err := repo.filter(query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errDeleteFailed, err)
+	}
+
+	for _, e := range entities {
+		delete(repo.Data, repo.getID(e))
+	}
+
+	err = repo.persist(repo.getIDs(entities)...)
+	if err != nil {
+		for _, e := range entities {
+			repo.Data[repo.getID(e)] = e
+		}
+
+		return fmt.Errorf("%w: could not store: %w", errDeleteFailed, err)
+	}
+
+	for i := range entities {
+		repo.record(ctx, OperationDelete, &entities[i], nil)
+	}
+
+	return nil
+}
+
+func (repo *MemoryRepository[E, ID]) DeleteAll(ctx context.Context) error {
 	repo.Lock()
 	defer repo.Unlock()
 
@@ -601,6 +1108,10 @@ func (repo *MemoryRepository[E, ID]) DeleteAll(_ context.Context) error {
 		return fmt.Errorf("%w: could not store: %w", errDeleteFailed, err)
 	}
 
+	for _, e := range oldEntities {
+		repo.record(ctx, OperationDelete, &e, nil)
+	}
+
 	return nil
 }
 
@@ -608,17 +1119,34 @@ func (repo *MemoryRepository[E, ID]) Clear(ctx context.Context) error {
 	return repo.DeleteAll(ctx)
 }
 
-func (repo *MemoryRepository[E, ID]) AllIter(_ context.Context) Iterator[E, ID] {
-	return MemoryIterator[E, ID]{repo: repo}
+func (repo *MemoryRepository[E, ID]) AllIter(ctx context.Context) Iterator[E, ID] {
+	return repo.AllByIter(ctx, q.Query{})
+}
+
+// AllByIter iterates over all entities matching query.
+// The entities are taken from the repository when the iteration starts,
+// so the repository can be changed while iterating.
+func (repo *MemoryRepository[E, ID]) AllByIter(ctx context.Context, query q.Query) Iterator[E, ID] {
+	return MemoryIterator[E, ID]{
+		entities: func() ([]E, error) {
+			return repo.AllBy(ctx, query)
+		},
+	}
 }
 
 type MemoryIterator[E any, ID id] struct {
-	repo *MemoryRepository[E, ID]
+	entities func() ([]E, error)
 }
 
 func (i MemoryIterator[E, ID]) Next() func(yield func(e E, err error) bool) {
 	return func(yield func(e E, err error) bool) {
-		for _, e := range i.repo.Data {
+		entities, err := i.entities()
+		if err != nil {
+			yield(*new(E), fmt.Errorf("%w: %w", errFindFailed, err))
+			return
+		}
+
+		for _, e := range entities {
 			if !yield(e, nil) {
 				return
 			}
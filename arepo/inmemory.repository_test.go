@@ -2,11 +2,13 @@ package arepo_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-arrower/arrower/arepo"
+	"github.com/go-arrower/arrower/arepo/q"
 	"github.com/go-arrower/arrower/arepo/testdata"
 )
 
@@ -215,3 +217,67 @@ func TestNewMemoryRepository_IntPK(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestMemoryRepository_OrderNulls(t *testing.T) {
+	t.Parallel()
+
+	testOrderNulls(t, arepo.NewMemoryRepository[stampedEntity, string]())
+}
+
+// testOrderNulls ensures all repositories sort missing values in the same way.
+func testOrderNulls(t *testing.T, repo arepo.Repository[stampedEntity, string]) {
+	t.Helper()
+
+	early := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	err := repo.AddAll(t.Context(), []stampedEntity{
+		{ID: "late", UpdatedAt: &late},
+		{ID: "null"},
+		{ID: "early", UpdatedAt: &early},
+	})
+	assert.NoError(t, err)
+
+	updatedAt := q.Ref(func(e *stampedEntity) **time.Time { return &e.UpdatedAt })
+
+	ids := func(query q.Query) []string {
+		all, err := repo.AllBy(t.Context(), query)
+		assert.NoError(t, err)
+
+		ids := make([]string, len(all))
+		for i, e := range all {
+			ids[i] = e.ID
+		}
+
+		return ids
+	}
+
+	assert.Equal(t, []string{"early", "late", "null"}, ids(updatedAt.Ascending()))
+	assert.Equal(t, []string{"null", "late", "early"}, ids(updatedAt.Descending()))
+	assert.Equal(t, []string{"null", "early", "late"},
+		ids(q.Query{}.OrderBy(updatedAt.String()).NullsFirst().Ascending()))
+	assert.Equal(t, []string{"late", "early", "null"},
+		ids(q.Query{}.OrderBy(updatedAt.String()).NullsLast().Descending()))
+}
+
+func TestMemoryRepository_TagOptions(t *testing.T) {
+	t.Parallel()
+
+	type entity struct {
+		ID       string
+		Nickname string `db:"nick,json"`
+	}
+
+	repo := arepo.NewMemoryRepository[entity, string]()
+
+	err := repo.AddAll(t.Context(), []entity{{ID: "1", Nickname: "b"}, {ID: "2", Nickname: "a"}})
+	assert.NoError(t, err)
+
+	all, err := repo.AllBy(t.Context(), q.Where("nick").Is("a"))
+	assert.NoError(t, err)
+	assert.Equal(t, []entity{{ID: "2", Nickname: "a"}}, all, "the column is named without the options of the tag")
+
+	all, err = repo.AllBy(t.Context(), q.Query{}.OrderBy("nick").Ascending())
+	assert.NoError(t, err)
+	assert.Equal(t, []entity{{ID: "2", Nickname: "a"}, {ID: "1", Nickname: "b"}}, all)
+}
@@ -1,10 +1,8 @@
 package arepo
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -14,6 +12,8 @@ var _ Store = (*JSONStore)(nil)
 
 // JSONStore is a naive implementation of a Store.
 // It persists the data as a human-readable JSON file on disc.
+// The file is replaced atomically, so it is not corrupted if the application crashes while writing.
+// For large data sets, use a LogStore, as JSONStore rewrites the whole file on every change.
 // JSONStore is not schema aware and uses the standard go marshalling.
 // CAUTION: Be aware if you change your structs, this can lead to data loss!
 // CAUTION: This is only intended for local development and prototyping.
@@ -40,18 +40,12 @@ func (s *JSONStore) Store(fileName string, data any) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	file, err := os.Create(filepath.Join(s.dir, fileName))
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrStore, err)
-	}
-	defer file.Close()
-
 	b, err := json.MarshalIndent(data, "", "\t")
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrStore, err)
 	}
 
-	_, err = io.Copy(file, bytes.NewReader(b))
+	err = writeFileAtomic(filepath.Join(s.dir, fileName), b)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrStore, err)
 	}
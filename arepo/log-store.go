@@ -0,0 +1,464 @@
+package arepo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+var _ AppendStore = (*LogStore)(nil)
+
+// DefaultCompactAfter is the number of changes a LogStore appends before it compacts them into a snapshot.
+const DefaultCompactAfter = 1000
+
+// LogStore is a Store that persists the data in a snapshot and an append-only log of changes.
+// A MemoryRepository only appends the entities it changed, so writes stay cheap for large data sets.
+// After a number of changes, the log is compacted into a new snapshot.
+//
+// For a fileName, the snapshot is written to fileName and the log to fileName.log.
+// Both are human-readable JSON. The snapshot is replaced atomically and every change is synced to disc,
+// so the data survives a crash or restart of the application.
+// A change that was only partially written when the application crashed is discarded on Load.
+//
+// LogStore is not schema aware and uses the standard go marshalling.
+// CAUTION: Be aware if you change your structs, this can lead to data loss!
+// CAUTION: This is only intended for local development and prototyping.
+type LogStore struct {
+	dir          string
+	compactAfter int
+
+	// logs holds the state of each log file, once it is known to the LogStore.
+	logs map[string]*logState
+	mu   sync.Mutex
+}
+
+// logState is the state of the log of one file name.
+type logState struct {
+	// sequence is the number of the last change, it increases with every change and is never reset.
+	sequence uint64
+	// entries is the number of changes in the log since the last snapshot.
+	entries int
+}
+
+// snapshot is the content of a snapshot file.
+type snapshot struct {
+	// Sequence is the number of the last change contained in Data.
+	// Changes in the log up to this sequence are already part of the snapshot.
+	Sequence uint64                     `json:"sequence"`
+	Data     map[string]json.RawMessage `json:"data"`
+}
+
+// logEntry is a single line in the log file.
+type logEntry struct {
+	Sequence uint64          `json:"sequence"`
+	ID       string          `json:"id"`
+	Entity   json.RawMessage `json:"entity,omitempty"`
+	Deleted  bool            `json:"deleted,omitempty"`
+}
+
+// NewLogStore returns a LogStore that persists into the directory path.
+// It compacts the log after compactAfter changes, use 0 for DefaultCompactAfter.
+func NewLogStore(path string, compactAfter int) *LogStore {
+	err := os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		panic("could not create path: " + path + ": " + err.Error())
+	}
+
+	if compactAfter <= 0 {
+		compactAfter = DefaultCompactAfter
+	}
+
+	return &LogStore{
+		dir:          path,
+		compactAfter: compactAfter,
+		logs:         make(map[string]*logState),
+		mu:           sync.Mutex{},
+	}
+}
+
+// Store writes data as a new snapshot and discards all changes in the log.
+func (s *LogStore) Store(fileName string, data any) error {
+	if data == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.state(fileName)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	snap := snapshot{Sequence: state.sequence, Data: map[string]json.RawMessage{}}
+
+	err = json.Unmarshal(b, &snap.Data)
+	if err != nil {
+		return fmt.Errorf("%w: data has to be a map: %v", ErrStore, err)
+	}
+
+	err = s.writeSnapshot(fileName, state, snap)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	return nil
+}
+
+// Append appends the changes to the log and compacts it, if it has grown too large.
+func (s *LogStore) Append(fileName string, changes ...StoreChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.state(fileName)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	buf := bytes.Buffer{}
+	sequence := state.sequence
+
+	for _, change := range changes {
+		sequence++
+
+		entry, err := newLogEntry(sequence, change)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStore, err)
+		}
+
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStore, err)
+		}
+
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(s.logPath(fileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	state.sequence = sequence
+	state.entries += len(changes)
+
+	if state.entries >= s.compactAfter {
+		if err := s.compact(fileName, state); err != nil {
+			return fmt.Errorf("%w: %v", ErrStore, err)
+		}
+	}
+
+	return nil
+}
+
+// Load reads the snapshot and applies all changes from the log to it.
+func (s *LogStore) Load(fileName string, data any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap, err := s.read(fileName)
+	if err != nil {
+		// Note: exception: expose the underlying error to the API,
+		// so os.ErrNotExist is available to the caller.
+		return fmt.Errorf("%w: %w", ErrLoad, err)
+	}
+
+	b, err := json.Marshal(snap.Data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+
+	err = json.Unmarshal(b, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+
+	return nil
+}
+
+// Compact writes all changes of the log into a new snapshot.
+// This happens automatically, use Compact to do it earlier, e.g. before shutting down.
+func (s *LogStore) Compact(fileName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.state(fileName)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	if err := s.compact(fileName, state); err != nil {
+		return fmt.Errorf("%w: %v", ErrStore, err)
+	}
+
+	return nil
+}
+
+// compact replaces the snapshot by one containing all changes of the log.
+// The caller has to hold the lock.
+func (s *LogStore) compact(fileName string, state *logState) error {
+	snap, err := s.read(fileName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return s.writeSnapshot(fileName, state, snap)
+}
+
+// writeSnapshot replaces the snapshot and removes the log afterwards.
+// If the application crashes in between, the changes in the log are skipped on the next Load,
+// because the sequence of the snapshot already contains them.
+func (s *LogStore) writeSnapshot(fileName string, state *logState, snap snapshot) error {
+	b, err := json.MarshalIndent(snap, "", "\t")
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	err = writeFileAtomic(filepath.Join(s.dir, fileName), b)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(s.logPath(fileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	state.entries = 0
+
+	return nil
+}
+
+// state returns the state of the log of fileName.
+// If the state is not known yet, it is read from disc.
+// The caller has to hold the lock.
+func (s *LogStore) state(fileName string) (*logState, error) {
+	if state, ok := s.logs[fileName]; ok {
+		return state, nil
+	}
+
+	_, err := s.read(fileName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return s.logs[fileName], nil
+}
+
+// read reads the snapshot and applies the log to it, updating the state of the log.
+// The sequence of the returned snapshot is the one of the last change in the log.
+// It returns os.ErrNotExist, if neither the snapshot nor the log exist.
+// The caller has to hold the lock.
+func (s *LogStore) read(fileName string) (snapshot, error) {
+	snap := snapshot{Data: map[string]json.RawMessage{}}
+	snapshotMissing := false
+
+	b, err := os.ReadFile(filepath.Join(s.dir, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		snapshotMissing = true
+	} else if err != nil {
+		return snapshot{}, err //nolint:wrapcheck // wrapped by the caller
+	} else if err := json.Unmarshal(b, &snap); err != nil {
+		return snapshot{}, fmt.Errorf("invalid snapshot: %w", err)
+	}
+
+	if snap.Data == nil {
+		snap.Data = map[string]json.RawMessage{}
+	}
+
+	f, err := os.OpenFile(s.logPath(fileName), os.O_RDWR, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		s.setState(fileName, snap.Sequence, 0)
+
+		if snapshotMissing {
+			return snap, os.ErrNotExist
+		}
+
+		return snap, nil
+	}
+
+	if err != nil {
+		return snapshot{}, err //nolint:wrapcheck // wrapped by the caller
+	}
+	defer f.Close()
+
+	entries, err := replay(f, &snap)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	s.setState(fileName, snap.Sequence, entries)
+
+	return snap, nil
+}
+
+// replay applies all changes from the log that are newer than the snapshot
+// and returns the number of changes in the log.
+// A last line that was only partially written is removed from the log.
+func replay(f *os.File, snap *snapshot) (int, error) {
+	reader := bufio.NewReader(f)
+	entries := 0
+	valid := int64(0)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				// the application crashed while appending, the change was never confirmed
+				if err := f.Truncate(valid); err != nil {
+					return 0, err //nolint:wrapcheck // wrapped by the caller
+				}
+			}
+
+			return entries, nil
+		}
+
+		if err != nil {
+			return 0, err //nolint:wrapcheck // wrapped by the caller
+		}
+
+		var entry logEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return 0, fmt.Errorf("invalid log entry after byte %d: %w", valid, err)
+		}
+
+		valid += int64(len(line))
+		entries++
+
+		if entry.Sequence <= snap.Sequence {
+			continue
+		}
+
+		snap.Sequence = entry.Sequence
+
+		if entry.Deleted {
+			delete(snap.Data, entry.ID)
+		} else {
+			snap.Data[entry.ID] = entry.Entity
+		}
+	}
+}
+
+// setState updates the state of the log in place, so callers holding the state see the change.
+func (s *LogStore) setState(fileName string, sequence uint64, entries int) {
+	state, ok := s.logs[fileName]
+	if !ok {
+		state = &logState{}
+		s.logs[fileName] = state
+	}
+
+	state.sequence = sequence
+	state.entries = entries
+}
+
+func (s *LogStore) logPath(fileName string) string {
+	return filepath.Join(s.dir, fileName+".log")
+}
+
+func newLogEntry(sequence uint64, change StoreChange) (logEntry, error) {
+	id, err := mapKey(change.ID)
+	if err != nil {
+		return logEntry{}, err
+	}
+
+	entry := logEntry{Sequence: sequence, ID: id, Deleted: change.Deleted}
+
+	if !change.Deleted {
+		entry.Entity, err = json.Marshal(change.Entity)
+		if err != nil {
+			return logEntry{}, err //nolint:wrapcheck // wrapped by the caller
+		}
+	}
+
+	return entry, nil
+}
+
+// mapKey returns the key the encoding/json package uses for id as the key of a map,
+// so changes in the log match the keys of the snapshot.
+func mapKey(id any) (string, error) {
+	v := reflect.ValueOf(id)
+
+	switch v.Kind() { //nolint:exhaustive // only kinds valid for map keys in json
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+
+	if m, ok := id.(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	return "", fmt.Errorf("unsupported id type: %T", id) //nolint:err113 // wrapped by the caller
+}
+
+// writeFileAtomic replaces the file name with data, so that it either contains the old or the new data,
+// even if the application crashes while writing.
+func writeFileAtomic(name string, data []byte) error {
+	dir := filepath.Dir(name)
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	err = os.Rename(f.Name(), name)
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	// persist the rename itself, not supported on all platforms, so it is best effort
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+
+	return nil
+}
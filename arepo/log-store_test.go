@@ -0,0 +1,176 @@
+//go:build integration
+
+package arepo_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo"
+	"github.com/go-arrower/arrower/arepo/testdata"
+)
+
+func TestLogStore(t *testing.T) {
+	t.Parallel()
+
+	t.Run("load from empty folder", func(t *testing.T) {
+		t.Parallel()
+
+		store := arepo.NewLogStore(t.TempDir(), 0)
+
+		err := store.Load("Entity.json", &map[testdata.EntityID]testdata.Entity{})
+		assert.ErrorIs(t, err, arepo.ErrLoad)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("append changes", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		repo := arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		e0, e1 := testdata.RandomEntity(), testdata.RandomEntity()
+
+		err := repo.AddAll(t.Context(), []testdata.Entity{e0, e1})
+		assert.NoError(t, err)
+
+		e1.Name = "updated"
+		err = repo.Update(t.Context(), e1)
+		assert.NoError(t, err)
+
+		err = repo.Delete(t.Context(), e0)
+		assert.NoError(t, err)
+
+		assert.FileExists(t, path.Join(dir, "Entity.json.log"))
+		assert.NoFileExists(t, path.Join(dir, "Entity.json"), "no snapshot before compaction")
+
+		// restart with a new store, as if the application restarted
+		repo = arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		all, err := repo.All(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, []testdata.Entity{e1}, all)
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		store := arepo.NewLogStore(dir, 3)
+		repo := arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(store))
+
+		for range 4 {
+			err := repo.Create(t.Context(), testdata.RandomEntity())
+			assert.NoError(t, err)
+		}
+
+		assert.FileExists(t, path.Join(dir, "Entity.json"))
+
+		err := store.Compact("Entity.json")
+		assert.NoError(t, err)
+		assert.NoFileExists(t, path.Join(dir, "Entity.json.log"))
+
+		repo = arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(arepo.NewLogStore(dir, 3)))
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+	})
+
+	t.Run("store replaces all data", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		repo := arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		err := repo.Create(t.Context(), testdata.RandomEntity())
+		assert.NoError(t, err)
+
+		err = repo.DeleteAll(t.Context())
+		assert.NoError(t, err)
+
+		repo = arepo.NewMemoryRepository[testdata.Entity, testdata.EntityID](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("log older than the snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		store := arepo.NewLogStore(dir, 0)
+		e := testdata.RandomEntity()
+
+		err := store.Append("Entity.json", arepo.StoreChange{ID: e.ID, Entity: e})
+		assert.NoError(t, err)
+
+		log, err := os.ReadFile(path.Join(dir, "Entity.json.log"))
+		assert.NoError(t, err)
+
+		err = store.Store("Entity.json", map[testdata.EntityID]testdata.Entity{})
+		assert.NoError(t, err)
+
+		// simulate a crash after the snapshot was written, but before the log was removed
+		err = os.WriteFile(path.Join(dir, "Entity.json.log"), log, 0o600)
+		assert.NoError(t, err)
+
+		data := map[testdata.EntityID]testdata.Entity{}
+		err = arepo.NewLogStore(dir, 0).Load("Entity.json", &data)
+		assert.NoError(t, err)
+		assert.Empty(t, data)
+	})
+
+	t.Run("partially written change", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		store := arepo.NewLogStore(dir, 0)
+		e0, e1 := testdata.RandomEntity(), testdata.RandomEntity()
+
+		err := store.Append("Entity.json", arepo.StoreChange{ID: e0.ID, Entity: e0})
+		assert.NoError(t, err)
+
+		// simulate a crash while appending
+		f, err := os.OpenFile(path.Join(dir, "Entity.json.log"), os.O_APPEND|os.O_WRONLY, 0o600)
+		assert.NoError(t, err)
+		_, err = f.WriteString(`{"sequence":2,"id":"`)
+		assert.NoError(t, err)
+		f.Close()
+
+		store = arepo.NewLogStore(dir, 0)
+		data := map[testdata.EntityID]testdata.Entity{}
+
+		err = store.Load("Entity.json", &data)
+		assert.NoError(t, err)
+		assert.Equal(t, map[testdata.EntityID]testdata.Entity{e0.ID: e0}, data)
+
+		err = store.Append("Entity.json", arepo.StoreChange{ID: e1.ID, Entity: e1})
+		assert.NoError(t, err)
+
+		data = map[testdata.EntityID]testdata.Entity{}
+		err = arepo.NewLogStore(dir, 0).Load("Entity.json", &data)
+		assert.NoError(t, err)
+		assert.Len(t, data, 2)
+	})
+
+	t.Run("integer ids", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		repo := arepo.NewMemoryRepository[testdata.EntityWithIntPK, testdata.EntityIDInt](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		err := repo.Create(t.Context(), testdata.EntityWithIntPK{ID: 1})
+		assert.NoError(t, err)
+
+		repo = arepo.NewMemoryRepository[testdata.EntityWithIntPK, testdata.EntityIDInt](arepo.WithStore(arepo.NewLogStore(dir, 0)))
+
+		exists, err := repo.ExistByID(t.Context(), 1)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+}
@@ -3,6 +3,7 @@ package arepo
 import (
 	"context"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand/v2"
@@ -10,7 +11,6 @@ import (
 	"slices"
 	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -45,6 +45,29 @@ var (
 //	}
 //
 // In the example above User struct is mapped to the following columns: "user_id", "first_name", "email".
+//
+// Nested structs are flattened into columns prefixed with the field name, e.g. "address.street".
+// Slices of primitive types are stored as Postgres arrays.
+// Fields that can not be flattened, like maps or value objects, are stored as JSONB,
+// if their `db` tag has the json option. The tag has to name the column:
+//
+//	type User struct {
+//		ID      string
+//		Tags    []string                           // text[]
+//		Profile map[string]string `db:"profile,json"` // jsonb
+//		Emails  []Email           `db:"user_email,children"`
+//	}
+//
+// Slices of structs with the children option are one-to-many relations stored in their own table.
+// The table name defaults to the lower case type name, e.g. "email", and can be set in the tag.
+// The child table requires a column referencing the parent, named after the parent table without schema,
+// e.g. "user_id" for the tables "user" and "auth.user".
+// Children are loaded eagerly with the entity and replaced on each write, their order is not guaranteed.
+//
+// If using the WithHistory option, all changes are written into the table arrower.history,
+// that is created by the default arrower migrations.
+//
+// If using the WithReplicas option, reads are routed to the replicas, see postgres.ReadPool.
 func NewPostgresRepository[E any, ID id](pgx *pgxpool.Pool, opts ...Option) (*PostgresRepository[E, ID], error) {
 	repo := &PostgresRepository[E, ID]{
 		PGx:         pgx,
@@ -67,24 +90,78 @@ func NewPostgresRepository[E any, ID id](pgx *pgxpool.Pool, opts ...Option) (*Po
 		return nil, fmt.Errorf("%w: %s: entity does not have the ID field with name: %v", errRepositoryInvalid, name, repo.IDFieldName) //nolint:lll
 	}
 
+	if err := checkDBTags(reflect.TypeOf(*new(E))); err != nil {
+		name := reflect.TypeOf(*new(E)).Name()
+		return nil, fmt.Errorf("%w: %s: %w", errRepositoryInvalid, name, err)
+	}
+
+	children, err := childTables(reflect.TypeOf(*new(E)))
+	if err != nil {
+		name := reflect.TypeOf(*new(E)).Name()
+		return nil, fmt.Errorf("%w: %s: %w", errRepositoryInvalid, name, err)
+	}
+
+	repo.children = children
+
 	return repo, nil
 }
 
+// WithReplicas routes all reads of the PostgresRepository to one of the read replicas,
+// e.g. postgres.Handler.Replicas. Writes, NextID, and everything inside a transaction
+// use the primary, also reads in a context created by postgres.WithPrimary.
+func WithReplicas(replicas ...*pgxpool.Pool) Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(interface {
+			setReplicas(replicas []*pgxpool.Pool)
+		}); ok {
+			repo.setReplicas(replicas)
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithReplicas is not supported by this repository", errInvalidOption)
+	}
+}
+
 // PostgresRepository implements Repository in a generic way. Use it to speed up your development.
 //
 // The repository exposes fields required to extend the repository with custom methods.
 type PostgresRepository[E any, ID id] struct {
 	PGx *pgxpool.Pool
+	// Replicas are the read replicas used by Reader, if the repository is created WithReplicas.
+	Replicas []*pgxpool.Pool
 
 	IDFieldName string
 	Table       string
 	Columns     []string
+	// HistoryTable is the table changes are recorded in, if the repository is created WithHistory.
+	HistoryTable string
+
+	children     []childTable
+	searchFields map[string]SearchWeight
+	timestamps
+}
+
+const defaultHistoryTable = "arrower.history"
+
+func (repo *PostgresRepository[E, ID]) enableHistory() {
+	repo.HistoryTable = defaultHistoryTable
+}
+
+func (repo *PostgresRepository[E, ID]) setReplicas(replicas []*pgxpool.Pool) {
+	repo.Replicas = replicas
+}
+
+func (repo *PostgresRepository[E, ID]) setSearchFields(fields map[string]SearchWeight) {
+	repo.searchFields = fields
 }
 
-func (repo *PostgresRepository[E, ID]) TxOrConn(ctx context.Context) interface {
+// dbConn is the connection returned by TxOrConn.
+type dbConn = interface {
 	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
-} {
+}
+
+func (repo *PostgresRepository[E, ID]) TxOrConn(ctx context.Context) dbConn {
 	tx, ok := ctx.Value(postgres.CtxTX).(pgx.Tx)
 	if ok {
 		return tx
@@ -93,6 +170,17 @@ func (repo *PostgresRepository[E, ID]) TxOrConn(ctx context.Context) interface {
 	return repo.PGx
 }
 
+// Reader returns the connection for read only queries:
+// the transaction in ctx, the primary, or one of the Replicas, see postgres.ReadPool.
+func (repo *PostgresRepository[E, ID]) Reader(ctx context.Context) dbConn {
+	tx, ok := ctx.Value(postgres.CtxTX).(pgx.Tx)
+	if ok {
+		return tx
+	}
+
+	return postgres.ReadPool(ctx, repo.PGx, repo.Replicas)
+}
+
 func (repo *PostgresRepository[E, ID]) Tx(ctx context.Context) pgx.Tx {
 	tx, ok := ctx.Value(postgres.CtxTX).(pgx.Tx)
 	if ok {
@@ -143,7 +231,7 @@ func (repo *PostgresRepository[E, ID]) NextID(ctx context.Context) (ID, error) {
 			"SELECT nextval(pg_get_serial_sequence('"+repo.Table+"', '"+strings.ToLower(repo.IDFieldName)+"'))",
 		)
 		if err != nil {
-			return id, fmt.Errorf("%w: could not get from sequence: %v", errIDGenerationFailed, err)
+			return id, fmt.Errorf("%w: could not get from sequence: %w", errIDGenerationFailed, err)
 		}
 
 		reflect.ValueOf(&id).Elem().SetInt(serial)
@@ -154,7 +242,7 @@ func (repo *PostgresRepository[E, ID]) NextID(ctx context.Context) (ID, error) {
 			"SELECT nextval(pg_get_serial_sequence('"+repo.Table+"', '"+strings.ToLower(repo.IDFieldName)+"'))",
 		)
 		if err != nil {
-			return id, fmt.Errorf("%w: could not get from sequence: %v", errIDGenerationFailed, err)
+			return id, fmt.Errorf("%w: could not get from sequence: %w", errIDGenerationFailed, err)
 		}
 
 		reflect.ValueOf(&id).Elem().SetInt(serial)
@@ -172,20 +260,28 @@ func (repo *PostgresRepository[E, ID]) Create(ctx context.Context, entity E) err
 		return fmt.Errorf("%w: %w", errCreateFailed, err)
 	}
 
+	entity = stamp(ctx, &repo.timestamps, repo.clock(), entity, true)
+
 	sql, args, err := psql.Insert(repo.Table).Columns(repo.Columns...).Values(columnValues(entity)...).ToSql()
 	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errCreateFailed, err)
+		return fmt.Errorf("%w: could not build query: %w", errCreateFailed, err)
 	}
 
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
-	if err != nil && strings.Contains(err.Error(), "SQLSTATE 23505") {
-		return ErrAlreadyExists
-	}
-	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return fmt.Errorf("%w: could not insert entity with id %v: %v", errCreateFailed, id, err)
-	}
+	return repo.track(ctx, errCreateFailed, func(conn dbConn) ([]Change[E, ID], error) {
+		_, err := conn.Exec(ctx, sql, args...)
+		if isUniqueViolation(err) {
+			return nil, ErrAlreadyExists
+		}
+		if err != nil { //nolint:wsl_v5 // error handling belongs together
+			return nil, fmt.Errorf("%w: could not insert entity with id %v: %w", errCreateFailed, id, err)
+		}
 
-	return nil
+		if err := repo.saveChildren(ctx, conn, entity); err != nil {
+			return nil, fmt.Errorf("%w: %w", errCreateFailed, err)
+		}
+
+		return repo.changes(nil, entity), nil
+	})
 }
 
 func (repo *PostgresRepository[E, ID]) Read(ctx context.Context, id ID) (E, error) {
@@ -198,27 +294,33 @@ func (repo *PostgresRepository[E, ID]) Update(ctx context.Context, entity E) err
 		return fmt.Errorf("%w: %w", errUpdateFailed, err)
 	}
 
-	query := psql.Update(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id})
-
-	vals := columnValues(entity)
-	for i, name := range repo.Columns {
-		query = query.Set(name, vals[i])
-	}
+	entity = stamp(ctx, &repo.timestamps, repo.clock(), entity, false)
 
-	sql, args, err := query.ToSql()
+	sql, args, err := repo.updateSQL(id, entity)
 	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errUpdateFailed, err)
+		return fmt.Errorf("%w: could not build query: %w", errUpdateFailed, err)
 	}
 
-	res, err := repo.TxOrConn(ctx).Exec(ctx, sql, args...)
-	if err == nil && res.RowsAffected() == 0 {
-		return fmt.Errorf("entity %w", ErrNotFound)
-	}
-	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return fmt.Errorf("%w: could not update entity with id: %v: %v", errUpdateFailed, id, err)
-	}
+	return repo.track(ctx, errUpdateFailed, func(conn dbConn) ([]Change[E, ID], error) {
+		oldEntities, err := repo.lockForHistory(ctx, conn, []ID{id})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errUpdateFailed, err)
+		}
 
-	return nil
+		res, err := conn.Exec(ctx, sql, args...)
+		if err == nil && res.RowsAffected() == 0 {
+			return nil, fmt.Errorf("entity %w", ErrNotFound)
+		}
+		if err != nil { //nolint:wsl_v5 // error handling belongs together
+			return nil, fmt.Errorf("%w: could not update entity with id: %v: %w", errUpdateFailed, id, err)
+		}
+
+		if err := repo.saveChildren(ctx, conn, entity); err != nil {
+			return nil, fmt.Errorf("%w: %w", errUpdateFailed, err)
+		}
+
+		return repo.changes(oldEntities, entity), nil
+	})
 }
 
 func (repo *PostgresRepository[E, ID]) Delete(ctx context.Context, entity E) error {
@@ -227,17 +329,7 @@ func (repo *PostgresRepository[E, ID]) Delete(ctx context.Context, entity E) err
 		return nil //nolint:nilerr // entity without ID does not exist in the repo; meaning it is as if it is deleted.
 	}
 
-	sql, args, err := psql.Delete(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id}).ToSql()
-	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errDeleteFailed, err)
-	}
-
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
-	if err != nil {
-		return fmt.Errorf("%w: could not delete entity with id: %v: %v", errDeleteFailed, id, err)
-	}
-
-	return nil
+	return repo.deleteWhere(ctx, squirrel.Eq{repo.IDFieldName: id})
 }
 
 func (repo *PostgresRepository[E, ID]) All(ctx context.Context) ([]E, error) {
@@ -249,17 +341,22 @@ func (repo *PostgresRepository[E, ID]) All(ctx context.Context) ([]E, error) {
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return []E{}, fmt.Errorf("%w: could not build query: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
+	conn := repo.Reader(ctx) // the same for the children, in case of replicas
 	entities := []E{}
 
-	err = pgxscan.Select(ctx, repo.PGx, &entities, sql, args...)
+	err = pgxscan.Select(ctx, conn, &entities, sql, args...)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return []E{}, fmt.Errorf("entities %w: %v", ErrNotFound, err)
+		return []E{}, fmt.Errorf("entities %w: %w", ErrNotFound, err)
 	}
 	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return []E{}, fmt.Errorf("%w: could not scan entities: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not scan entities: %w", errFindFailed, err)
+	}
+
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return []E{}, fmt.Errorf("%w: %w", errFindFailed, err)
 	}
 
 	return entities, nil
@@ -272,82 +369,110 @@ func (repo *PostgresRepository[E, ID]) AllByIDs(ctx context.Context, ids []ID) (
 
 	sql, args, err := psql.Select(repo.Columns...).From(repo.Table).Where(squirrel.Eq{repo.IDFieldName: ids}).ToSql()
 	if err != nil {
-		return []E{}, fmt.Errorf("%w: could not build query: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
+	conn := repo.Reader(ctx) // the same for the children, in case of replicas
 	entities := []E{}
 
-	err = pgxscan.Select(ctx, repo.TxOrConn(ctx), &entities, sql, args...)
+	err = pgxscan.Select(ctx, conn, &entities, sql, args...)
 	if err != nil {
-		return []E{}, fmt.Errorf("%w: could not scan entities: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not scan entities: %w", errFindFailed, err)
 	}
 
 	if len(entities) != len(ids) {
 		return []E{}, fmt.Errorf("some ids: %w", ErrNotFound)
 	}
 
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return []E{}, fmt.Errorf("%w: %w", errFindFailed, err)
+	}
+
 	return entities, nil
 }
 
 func (repo *PostgresRepository[E, ID]) AllBy(ctx context.Context, query q.Query) ([]E, error) {
 	sql, args, err := repo.buildFilteredSQL(query)
 	if err != nil {
-		return []E{}, fmt.Errorf("%w: could not build query: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
+	conn := repo.Reader(ctx) // the same for the children, in case of replicas
 	entities := []E{}
 
-	err = pgxscan.Select(ctx, repo.TxOrConn(ctx), &entities, sql, args...)
+	err = pgxscan.Select(ctx, conn, &entities, sql, args...)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return []E{}, fmt.Errorf("entities %w: %v", ErrNotFound, err)
+		return []E{}, fmt.Errorf("entities %w: %w", ErrNotFound, err)
 	}
 	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return []E{}, fmt.Errorf("%w: could not scan entities: %v", errFindFailed, err)
+		return []E{}, fmt.Errorf("%w: could not scan entities: %w", errFindFailed, err)
+	}
+
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return []E{}, fmt.Errorf("%w: %w", errFindFailed, err)
 	}
 
 	return entities, nil
 }
 
+// Search returns all entities containing text in one of the fields, the best match first.
+// Each word of text has to match the beginning of a word in the fields.
+// Without fields, the fields set by WithSearchFields or all text columns are searched.
+func (repo *PostgresRepository[E, ID]) Search(ctx context.Context, text string, fields ...string) ([]E, error) {
+	return repo.AllBy(ctx, q.Search(text, fields...))
+}
+
 func (repo *PostgresRepository[E, ID]) FindBy(ctx context.Context, query q.Query) (E, error) {
 	sql, args, err := repo.buildFilteredSQL(query)
 	if err != nil {
-		return *new(E), fmt.Errorf("%w: could not build query: %v", errFindFailed, err)
+		return *new(E), fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
+	conn := repo.Reader(ctx) // the same for the children, in case of replicas
 	entities := []E{}
 
-	err = pgxscan.Select(ctx, repo.TxOrConn(ctx), &entities, sql, args...)
+	err = pgxscan.Select(ctx, conn, &entities, sql, args...)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return *new(E), fmt.Errorf("entities %w: %v", ErrNotFound, err)
+		return *new(E), fmt.Errorf("entities %w: %w", ErrNotFound, err)
 	}
 	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return *new(E), fmt.Errorf("%w: could not scan entities: %v", errFindFailed, err)
+		return *new(E), fmt.Errorf("%w: could not scan entities: %w", errFindFailed, err)
 	}
 
 	if len(entities) != 1 {
 		return *new(E), fmt.Errorf("%w: FindBy only returns one entity, but filter found: %d", ErrNotFound, len(entities))
 	}
 
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return *new(E), fmt.Errorf("%w: %w", errFindFailed, err)
+	}
+
 	return entities[0], nil
 }
 
 func (repo *PostgresRepository[E, ID]) FindByID(ctx context.Context, id ID) (E, error) {
 	sql, args, err := psql.Select(repo.Columns...).From(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id}).ToSql()
 	if err != nil {
-		return *new(E), fmt.Errorf("%w: could not build query: %v", errFindFailed, err)
+		return *new(E), fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
+	conn := repo.Reader(ctx) // the same for the children, in case of replicas
 	entity := new(E)
 
-	err = pgxscan.Get(ctx, repo.TxOrConn(ctx), entity, sql, args...)
+	err = pgxscan.Get(ctx, conn, entity, sql, args...)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return *new(E), fmt.Errorf("entity %w: %v", ErrNotFound, err)
+		return *new(E), fmt.Errorf("entity %w: %w", ErrNotFound, err)
 	}
 	if err != nil { //nolint:wsl_v5 // error handling belongs together
-		return *new(E), fmt.Errorf("%w: could not scan entity: %v", errFindFailed, err)
+		return *new(E), fmt.Errorf("%w: could not scan entity: %w", errFindFailed, err)
+	}
+
+	entities := []E{*entity}
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return *new(E), fmt.Errorf("%w: %w", errFindFailed, err)
 	}
 
-	return *entity, nil
+	return entities[0], nil
 }
 
 func (repo *PostgresRepository[E, ID]) Exist(ctx context.Context, id ID) (bool, error) {
@@ -356,63 +481,82 @@ func (repo *PostgresRepository[E, ID]) Exist(ctx context.Context, id ID) (bool,
 		From(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id}).
 		Suffix(")").ToSql()
 	if err != nil {
-		return false, fmt.Errorf("%w: could not build query: %v", errExistsFailed, err)
+		return false, fmt.Errorf("%w: could not build query: %w", errExistsFailed, err)
 	}
 
 	var exists bool
 
-	err = pgxscan.Get(ctx, repo.TxOrConn(ctx), &exists, sql, args...)
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &exists, sql, args...)
 	if err != nil {
-		return false, fmt.Errorf("%w: could not scan result: %v", errExistsFailed, err)
+		return false, fmt.Errorf("%w: could not scan result: %w", errExistsFailed, err)
 	}
 
 	return exists, nil
 }
 
-func (repo *PostgresRepository[E, ID]) ExistByID(ctx context.Context, id ID) (bool, error) {
-	return repo.Exist(ctx, id)
-}
+func (repo *PostgresRepository[E, ID]) ExistBy(ctx context.Context, query q.Query) (bool, error) {
+	sql, args, err := repo.buildAggregateSQL(query, "1")
+	if err != nil {
+		return false, fmt.Errorf("%w: could not build query: %w", errExistsFailed, err)
+	}
 
-func (repo *PostgresRepository[E, ID]) ExistByIDs(ctx context.Context, ids []ID) (bool, error) {
-	return repo.ExistAll(ctx, ids)
-}
+	var exists bool
+
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &exists, "SELECT EXISTS ("+sql+")", args...)
+	if err != nil {
+		return false, fmt.Errorf("%w: could not scan result: %w", errExistsFailed, err)
+	}
 
-func (repo *PostgresRepository[E, ID]) ExistAll(_ context.Context, _ []ID) (bool, error) {
-	panic("implement me")
+	return exists, nil
 }
 
-func (repo *PostgresRepository[E, ID]) Contains(ctx context.Context, id ID) (bool, error) {
-	return repo.ExistByID(ctx, id)
+func (repo *PostgresRepository[E, ID]) ExistByID(ctx context.Context, id ID) (bool, error) {
+	return repo.Exist(ctx, id)
 }
 
-func (repo *PostgresRepository[E, ID]) ContainsID(ctx context.Context, id ID) (bool, error) {
-	return repo.ContainsIDs(ctx, []ID{id})
+func (repo *PostgresRepository[E, ID]) ExistByIDs(ctx context.Context, ids []ID) (bool, error) {
+	return repo.ExistAll(ctx, ids)
 }
 
-func (repo *PostgresRepository[E, ID]) ContainsIDs(ctx context.Context, ids []ID) (bool, error) {
+// ExistAll returns true, if all entities with the given ids exist.
+// Duplicate ids are only counted once.
+func (repo *PostgresRepository[E, ID]) ExistAll(ctx context.Context, ids []ID) (bool, error) {
 	if len(ids) == 0 {
 		return false, nil
 	}
 
-	sql, args, err := psql.Select("COUNT(" + repo.IDFieldName + ")").
+	unique := make(map[ID]struct{}, len(ids))
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+
+	sql, args, err := psql.Select("COUNT(DISTINCT " + repo.IDFieldName + ")").
 		From(repo.Table).Where(squirrel.Eq{repo.IDFieldName: ids}).
 		ToSql()
 	if err != nil {
-		return false, fmt.Errorf("%w: could not build query: %v", errExistsFailed, err)
+		return false, fmt.Errorf("%w: could not build query: %w", errExistsFailed, err)
 	}
 
 	var count int
 
-	err = pgxscan.Get(ctx, repo.TxOrConn(ctx), &count, sql, args...)
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &count, sql, args...)
 	if err != nil {
-		return false, fmt.Errorf("%w: could not scan result: %v", errExistsFailed, err)
+		return false, fmt.Errorf("%w: could not scan result: %w", errExistsFailed, err)
 	}
 
-	if count == len(ids) {
-		return true, nil
-	}
+	return count == len(unique), nil
+}
+
+func (repo *PostgresRepository[E, ID]) Contains(ctx context.Context, id ID) (bool, error) {
+	return repo.ExistByID(ctx, id)
+}
+
+func (repo *PostgresRepository[E, ID]) ContainsID(ctx context.Context, id ID) (bool, error) {
+	return repo.ContainsIDs(ctx, []ID{id})
+}
 
-	return false, nil
+func (repo *PostgresRepository[E, ID]) ContainsIDs(ctx context.Context, ids []ID) (bool, error) {
+	return repo.ExistAll(ctx, ids)
 }
 
 func (repo *PostgresRepository[E, ID]) ContainsAll(ctx context.Context, ids []ID) (bool, error) {
@@ -424,177 +568,231 @@ func (repo *PostgresRepository[E, ID]) CreateAll(ctx context.Context, entities [
 }
 
 func (repo *PostgresRepository[E, ID]) Save(ctx context.Context, entity E) error {
-	_, err := repo.getID(entity)
+	id, err := repo.getID(entity)
 	if err != nil {
 		return fmt.Errorf("%w: %w", errSaveFailed, err)
 	}
 
-	values := make([]any, len(repo.Columns))
+	entity = stamp(ctx, &repo.timestamps, repo.clock(), entity, true)
 
-	ent := reflect.ValueOf(entity)
-	for i, name := range repo.Columns {
-		values[i] = ent.FieldByName(entitiesFieldNameByColumnName[E](name)).Interface()
+	sql, args, err := repo.upsertSQL(entity)
+	if err != nil {
+		return fmt.Errorf("%w: could not build query: %w", errSaveFailed, err)
 	}
 
-	query := psql.
-		Insert(repo.Table).
-		Columns(repo.Columns...).
-		Values(values...).
-		Suffix("ON CONFLICT (" + repo.IDFieldName + ") DO UPDATE SET")
+	return repo.track(ctx, errSaveFailed, func(conn dbConn) ([]Change[E, ID], error) {
+		oldEntities, err := repo.lockForHistory(ctx, conn, []ID{id})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errSaveFailed, err)
+		}
 
-	for i, name := range repo.Columns {
-		expr := name + "= ?,"
-		if i == len(repo.Columns)-1 {
-			expr = name + "= ?"
+		_, err = conn.Exec(ctx, sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not insert entity: %w", errSaveFailed, err)
 		}
 
-		sql, args, sqErr := squirrel.Expr(expr, ent.FieldByName(entitiesFieldNameByColumnName[E](name)).Interface()).ToSql()
-		if sqErr != nil {
-			return fmt.Errorf("%w: could not build on conflict sub-query: %v", errSaveFailed, sqErr)
+		if err := repo.saveChildren(ctx, conn, entity); err != nil {
+			return nil, fmt.Errorf("%w: %w", errSaveFailed, err)
 		}
 
-		query = query.Suffix(sql, args...)
-	}
+		return repo.changes(oldEntities, entity), nil
+	})
+}
 
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errSaveFailed, err)
+// SaveAll upserts all entities in one batch.
+// Either all entities are saved or none.
+func (repo *PostgresRepository[E, ID]) SaveAll(ctx context.Context, entities []E) error {
+	if len(entities) == 0 {
+		return nil
 	}
 
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
-	if err != nil {
-		return fmt.Errorf("%w: could not insert entity: %v", errSaveFailed, err)
+	batch := &pgx.Batch{}
+	ids := make([]ID, 0, len(entities))
+	entities = slices.Clone(entities)
+	now := repo.clock()
+
+	for i, entity := range entities {
+		id, err := repo.getID(entity)
+		if err != nil {
+			return fmt.Errorf("%w: at least one entity: %w", errSaveFailed, err)
+		}
+
+		entities[i] = stamp(ctx, &repo.timestamps, now, entity, true)
+
+		sql, args, err := repo.upsertSQL(entities[i])
+		if err != nil {
+			return fmt.Errorf("%w: could not build query: %w", errSaveFailed, err)
+		}
+
+		batch.Queue(sql, args...)
+
+		ids = append(ids, id)
 	}
 
-	return nil
-}
+	return repo.inTx(ctx, errSaveFailed, func(tx pgx.Tx) error {
+		oldEntities, err := repo.lockForHistory(ctx, tx, ids)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errSaveFailed, err)
+		}
+
+		err = execBatch(ctx, tx, batch, func(_ pgconn.CommandTag, err error) error {
+			if err != nil {
+				return fmt.Errorf("%w: could not save entity: %w", errSaveFailed, err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := repo.saveChildren(ctx, tx, entities...); err != nil {
+			return fmt.Errorf("%w: %w", errSaveFailed, err)
+		}
 
-func (repo *PostgresRepository[E, ID]) SaveAll(_ context.Context, _ []E) error {
-	panic("implement me")
+		return repo.recordHistory(ctx, tx, repo.changes(oldEntities, entities...))
+	})
 }
 
+// UpdateAll updates all entities in one batch.
+// If at least one entity does not exist, none is updated.
 func (repo *PostgresRepository[E, ID]) UpdateAll(ctx context.Context, entities []E) error {
-	var tx pgx.Tx
+	if len(entities) == 0 {
+		return nil
+	}
 
-	conn := repo.TxOrConn(ctx)
-	if conTx, ok := conn.(pgx.Tx); ok {
-		tx = conTx
-	} else {
-		t, err := repo.PGx.Begin(ctx)
+	batch := &pgx.Batch{}
+	ids := make([]ID, 0, len(entities))
+	entities = slices.Clone(entities)
+	now := repo.clock()
+
+	for i, entity := range entities {
+		id, err := repo.getID(entity)
 		if err != nil {
-			return fmt.Errorf("%w: could not start transaction: %v", errSaveFailed, err)
+			return fmt.Errorf("%w: at least one entity: %w", errUpdateFailed, err)
 		}
 
-		tx = t
-	}
+		entities[i] = stamp(ctx, &repo.timestamps, now, entity, false)
 
-	for _, entity := range entities {
-		err := func() error {
-			id, err := repo.getID(entity)
-			if err != nil {
-				return fmt.Errorf("%w: %w", errUpdateFailed, err)
-			}
+		sql, args, err := repo.updateSQL(id, entities[i])
+		if err != nil {
+			return fmt.Errorf("%w: could not build query: %w", errUpdateFailed, err)
+		}
 
-			query := psql.Update(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id})
+		batch.Queue(sql, args...)
 
-			e := reflect.ValueOf(entity)
-			for _, name := range repo.Columns {
-				query = query.Set(name, e.FieldByName(entitiesFieldNameByColumnName[E](name)).Interface())
-			}
+		ids = append(ids, id)
+	}
+
+	return repo.inTx(ctx, errUpdateFailed, func(tx pgx.Tx) error {
+		oldEntities, err := repo.lockForHistory(ctx, tx, ids)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUpdateFailed, err)
+		}
 
-			sql, args, err := query.ToSql()
+		err = execBatch(ctx, tx, batch, func(tag pgconn.CommandTag, err error) error {
 			if err != nil {
-				return fmt.Errorf("%w: could not build query: %v", errUpdateFailed, err)
+				return fmt.Errorf("%w: could not update entity: %w", errUpdateFailed, err)
 			}
 
-			res, err := tx.Exec(ctx, sql, args...)
-			if err == nil && res.RowsAffected() == 0 {
-				return fmt.Errorf("entity %w", ErrNotFound)
-			}
-			if err != nil { //nolint:wsl_v5 // error handling belongs together
-				return fmt.Errorf("%w: could not update entity with id: %v: %v", errUpdateFailed, id, err)
+			if tag.RowsAffected() == 0 {
+				return fmt.Errorf("%w: at least one entity %w", errUpdateFailed, ErrNotFound)
 			}
 
 			return nil
-		}()
+		})
 		if err != nil {
-			rb := tx.Rollback(ctx)
-			if rb != nil {
-				return fmt.Errorf("%w: %v: could not rollback transaction: %v", errSaveFailed, err, rb)
-			}
-
 			return err
 		}
-	}
 
-	rb := tx.Commit(ctx)
-	if rb != nil {
-		return fmt.Errorf("%w: could not commit transaction: %v", errSaveFailed, rb)
-	}
+		if err := repo.saveChildren(ctx, tx, entities...); err != nil {
+			return fmt.Errorf("%w: %w", errUpdateFailed, err)
+		}
 
-	return nil
+		return repo.recordHistory(ctx, tx, repo.changes(oldEntities, entities...))
+	})
 }
 
 func (repo *PostgresRepository[E, ID]) Add(ctx context.Context, entity E) error {
 	return repo.Create(ctx, entity)
 }
 
+// AddAll inserts all entities in one batch.
+// If at least one entity exists already, none is inserted.
 func (repo *PostgresRepository[E, ID]) AddAll(ctx context.Context, entities []E) error {
-	query := psql.Insert(repo.Table).Columns(repo.Columns...)
+	if len(entities) == 0 {
+		return nil
+	}
 
-	for _, entity := range entities {
-		if reflect.DeepEqual(entity, *new(E)) {
-			return fmt.Errorf("%w", errCreateFailed)
-		}
+	batch := &pgx.Batch{}
+	entities = slices.Clone(entities)
+	now := repo.clock()
 
-		id, err := repo.getID(entity)
-		if err != nil {
-			return fmt.Errorf("%w: could not get id for entity %v: %w", errCreateFailed, entity, err)
+	for i, entity := range entities {
+		if _, err := repo.getID(entity); err != nil {
+			return fmt.Errorf("%w: at least one entity: %w", errCreateFailed, err)
 		}
 
-		exists, err := repo.Exist(ctx, id)
+		entities[i] = stamp(ctx, &repo.timestamps, now, entity, true)
+
+		sql, args, err := psql.Insert(repo.Table).Columns(repo.Columns...).Values(columnValues(entities[i])...).ToSql()
 		if err != nil {
-			return fmt.Errorf("%w", err)
+			return fmt.Errorf("%w: could not build query: %w", errCreateFailed, err)
 		}
 
-		if exists {
-			return fmt.Errorf("at least one %w", ErrAlreadyExists)
-		}
+		batch.Queue(sql, args...)
+	}
+
+	return repo.inTx(ctx, errCreateFailed, func(tx pgx.Tx) error {
+		err := execBatch(ctx, tx, batch, func(_ pgconn.CommandTag, err error) error {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("at least one %w", ErrAlreadyExists)
+			}
+			if err != nil { //nolint:wsl_v5 // error handling belongs together
+				return fmt.Errorf("%w: could not insert entity: %w", errCreateFailed, err)
+			}
 
-		vals := []any{}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 
-		e := reflect.ValueOf(entity)
-		for _, name := range repo.Columns {
-			vals = append(vals, e.FieldByName(entitiesFieldNameByColumnName[E](name)).Interface())
+		if err := repo.saveChildren(ctx, tx, entities...); err != nil {
+			return fmt.Errorf("%w: %w", errCreateFailed, err)
 		}
 
-		query = query.Values(vals...)
-	}
+		return repo.recordHistory(ctx, tx, repo.changes(nil, entities...))
+	})
+}
 
-	sql, args, err := query.ToSql()
+func (repo *PostgresRepository[E, ID]) Count(ctx context.Context) (int, error) {
+	sql, args, err := psql.Select("COUNT(*)").From(repo.Table).ToSql()
 	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errCreateFailed, err)
+		return 0, fmt.Errorf("%w: could not build query: %w", errCountFailed, err)
 	}
 
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
+	var count int
+
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &count, sql, args...)
 	if err != nil {
-		return fmt.Errorf("%w: could not execute query: %v", errCreateFailed, err)
+		return 0, fmt.Errorf("%w: could not scan result: %w", errCountFailed, err)
 	}
 
-	return nil
+	return count, nil
 }
 
-func (repo *PostgresRepository[E, ID]) Count(ctx context.Context) (int, error) {
-	sql, args, err := psql.Select("COUNT(*)").From(repo.Table).ToSql()
+func (repo *PostgresRepository[E, ID]) CountBy(ctx context.Context, query q.Query) (int, error) {
+	sql, args, err := repo.buildAggregateSQL(query, "COUNT(*)")
 	if err != nil {
-		return 0, fmt.Errorf("%w: could not build query: %v", errCountFailed, err)
+		return 0, fmt.Errorf("%w: could not build query: %w", errCountFailed, err)
 	}
 
 	var count int
 
-	err = pgxscan.Get(ctx, repo.TxOrConn(ctx), &count, sql, args...)
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &count, sql, args...)
 	if err != nil {
-		return 0, fmt.Errorf("%w: could not scan result: %v", errCountFailed, err)
+		return 0, fmt.Errorf("%w: could not scan result: %w", errCountFailed, err)
 	}
 
 	return count, nil
@@ -604,244 +802,708 @@ func (repo *PostgresRepository[E, ID]) Length(ctx context.Context) (int, error)
 	return repo.Count(ctx)
 }
 
-func (repo *PostgresRepository[E, ID]) DeleteByID(ctx context.Context, id ID) error {
-	return repo.DeleteByIDs(ctx, []ID{id})
-}
-
-func (repo *PostgresRepository[E, ID]) DeleteByIDs(ctx context.Context, ids []ID) error {
-	sql, args, err := psql.Delete(repo.Table).Where(squirrel.Eq{repo.IDFieldName: ids}).ToSql()
-	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errDeleteFailed, err)
-	}
+func (repo *PostgresRepository[E, ID]) Sum(ctx context.Context, field string, query q.Query) (float64, error) {
+	entityType := reflect.TypeOf(*new(E))
 
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
-	if err != nil {
-		return fmt.Errorf("%w: could not execute query: %v", errDeleteFailed, err)
+	if _, ok := entityType.FieldByName(fieldName(entityType, field)); !ok {
+		return 0, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, field)
 	}
 
-	return nil
-}
+	column := pgFieldName(entityType, field)
 
-func (repo *PostgresRepository[E, ID]) DeleteAll(ctx context.Context) error {
-	sql, args, err := psql.Delete(repo.Table).ToSql()
+	sql, args, err := repo.buildAggregateSQL(query, "COALESCE(SUM("+column+"), 0)::DOUBLE PRECISION")
 	if err != nil {
-		return fmt.Errorf("%w: could not build query: %v", errDeleteFailed, err)
+		return 0, fmt.Errorf("%w: could not build query: %w", errAggregateFailed, err)
 	}
 
-	_, err = repo.TxOrConn(ctx).Exec(ctx, sql, args...)
+	var sum float64
+
+	err = pgxscan.Get(ctx, repo.Reader(ctx), &sum, sql, args...)
 	if err != nil {
-		return fmt.Errorf("%w: could not execute query: %v", errDeleteFailed, err)
+		return 0, fmt.Errorf("%w: could not scan result: %w", errAggregateFailed, err)
 	}
 
-	return nil
+	return sum, nil
 }
 
-func (repo *PostgresRepository[E, ID]) Clear(ctx context.Context) error {
-	return repo.DeleteAll(ctx)
+func (repo *PostgresRepository[E, ID]) Min(ctx context.Context, field string, query q.Query) (any, error) {
+	return repo.aggregate(ctx, "MIN", field, query)
 }
 
-func (repo *PostgresRepository[E, ID]) AllIter(ctx context.Context) Iterator[E, ID] {
-	return PostgresIterator[E, ID]{
-		repo:       repo,
-		ctx:        ctx,
-		tx:         nil,
-		sql:        "SELECT * FROM " + repo.Table,
-		cursorOpen: false,
-	}
+func (repo *PostgresRepository[E, ID]) Max(ctx context.Context, field string, query q.Query) (any, error) {
+	return repo.aggregate(ctx, "MAX", field, query)
 }
 
-//nolint:wrapcheck // caller wraps properly
-func (repo *PostgresRepository[E, ID]) buildFilteredSQL(dataQuery q.Query) (string, []any, error) {
-	query := psql.Select(repo.Columns...).From(repo.Table)
+// aggregate applies the SQL aggregate function fn on field
+// and returns the result as the same type as the entity's field.
+func (repo *PostgresRepository[E, ID]) aggregate(ctx context.Context, fn string, field string, query q.Query) (any, error) {
+	entityType := reflect.TypeOf(*new(E))
 
-	conditions := dataQuery.Conditions.Conditions
-	if len(conditions) == 0 {
-		return query.ToSql()
+	structField, ok := entityType.FieldByName(fieldName(entityType, field))
+	if !ok {
+		return nil, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, field)
 	}
 
-	for _, condition := range conditions {
-		if condition.Value == nil {
-			return "", nil, errValueNil
-		}
+	column := pgFieldName(entityType, field)
 
-		query = query.Where(squirrel.Eq{
-			pgFieldName(reflect.TypeOf(*new(E)), condition.Field): condition.Value,
-		})
+	sql, args, err := repo.buildAggregateSQL(query, fn+"("+column+")")
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not build query: %w", errAggregateFailed, err)
 	}
 
-	groups := dataQuery.Conditions.Groups
-	for _, g := range groups {
-		inner := squirrel.Or{}
-
-		for _, condition := range g.Conditions {
-			if condition.Value == nil {
-				return "", nil, errValueNil
-			}
+	// scan into a pointer, so NULL can be detected, if no entity matches
+	result := reflect.New(reflect.PointerTo(structField.Type))
 
-			inner = append(inner, squirrel.Eq{
-				pgFieldName(reflect.TypeOf(*new(E)), condition.Field): condition.Value,
-			})
-		}
+	err = pgxscan.Get(ctx, repo.Reader(ctx), result.Interface(), sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not scan result: %w", errAggregateFailed, err)
+	}
 
-		query = query.Where(inner)
+	if result.Elem().IsNil() {
+		return nil, fmt.Errorf("entities %w", ErrNotFound)
 	}
 
-	return query.ToSql()
+	return result.Elem().Elem().Interface(), nil
 }
 
-const batchSize = 1000
+func (repo *PostgresRepository[E, ID]) Project(ctx context.Context, query q.Query, dst any) error {
+	projection, err := projectionType(dst)
+	if err != nil {
+		return err
+	}
 
-type PostgresIterator[E any, ID id] struct {
-	repo *PostgresRepository[E, ID]
-	ctx  context.Context //nolint:containedctx
-	tx   pgx.Tx
+	columns := columnNames(reflect.Zero(projection).Interface())
 
-	sql        string
-	cursorOpen bool
-}
+	sql, args, err := repo.buildFilteredSQL(query, columns...)
+	if err != nil {
+		return fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
+	}
 
-func (i PostgresIterator[E, ID]) Next() func(yield func(e E, err error) bool) {
-	// return i.nextSingleValue()
-	return i.nextBatchValue()
+	err = pgxscan.Select(ctx, repo.Reader(ctx), dst, sql, args...)
+	if err != nil {
+		return fmt.Errorf("%w: could not scan projection: %w", errFindFailed, err)
+	}
+
+	return nil
+}
+
+func (repo *PostgresRepository[E, ID]) DeleteByID(ctx context.Context, id ID) error {
+	return repo.DeleteByIDs(ctx, []ID{id})
+}
+
+func (repo *PostgresRepository[E, ID]) DeleteByIDs(ctx context.Context, ids []ID) error {
+	return repo.deleteWhere(ctx, squirrel.Eq{repo.IDFieldName: ids})
+}
+
+func (repo *PostgresRepository[E, ID]) DeleteBy(ctx context.Context, query q.Query) error {
+	where, err := repo.where(query)
+	if err != nil {
+		return fmt.Errorf("%w: could not build query: %w", errDeleteFailed, err)
+	}
+
+	return repo.deleteWhere(ctx, where)
 }
 
-func (i PostgresIterator[E, ID]) nextBatchValue() func(yield func(e E, err error) bool) {
-	cursorName := "cursor_" + strconv.Itoa(rand.Int()) //nolint:gosec // get a unique name, no crypto required
+func (repo *PostgresRepository[E, ID]) DeleteAll(ctx context.Context) error {
+	return repo.deleteWhere(ctx, nil)
+}
 
-	if !i.cursorOpen {
-		tx := i.repo.Tx(i.ctx)
-		i.tx = tx
+// deleteWhere deletes all entities matching where. If where is nil, all entities are deleted.
+func (repo *PostgresRepository[E, ID]) deleteWhere(ctx context.Context, where squirrel.Sqlizer) error {
+	query := psql.Delete(repo.Table)
+	if where != nil {
+		query = query.Where(where)
+	}
 
-		_, err := i.tx.Exec(i.ctx, "DECLARE "+cursorName+" CURSOR FOR "+i.sql+";")
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("%w: could not build query: %w", errDeleteFailed, err)
+	}
+
+	return repo.track(ctx, errDeleteFailed, func(conn dbConn) ([]Change[E, ID], error) {
+		deleted, err := repo.lockWhere(ctx, conn, where)
 		if err != nil {
-			return func(yield func(e E, err error) bool) {
-				yield(*new(E), fmt.Errorf("%w: iterator could not declare cursor: %v", errFindFailed, err))
-			}
+			return nil, fmt.Errorf("%w: %w", errDeleteFailed, err)
+		}
+
+		if err := repo.deleteChildren(ctx, conn, where); err != nil {
+			return nil, fmt.Errorf("%w: %w", errDeleteFailed, err)
+		}
+
+		_, err = conn.Exec(ctx, sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not execute query: %w", errDeleteFailed, err)
 		}
 
-		i.cursorOpen = true
+		changes := make([]Change[E, ID], len(deleted))
+		for i := range deleted {
+			id, _ := repo.getID(deleted[i])
+			changes[i] = Change[E, ID]{EntityID: id, Operation: OperationDelete, Old: &deleted[i]}
+		}
+
+		return changes, nil
+	})
+}
+
+func (repo *PostgresRepository[E, ID]) Clear(ctx context.Context) error {
+	return repo.DeleteAll(ctx)
+}
+
+func (repo *PostgresRepository[E, ID]) AllIter(ctx context.Context) Iterator[E, ID] {
+	return repo.AllByIter(ctx, q.Query{})
+}
+
+// AllByIter streams all entities matching query via a server-side cursor, so not all entities are loaded at once.
+// If the ctx contains a transaction, the cursor is opened in it, otherwise in a new transaction.
+func (repo *PostgresRepository[E, ID]) AllByIter(ctx context.Context, query q.Query) Iterator[E, ID] {
+	sql, args, err := repo.buildFilteredSQL(query)
+	if err != nil {
+		err = fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
 	}
 
-	// cap 2, so regular and error branches can terminate the go routine without blocking
-	quit := make(chan struct{}, 2) //nolint:mnd
+	return PostgresIterator[E, ID]{
+		repo: repo,
+		ctx:  ctx,
+		sql:  sql,
+		args: args,
+		err:  err,
+	}
+}
 
-	ch := make(chan E, batchSize)
+// buildFilteredSQL selects the given columns of all entities matching dataQuery.
+// If no columns are given, all columns of the entity are selected.
+//
+//nolint:wrapcheck // caller wraps properly
+func (repo *PostgresRepository[E, ID]) buildFilteredSQL(dataQuery q.Query, columns ...string) (string, []any, error) {
+	if len(columns) == 0 {
+		columns = repo.Columns
+	}
 
-	var fetchErr atomic.Value
+	query := psql.Select(columns...).From(repo.Table)
 
-	go func() {
-		entities := make([]E, batchSize)
+	where, err := repo.where(dataQuery)
+	if err != nil {
+		return "", nil, err
+	}
 
-		for {
-			select { // prevent go routine leak
-			case <-quit:
-				close(ch)
-				return
-			default:
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	for _, order := range dataQuery.Orders() {
+		direction := " ASC"
+		if order.Descending {
+			direction = " DESC"
+		}
+
+		switch order.Nulls {
+		case q.NullsFirst:
+			direction += " NULLS FIRST"
+		case q.NullsLast:
+			direction += " NULLS LAST"
+		case q.NullsDefault:
+		}
+
+		query = query.OrderBy(quoteIdent(pgFieldName(reflect.TypeOf(*new(E)), order.Field)) + direction)
+	}
+
+	if vector, tsquery := repo.search(dataQuery); vector != "" {
+		query = query.OrderByClause("ts_rank("+vector+", to_tsquery('simple', ?)) DESC", tsquery)
+	}
+
+	limit, offset := dataQuery.Pagination()
+	if limit > 0 {
+		query = query.Limit(uint64(limit))
+	}
+
+	if offset > 0 {
+		query = query.Offset(uint64(offset))
+	}
+
+	return query.ToSql()
+}
+
+// buildAggregateSQL selects the aggregate over all entities matching dataQuery.
+// Ordering and pagination do not apply to aggregates.
+func (repo *PostgresRepository[E, ID]) buildAggregateSQL(dataQuery q.Query, aggregate string) (string, []any, error) {
+	query := psql.Select(aggregate).From(repo.Table)
+
+	where, err := repo.where(dataQuery)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	return query.ToSql()
+}
+
+// search returns the weighted tsvector of the searched columns and the tsquery of the search of dataQuery.
+// Each word has to match as a prefix of a word in the searched columns.
+// If dataQuery has no search, both are empty.
+func (repo *PostgresRepository[E, ID]) search(dataQuery q.Query) (string, string) {
+	search := dataQuery.TextSearch()
+	if search == nil {
+		return "", ""
+	}
+
+	tokens := searchTokens(search.Text)
+	if len(tokens) == 0 {
+		return "", ""
+	}
+
+	fields := searchFields(search, repo.searchFields)
+	if fields == nil {
+		for _, c := range columnTypes(reflect.TypeFor[E](), "") {
+			if c.pgType == "TEXT" {
+				fields = append(fields, searchField{name: `"` + c.name + `"`, weight: SearchWeightD})
 			}
+		}
+	}
 
-			err := pgxscan.Select(i.ctx, i.tx, &entities, "FETCH FORWARD 1000 FROM "+cursorName+";")
-			if err != nil {
-				fetchErr.Store(err)
-				break
+	for i, t := range tokens {
+		tokens[i] = t + ":*"
+	}
+
+	if len(fields) == 0 {
+		return "''::TSVECTOR", strings.Join(tokens, " & ")
+	}
+
+	vectors := make([]string, len(fields))
+	for i, f := range fields {
+		column := quoteIdent(pgFieldName(reflect.TypeFor[E](), f.name))
+		vectors[i] = "setweight(to_tsvector('simple', COALESCE(" + column + "::TEXT, '')), '" + string(f.weight) + "')"
+	}
+
+	return "(" + strings.Join(vectors, " || ") + ")", strings.Join(tokens, " & ")
+}
+
+// where translates the conditions of dataQuery into a WHERE clause.
+// If dataQuery has no conditions, nil is returned.
+func (repo *PostgresRepository[E, ID]) where(dataQuery q.Query) (squirrel.Sqlizer, error) {
+	where := squirrel.And{}
+
+	for _, condition := range dataQuery.Conditions.Conditions {
+		if condition.Value == nil {
+			return nil, errValueNil
+		}
+
+		where = append(where, squirrel.Eq{
+			pgFieldName(reflect.TypeOf(*new(E)), condition.Field): condition.Value,
+		})
+	}
+
+	for _, g := range dataQuery.Conditions.Groups {
+		inner := squirrel.Or{}
+
+		for _, condition := range g.Conditions {
+			if condition.Value == nil {
+				return nil, errValueNil
 			}
 
-			if len(entities) == 0 {
-				break
+			inner = append(inner, squirrel.Eq{
+				pgFieldName(reflect.TypeOf(*new(E)), condition.Field): condition.Value,
+			})
+		}
+
+		where = append(where, inner)
+	}
+
+	if vector, tsquery := repo.search(dataQuery); vector != "" {
+		where = append(where, squirrel.Expr(vector+" @@ to_tsquery('simple', ?)", tsquery))
+	}
+
+	if len(where) == 0 {
+		return nil, nil //nolint:nilnil // no conditions means no WHERE clause
+	}
+
+	return where, nil
+}
+
+// History returns all changes of the entity with the given id, the oldest change first.
+// It requires the repository to be created WithHistory.
+func (repo *PostgresRepository[E, ID]) History(ctx context.Context, id ID) ([]Change[E, ID], error) {
+	if repo.HistoryTable == "" {
+		return nil, errHistoryDisabled
+	}
+
+	sql, args, err := psql.Select("operation", "old_value", "new_value", "changed_at", "changed_by").
+		From(repo.HistoryTable).
+		Where(squirrel.Eq{"entity": repo.Table, "entity_id": fmt.Sprint(id)}).
+		OrderBy("id ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not build query: %w", errFindFailed, err)
+	}
+
+	rows := []struct {
+		Operation Operation
+		OldValue  []byte
+		NewValue  []byte
+		ChangedAt time.Time
+		ChangedBy string
+	}{}
+
+	err = pgxscan.Select(ctx, repo.Reader(ctx), &rows, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not scan history: %w", errFindFailed, err)
+	}
+
+	changes := make([]Change[E, ID], len(rows))
+
+	for i, row := range rows {
+		changes[i] = Change[E, ID]{
+			EntityID:  id,
+			Operation: row.Operation,
+			ChangedAt: row.ChangedAt,
+			ChangedBy: row.ChangedBy,
+		}
+
+		if row.OldValue != nil {
+			changes[i].Old = new(E)
+			if err := json.Unmarshal(row.OldValue, changes[i].Old); err != nil {
+				return nil, fmt.Errorf("%w: could not unmarshal old value: %w", errFindFailed, err)
 			}
+		}
 
-			for i := range entities {
-				ch <- entities[i]
+		if row.NewValue != nil {
+			changes[i].New = new(E)
+			if err := json.Unmarshal(row.NewValue, changes[i].New); err != nil {
+				return nil, fmt.Errorf("%w: could not unmarshal new value: %w", errFindFailed, err)
 			}
 		}
+	}
 
-		close(ch)
-	}()
+	return changes, nil
+}
 
-	return func(yield func(e E, err error) bool) {
-		defer func() {
-			quit <- struct{}{}
-		}()
+// track runs the write operation fn and records the changes it returns.
+// If the repository is created WithHistory or has children, fn runs inside a transaction,
+// so the changes, their children, and their history are stored atomically.
+func (repo *PostgresRepository[E, ID]) track(
+	ctx context.Context,
+	errFailed error,
+	fn func(conn dbConn) ([]Change[E, ID], error),
+) error {
+	if repo.HistoryTable == "" && len(repo.children) == 0 {
+		_, err := fn(repo.TxOrConn(ctx))
+		return err
+	}
+
+	return repo.inTx(ctx, errFailed, func(tx pgx.Tx) error {
+		changes, err := fn(tx)
+		if err != nil {
+			return err
+		}
 
-		var err error
+		return repo.recordHistory(ctx, tx, changes)
+	})
+}
 
-		for entity := range ch {
-			select {
-			case <-i.ctx.Done():
-				yield(*new(E), i.ctx.Err())
-				return
-			default:
-				if fe := fetchErr.Load(); fe != nil {
-					err = fe.(error) //nolint:forcetypeassert // fetchErr is local and only stores errors
-				}
+// lockForHistory returns the current entities with the given ids and locks them until the transaction ends,
+// so their old value can be recorded. If no history is recorded, nothing is read.
+func (repo *PostgresRepository[E, ID]) lockForHistory(ctx context.Context, conn dbConn, ids []ID) (map[ID]E, error) {
+	entities, err := repo.lockWhere(ctx, conn, squirrel.Eq{repo.IDFieldName: ids})
+	if err != nil {
+		return nil, err
+	}
 
-				if !yield(entity, err) {
-					quit <- struct{}{}
+	oldEntities := make(map[ID]E, len(entities))
+	for _, e := range entities {
+		id, _ := repo.getID(e)
+		oldEntities[id] = e
+	}
 
-					return
-				}
-			}
+	return oldEntities, nil
+}
+
+// lockWhere is like lockForHistory for all entities matching where. If where is nil, all entities are locked.
+func (repo *PostgresRepository[E, ID]) lockWhere(ctx context.Context, conn dbConn, where squirrel.Sqlizer) ([]E, error) {
+	if repo.HistoryTable == "" {
+		return nil, nil
+	}
+
+	query := psql.Select(repo.Columns...).From(repo.Table).Suffix("FOR UPDATE")
+	if where != nil {
+		query = query.Where(where)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("could not build query: %w", err)
+	}
+
+	entities := []E{}
+
+	err = pgxscan.Select(ctx, conn, &entities, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not read old entities: %w", err)
+	}
+
+	if err := repo.loadChildren(ctx, conn, entities); err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// changes returns a Change for each entity: an update, if an old entity with the same id exists,
+// otherwise a create. If no history is recorded, nil is returned.
+func (repo *PostgresRepository[E, ID]) changes(oldEntities map[ID]E, entities ...E) []Change[E, ID] {
+	if repo.HistoryTable == "" {
+		return nil
+	}
+
+	changes := make([]Change[E, ID], len(entities))
+
+	for i := range entities {
+		id, _ := repo.getID(entities[i])
+		changes[i] = Change[E, ID]{EntityID: id, Operation: OperationCreate, New: &entities[i]}
+
+		if old, ok := oldEntities[id]; ok {
+			// the creation columns are not updated in the table, so the new value keeps them as well
+			updated := keepCreated(&repo.timestamps, entities[i], old)
+
+			changes[i].Operation = OperationUpdate
+			changes[i].Old = &old
+			changes[i].New = &updated
 		}
+	}
+
+	return changes
+}
+
+// recordHistory writes the changes into the HistoryTable.
+func (repo *PostgresRepository[E, ID]) recordHistory(ctx context.Context, conn dbConn, changes []Change[E, ID]) error {
+	if repo.HistoryTable == "" || len(changes) == 0 {
+		return nil
+	}
+
+	tx, ok := conn.(pgx.Tx)
+	if !ok {
+		return fmt.Errorf("%w: history has to be recorded in a transaction", ErrStorage)
+	}
+
+	changedAt := repo.clock()
+	changedBy := currentUserID(ctx)
+	rows := make([][]any, len(changes))
 
-		_, err = i.tx.Exec(i.ctx, "CLOSE "+cursorName+";")
+	for i, c := range changes {
+		oldValue, err := marshalNullable(c.Old)
 		if err != nil {
-			yield(*new(E), fmt.Errorf("%w: iterator could close cursor: %v", errFindFailed, err))
+			return fmt.Errorf("%w: could not marshal old value: %w", ErrStorage, err)
 		}
 
-		err = i.tx.Commit(i.ctx)
+		newValue, err := marshalNullable(c.New)
 		if err != nil {
-			yield(*new(E), fmt.Errorf("%w: iterator could close cursor: %v", errFindFailed, err))
+			return fmt.Errorf("%w: could not marshal new value: %w", ErrStorage, err)
 		}
+
+		rows[i] = []any{repo.Table, fmt.Sprint(c.EntityID), string(c.Operation), oldValue, newValue, changedAt, changedBy}
+	}
+
+	_, err := tx.CopyFrom(ctx,
+		pgx.Identifier(strings.Split(repo.HistoryTable, ".")),
+		[]string{"entity", "entity_id", "operation", "old_value", "new_value", "changed_at", "changed_by"},
+		pgx.CopyFromRows(rows),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: could not record history: %w", ErrStorage, err)
+	}
+
+	return nil
+}
+
+// marshalNullable returns the JSON of v or nil, if v is nil, so it is stored as NULL.
+func marshalNullable[E any](v *E) ([]byte, error) {
+	if v == nil {
+		return nil, nil
 	}
+
+	return json.Marshal(v) //nolint:wrapcheck // caller wraps properly
 }
 
-// nextSingleValue returns always one value at a time.
+// upsertSQL builds an INSERT statement for entity,
+// that updates all columns in case an entity with the same id exists already.
 //
-//nolint:unused // keep it around for benchmarking
-func (i PostgresIterator[E, ID]) nextSingleValue() func(yield func(e E, err error) bool) {
-	cursorName := "cursor_" + strconv.Itoa(rand.Int()) //nolint:gosec // get a unique name, no crypto required
+//nolint:wrapcheck // caller wraps properly
+func (repo *PostgresRepository[E, ID]) upsertSQL(entity E) (string, []any, error) {
+	set := []string{}
 
-	if !i.cursorOpen {
-		tx := i.repo.Tx(i.ctx)
-		i.tx = tx
+	for _, name := range repo.Columns {
+		if repo.updatable(name) {
+			set = append(set, name+" = EXCLUDED."+name)
+		}
+	}
 
-		_, err := i.tx.Exec(i.ctx, "DECLARE "+cursorName+" CURSOR FOR "+i.sql+";")
-		if err != nil {
-			return func(yield func(e E, err error) bool) {
-				yield(*new(E), fmt.Errorf("%w: iterator could not declare cursor: %v", errFindFailed, err))
-			}
+	return psql.
+		Insert(repo.Table).
+		Columns(repo.Columns...).
+		Values(columnValues(entity)...).
+		Suffix("ON CONFLICT (" + repo.IDFieldName + ") DO UPDATE SET " + strings.Join(set, ", ")).
+		ToSql()
+}
+
+// updateSQL builds an UPDATE statement for entity.
+//
+//nolint:wrapcheck // caller wraps properly
+func (repo *PostgresRepository[E, ID]) updateSQL(id ID, entity E) (string, []any, error) {
+	query := psql.Update(repo.Table).Where(squirrel.Eq{repo.IDFieldName: id})
+
+	vals := columnValues(entity)
+	for i, name := range repo.Columns {
+		if repo.updatable(name) {
+			query = query.Set(name, vals[i])
 		}
+	}
+
+	return query.ToSql()
+}
+
+// updatable reports if the column is changed on updates.
+// With WithTimestamps, the creation columns keep their value.
+func (repo *PostgresRepository[E, ID]) updatable(column string) bool {
+	return !repo.stampEntities || !slices.Contains(createdColumns[E](), column)
+}
+
+// inTx runs fn in a new transaction, that is committed if fn succeeds and rolled back otherwise.
+// If ctx contains a postgres.CtxTX already, a savepoint inside that transaction is used instead,
+// so a failing fn does not abort the surrounding transaction.
+func (repo *PostgresRepository[E, ID]) inTx(ctx context.Context, errFailed error, fn func(tx pgx.Tx) error) error {
+	var (
+		tx  pgx.Tx
+		err error
+	)
+
+	if ctxTx, ok := ctx.Value(postgres.CtxTX).(pgx.Tx); ok {
+		tx, err = ctxTx.Begin(ctx)
+	} else {
+		tx, err = repo.PGx.Begin(ctx)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: could not start transaction: %w", errFailed, err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		rb := tx.Rollback(ctx)
+		if rb != nil {
+			return fmt.Errorf("%w: could not rollback transaction: %w", err, rb)
+		}
+
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: could not commit transaction: %w", errFailed, err)
+	}
+
+	return nil
+}
+
+// execBatch sends batch in one round trip and calls check for the result of each queued statement.
+// It stops at the first error returned by check.
+func execBatch(
+	ctx context.Context,
+	tx pgx.Tx,
+	batch *pgx.Batch,
+	check func(tag pgconn.CommandTag, err error) error,
+) error {
+	results := tx.SendBatch(ctx, batch)
 
-		i.cursorOpen = true
+	for range batch.Len() {
+		tag, err := results.Exec()
+
+		err = check(tag, err)
+		if err != nil {
+			_ = results.Close()
+			return err
+		}
 	}
 
+	return results.Close() //nolint:wrapcheck // all statement errors are checked above
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+const batchSize = 1000
+
+type PostgresIterator[E any, ID id] struct {
+	repo *PostgresRepository[E, ID]
+	ctx  context.Context //nolint:containedctx
+
+	sql  string
+	args []any
+	err  error
+}
+
+// Next fetches the entities in batches from a cursor.
+// The cursor and its transaction are closed, when the iteration ends, also if the consumer stops early.
+func (i PostgresIterator[E, ID]) Next() func(yield func(e E, err error) bool) {
 	return func(yield func(e E, err error) bool) {
-		var entity E
+		if i.err != nil {
+			yield(*new(E), i.err)
+			return
+		}
 
-		for {
-			select {
-			case <-i.ctx.Done():
-				yield(*new(E), i.ctx.Err())
+		tx, ok := i.ctx.Value(postgres.CtxTX).(pgx.Tx)
+		ownTx := !ok
+
+		if ownTx {
+			var err error
+
+			tx, err = postgres.ReadPool(i.ctx, i.repo.PGx, i.repo.Replicas).Begin(i.ctx)
+			if err != nil {
+				yield(*new(E), fmt.Errorf("%w: iterator could not start transaction: %w", errFindFailed, err))
 				return
-			default:
-				err := pgxscan.Get(i.ctx, i.tx, &entity, "FETCH FORWARD 1 FROM "+cursorName+";")
-				if err != nil {
-					return
-				}
+			}
+		}
 
-				if !yield(entity, err) {
-					_, err = i.tx.Exec(i.ctx, "CLOSE "+cursorName+";")
-					if err != nil {
-						yield(*new(E), fmt.Errorf("%w: iterator could close cursor: %v", errFindFailed, err))
-						return
-					}
+		cursorName := "cursor_" + strconv.Itoa(rand.Int()) //nolint:gosec // get a unique name, no crypto required
 
-					err = i.tx.Commit(i.ctx)
-					if err != nil {
-						yield(*new(E), fmt.Errorf("%w: iterator could close cursor: %v", errFindFailed, err))
-						return
-					}
+		finished := false
 
+		defer func() {
+			err := closeCursor(i.ctx, tx, ownTx, cursorName)
+			if err != nil && finished { // after the consumer stopped, yield must not be called anymore
+				yield(*new(E), err)
+			}
+		}()
+
+		_, err := tx.Exec(i.ctx, "DECLARE "+cursorName+" CURSOR FOR "+i.sql, i.args...)
+		if err != nil {
+			yield(*new(E), fmt.Errorf("%w: iterator could not declare cursor: %w", errFindFailed, err))
+			return
+		}
+
+		for {
+			entities := []E{}
+
+			err := pgxscan.Select(i.ctx, tx, &entities, "FETCH FORWARD "+strconv.Itoa(batchSize)+" FROM "+cursorName)
+			if err == nil {
+				err = i.repo.loadChildren(i.ctx, tx, entities)
+			}
+
+			if err != nil {
+				yield(*new(E), fmt.Errorf("%w: iterator could not fetch: %w", errFindFailed, err))
+				return
+			}
+
+			if len(entities) == 0 {
+				finished = true
+				return
+			}
+
+			for _, e := range entities {
+				if !yield(e, nil) {
 					return
 				}
 			}
@@ -849,6 +1511,38 @@ func (i PostgresIterator[E, ID]) nextSingleValue() func(yield func(e E, err erro
 	}
 }
 
+// closeCursor releases the cursor. If the iterator started the transaction, it is ended as well.
+// It also works if ctx is already cancelled.
+func closeCursor(ctx context.Context, tx pgx.Tx, ownTx bool, cursorName string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	if ownTx {
+		// the iterator only reads, so there is nothing to commit; ending the transaction closes the cursor
+		if err := tx.Rollback(ctx); err != nil {
+			return fmt.Errorf("%w: iterator could not close transaction: %w", errFindFailed, err)
+		}
+
+		return nil
+	}
+
+	if tx.Conn().PgConn().TxStatus() == 'E' { // failed transaction: cursor is gone with the next rollback
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "CLOSE "+cursorName); err != nil && !isInvalidCursor(err) {
+		return fmt.Errorf("%w: iterator could not close cursor: %w", errFindFailed, err)
+	}
+
+	return nil
+}
+
+// isInvalidCursor reports whether the cursor was never declared.
+func isInvalidCursor(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "34000"
+}
+
 func tableName[E any](entity E) string {
 	return strings.ToLower(reflect.TypeOf(entity).Name())
 }
@@ -866,31 +1560,6 @@ func isScalarStruct(ft reflect.Type) bool {
 	return ft == timeType || ft.Implements(valuerType) || reflect.PointerTo(ft).Implements(valuerType)
 }
 
-func entitiesFieldNameByColumnName[E any](name string) string {
-	name = strings.TrimPrefix(name, `"`)
-	name = strings.TrimSuffix(name, `"`)
-
-	t := reflect.TypeOf(*new(E))
-
-	for i := range t.NumField() {
-		field := t.Field(i)
-		dbTag := field.Tag.Get("db")
-
-		if dbTag == name || dbscan.SnakeCaseMapper(field.Name) == name {
-			return field.Name
-		}
-
-		if strings.Contains(name, ".") {
-			embeddedName := strings.Split(name, ".")[0]
-			if dbTag == embeddedName || field.Name == embeddedName {
-				return field.Name
-			}
-		}
-	}
-
-	return ""
-}
-
 func pgFieldName(_ reflect.Type, name string) string {
 	isQuoted := strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`)
 	if isQuoted {
@@ -962,11 +1631,26 @@ func columnsAndValues(v reflect.Value, prefix string) ([]colVal, error) {
 			continue
 		}
 
-		col := f.Tag.Get("db")
+		col, opts := parseDBTag(f)
+		if col == "-" || opts.children {
+			continue
+		}
+
 		if col == "" {
 			col = dbscan.SnakeCaseMapper(f.Name)
 		}
 
+		if opts.json {
+			value, err := jsonValue(fv)
+			if err != nil {
+				return nil, fmt.Errorf("could not marshal field %s: %w", f.Name, err)
+			}
+
+			result = append(result, colVal{name: joinPrefix(prefix, col), value: value})
+
+			continue
+		}
+
 		// Deref pointer field
 		ft := f.Type
 		for ft.Kind() == reflect.Pointer {
@@ -990,7 +1674,7 @@ func columnsAndValues(v reflect.Value, prefix string) ([]colVal, error) {
 
 			if f.Anonymous {
 				// Embedded ohne db-Tag -> kein Prefix
-				if f.Tag.Get("db") == "" {
+				if name, _ := parseDBTag(f); name == "" {
 					newPrefix = prefix
 				} else {
 					// Embedded mit db-Tag -> custom.xxx
@@ -1026,3 +1710,315 @@ func joinPrefix(prefix, col string) string {
 
 	return prefix + "." + col
 }
+
+// tagOptions are the options of the `db` field tag, following the column name.
+type tagOptions struct {
+	// json stores the field as a single JSONB column.
+	json bool
+	// children stores the elements of a slice in their own table, see childTable.
+	children bool
+}
+
+// parseDBTag returns the column name and the options of the `db` tag of the field, e.g. `db:"profile,json"`.
+func parseDBTag(f reflect.StructField) (string, tagOptions) {
+	name, rawOpts, _ := strings.Cut(f.Tag.Get("db"), ",")
+
+	var opts tagOptions
+
+	for opt := range strings.SplitSeq(rawOpts, ",") {
+		switch strings.TrimSpace(opt) {
+		case "json":
+			opts.json = true
+		case "children":
+			opts.children = true
+		}
+	}
+
+	return name, opts
+}
+
+// jsonValue returns the JSON of v, so it can be stored in a JSONB column.
+// Nil values are stored as NULL.
+func jsonValue(v reflect.Value) (any, error) {
+	switch v.Kind() { //nolint:exhaustive // only nil-able kinds are relevant
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err //nolint:wrapcheck // caller wraps properly
+	}
+
+	return string(b), nil
+}
+
+// childTable is a one-to-many relation of an entity, see NewPostgresRepository.
+type childTable struct {
+	// field is the index of the slice field in the entity.
+	field []int
+	elem  reflect.Type
+
+	table string
+	// parentColumn references the id of the entity the child belongs to.
+	parentColumn string
+	columns      []string
+}
+
+// childTables returns all fields of the entity type t with the children option.
+// Their parentColumn is set by childTablesWithParent, as the Table of the repository can be changed.
+func childTables(t reflect.Type) ([]childTable, error) {
+	var children []childTable
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		name, opts := parseDBTag(f)
+		if !opts.children || f.PkgPath != "" {
+			continue
+		}
+
+		if f.Type.Kind() != reflect.Slice || f.Type.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field %s with children option has to be a slice of structs", f.Name)
+		}
+
+		if name == "" {
+			name = strings.ToLower(f.Type.Elem().Name())
+		}
+
+		child := childTable{
+			field: f.Index,
+			elem:  f.Type.Elem(),
+			table: name,
+		}
+
+		cols, _ := columnsAndValues(reflect.New(f.Type.Elem()).Elem(), "")
+		for _, c := range cols {
+			child.columns = append(child.columns, quoteIdent(c.name))
+		}
+
+		children = append(children, child)
+	}
+
+	return children, nil
+}
+
+// childTablesWithParent returns the children referencing the current Table,
+// by a column named after the table without its schema, e.g. "user_id" for "auth.user".
+func (repo *PostgresRepository[E, ID]) childTablesWithParent() []childTable {
+	parentColumn := quoteIdent(unqualified(repo.Table) + "_id")
+
+	children := make([]childTable, len(repo.children))
+
+	for i, child := range repo.children {
+		child.parentColumn = parentColumn
+		child.columns = slices.DeleteFunc(slices.Clone(child.columns), func(column string) bool {
+			return column == parentColumn // the parent id is always set by the repository
+		})
+
+		children[i] = child
+	}
+
+	return children
+}
+
+// unqualified returns the table name without its schema, e.g. "user" for "auth.user".
+func unqualified(table string) string {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[i+1:]
+	}
+
+	return table
+}
+
+// checkDBTags returns an error for a field of the struct type t, that dbscan can not map to its column.
+// dbscan uses the name in the `db` tag as the column, also if it is empty,
+// so a tag with the json option has to name the column, e.g. `db:"profile,json"`.
+func checkDBTags(t reflect.Type) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, opts := parseDBTag(f)
+		if name == "" && opts.json {
+			return fmt.Errorf("field %s with json option requires a column name, e.g. `db:\"%s,json\"`",
+				f.Name, dbscan.SnakeCaseMapper(f.Name))
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer || (opts.children && ft.Kind() == reflect.Slice) {
+			ft = ft.Elem()
+		}
+
+		if name != "-" && !opts.json && ft.Kind() == reflect.Struct && !isScalarStruct(ft) {
+			if err := checkDBTags(ft); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+//nolint:gochecknoglobals // same as pgxscan's default api, so unknown columns like the parent id are ignored.
+var childScanAPI = func() *pgxscan.API {
+	dbscanAPI, err := pgxscan.NewDBScanAPI(dbscan.WithAllowUnknownColumns(true))
+	if err != nil {
+		panic(err)
+	}
+
+	api, err := pgxscan.NewAPI(dbscanAPI)
+	if err != nil {
+		panic(err)
+	}
+
+	return api
+}()
+
+// parentIDColumn is the alias the parent id of a child is selected as.
+const parentIDColumn = "arrower_parent_id"
+
+// loadChildren reads the children of all entities and sets them on the entities.
+func (repo *PostgresRepository[E, ID]) loadChildren(ctx context.Context, conn dbConn, entities []E) error {
+	if len(repo.children) == 0 || len(entities) == 0 {
+		return nil
+	}
+
+	ids := make([]ID, len(entities))
+	byID := make(map[string]int, len(entities))
+
+	for i := range entities {
+		ids[i], _ = repo.getID(entities[i])
+		byID[fmt.Sprint(ids[i])] = i
+	}
+
+	for _, child := range repo.childTablesWithParent() {
+		columns := append([]string{child.parentColumn + "::TEXT AS " + parentIDColumn}, child.columns...)
+
+		sql, args, err := psql.Select(columns...).From(child.table).
+			Where(squirrel.Eq{child.parentColumn: ids}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("could not build query: %w", err)
+		}
+
+		rows, err := conn.Query(ctx, sql, args...)
+		if err != nil {
+			return fmt.Errorf("could not load children from %s: %w", child.table, err)
+		}
+
+		scanner := childScanAPI.NewRowScanner(rows)
+
+		for rows.Next() {
+			values, err := rows.Values()
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("could not scan children from %s: %w", child.table, err)
+			}
+
+			elem := reflect.New(child.elem)
+			if err := scanner.Scan(elem.Interface()); err != nil {
+				rows.Close()
+				return fmt.Errorf("could not scan children from %s: %w", child.table, err)
+			}
+
+			parent := reflect.ValueOf(&entities[byID[fmt.Sprint(values[0])]]).Elem().FieldByIndex(child.field)
+			parent.Set(reflect.Append(parent, elem.Elem()))
+		}
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("could not load children from %s: %w", child.table, err)
+		}
+	}
+
+	return nil
+}
+
+// saveChildren replaces the children of all entities with their current values.
+func (repo *PostgresRepository[E, ID]) saveChildren(ctx context.Context, conn dbConn, entities ...E) error {
+	if len(repo.children) == 0 || len(entities) == 0 {
+		return nil
+	}
+
+	ids := make([]ID, len(entities))
+	for i := range entities {
+		ids[i], _ = repo.getID(entities[i])
+	}
+
+	for _, child := range repo.childTablesWithParent() {
+		sql, args, err := psql.Delete(child.table).Where(squirrel.Eq{child.parentColumn: ids}).ToSql()
+		if err != nil {
+			return fmt.Errorf("could not build query: %w", err)
+		}
+
+		if _, err := conn.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("could not delete children from %s: %w", child.table, err)
+		}
+
+		query := psql.Insert(child.table).Columns(append([]string{child.parentColumn}, child.columns...)...)
+		hasChildren := false
+
+		for i := range entities {
+			elems := reflect.ValueOf(entities[i]).FieldByIndex(child.field)
+
+			for j := range elems.Len() {
+				cols, err := columnsAndValues(elems.Index(j), "")
+				if err != nil {
+					return err
+				}
+
+				values := make([]any, 0, len(cols)+1)
+				values = append(values, ids[i])
+
+				for _, c := range cols {
+					if quoteIdent(c.name) != child.parentColumn {
+						values = append(values, c.value)
+					}
+				}
+
+				query = query.Values(values...)
+				hasChildren = true
+			}
+		}
+
+		if !hasChildren {
+			continue
+		}
+
+		sql, args, err = query.ToSql()
+		if err != nil {
+			return fmt.Errorf("could not build query: %w", err)
+		}
+
+		if _, err := conn.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("could not insert children into %s: %w", child.table, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteChildren deletes the children of all entities matching where. If where is nil, all children are deleted.
+func (repo *PostgresRepository[E, ID]) deleteChildren(ctx context.Context, conn dbConn, where squirrel.Sqlizer) error {
+	for _, child := range repo.childTablesWithParent() {
+		parents := squirrel.Select(repo.IDFieldName).From(repo.Table)
+		if where != nil {
+			parents = parents.Where(where)
+		}
+
+		sql, args, err := psql.Delete(child.table).Where(squirrel.Expr(child.parentColumn+" IN (?)", parents)).ToSql()
+		if err != nil {
+			return fmt.Errorf("could not build query: %w", err)
+		}
+
+		if _, err := conn.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf("could not delete children from %s: %w", child.table, err)
+		}
+	}
+
+	return nil
+}
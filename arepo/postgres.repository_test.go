@@ -3,16 +3,19 @@
 package arepo_test
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
+	pgxv5 "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-arrower/arrower/arepo"
 	"github.com/go-arrower/arrower/arepo/testdata"
+	"github.com/go-arrower/arrower/postgres"
 	"github.com/go-arrower/arrower/secret"
 	"github.com/go-arrower/arrower/tests"
 )
@@ -226,6 +229,128 @@ func TestPostgresRepository_DbTags(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, repo.Columns, 3)
 	})
+
+	t.Run("json, array, and children columns", func(t *testing.T) {
+		t.Parallel()
+
+		type Line struct {
+			Product  string `db:"product"`
+			Quantity int    `db:"quantity"`
+		}
+
+		type Purchase struct {
+			ID      string            `db:"id"`
+			Tags    []string          `db:"tags"`
+			Profile map[string]string `db:"profile,json"`
+			Lines   []Line            `db:"purchase_line,children"`
+		}
+
+		pgx := pgHandler.NewTestDatabase()
+
+		_, err := pgx.Exec(t.Context(), `CREATE TABLE purchase(id TEXT PRIMARY KEY, tags TEXT[], profile JSONB);`)
+		assert.NoError(t, err)
+		_, err = pgx.Exec(t.Context(), `CREATE TABLE purchase_line(purchase_id TEXT REFERENCES purchase(id), product TEXT, quantity INTEGER);`)
+		assert.NoError(t, err)
+
+		repo, err := arepo.NewPostgresRepository[Purchase, string](pgx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`"id"`, `"tags"`, `"profile"`}, repo.Columns)
+
+		purchase := Purchase{
+			ID:      id,
+			Tags:    []string{"a", "b"},
+			Profile: map[string]string{"plan": "silver"},
+			Lines:   []Line{{Product: gofakeit.Name(), Quantity: 1}},
+		}
+
+		err = repo.Create(t.Context(), purchase)
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(t.Context(), id)
+		assert.NoError(t, err)
+		assert.Equal(t, purchase, got)
+
+		purchase.Lines = append(purchase.Lines, Line{Product: gofakeit.Name(), Quantity: 2})
+		err = repo.Save(t.Context(), purchase)
+		assert.NoError(t, err)
+
+		all, err := repo.All(t.Context())
+		assert.NoError(t, err)
+		assert.Len(t, all, 1)
+		assert.ElementsMatch(t, purchase.Lines, all[0].Lines)
+
+		err = repo.Delete(t.Context(), purchase)
+		assert.NoError(t, err, "children are deleted before the entity")
+
+		var count int
+		err = pgx.QueryRow(t.Context(), `SELECT COUNT(*) FROM purchase_line;`).Scan(&count)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("children of a table in a schema", func(t *testing.T) {
+		t.Parallel()
+
+		type Line struct {
+			Product string `db:"product"`
+		}
+
+		type Purchase struct {
+			ID    string `db:"id"`
+			Lines []Line `db:"shop.purchase_line,children"`
+		}
+
+		pgx := pgHandler.NewTestDatabase()
+
+		_, err := pgx.Exec(t.Context(), `CREATE SCHEMA shop;
+			CREATE TABLE shop.purchase(id TEXT PRIMARY KEY);
+			CREATE TABLE shop.purchase_line(purchase_id TEXT REFERENCES shop.purchase(id), product TEXT);`)
+		assert.NoError(t, err)
+
+		repo, err := arepo.NewPostgresRepository[Purchase, string](pgx)
+		assert.NoError(t, err)
+		repo.Table = "shop.purchase"
+
+		purchase := Purchase{ID: id, Lines: []Line{{Product: gofakeit.Name()}}}
+
+		err = postgres.InTx(t.Context(), pgx, func(ctx context.Context) error {
+			err := repo.Create(ctx, purchase)
+			assert.NoError(t, err)
+
+			all, err := repo.All(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, []Purchase{purchase}, all, "children are read in the transaction")
+
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("json option requires a column name", func(t *testing.T) {
+		t.Parallel()
+
+		type MyType struct {
+			ID      string            `db:"id"`
+			Profile map[string]string `db:",json"`
+		}
+
+		repo, err := arepo.NewPostgresRepository[MyType, string](nil)
+		assert.Error(t, err)
+		assert.Nil(t, repo)
+	})
+
+	t.Run("children have to be a slice of structs", func(t *testing.T) {
+		t.Parallel()
+
+		type MyType struct {
+			ID    string   `db:"id"`
+			Names []string `db:",children"`
+		}
+
+		repo, err := arepo.NewPostgresRepository[MyType, string](nil)
+		assert.Error(t, err)
+		assert.Nil(t, repo)
+	})
 }
 
 func initTestSchema(t *testing.T, pgx *pgxpool.Pool) {
@@ -240,7 +365,7 @@ func initTestSchema(t *testing.T, pgx *pgxpool.Pool) {
 	_, err = pgx.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS entitywithoutid(name TEXT PRIMARY KEY);`)
 	assert.NoError(t, err)
 
-	_, err = pgx.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS entitywithintpk(id SERIAL PRIMARY KEY, uint_id INTEGER, name TEXT);`)
+	_, err = pgx.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS entitywithintpk(id SERIAL PRIMARY KEY, uint_id INTEGER, name TEXT, score DOUBLE PRECISION);`)
 	assert.NoError(t, err)
 
 	_, err = pgx.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS nestedstruct(id TEXT PRIMARY KEY, "custom.name" TEXT);`)
@@ -293,3 +418,172 @@ func TestPostgresRepository_All(t *testing.T) {
 // - each run is in a new docker container with random values in the DB, so disc caching is not an issue
 // - the database table is trivial. Does not represent anything real world data
 // - network latency is not considered / tests run all on same machine
+
+func TestPostgresRepository_WithTimestamps(t *testing.T) {
+	t.Parallel()
+
+	testTimestamps(t, func(opts ...arepo.Option) arepo.Repository[stampedEntity, string] {
+		pgx := pgHandler.NewTestDatabase()
+
+		sql, err := arepo.CreateTableSQL[stampedEntity]()
+		assert.NoError(t, err)
+
+		_, err = pgx.Exec(t.Context(), sql)
+		assert.NoError(t, err)
+
+		repo, err := arepo.NewPostgresRepository[stampedEntity, string](pgx, opts...)
+		assert.NoError(t, err)
+
+		return repo
+	})
+}
+
+func TestPostgresRepository_OrderNulls(t *testing.T) {
+	t.Parallel()
+
+	pgx := pgHandler.NewTestDatabase()
+
+	sql, err := arepo.CreateTableSQL[stampedEntity]()
+	assert.NoError(t, err)
+
+	_, err = pgx.Exec(t.Context(), sql)
+	assert.NoError(t, err)
+
+	repo, err := arepo.NewPostgresRepository[stampedEntity, string](pgx)
+	assert.NoError(t, err)
+
+	testOrderNulls(t, repo)
+}
+
+func TestPostgresRepository_WithReplicas(t *testing.T) {
+	t.Parallel()
+
+	// a separate database takes the role of the replica, so it is visible where reads go to
+	primary, replica := pgHandler.NewTestDatabase(), pgHandler.NewTestDatabase()
+
+	repo, err := arepo.NewPostgresRepository[testdata.Entity, testdata.EntityID](primary, arepo.WithReplicas(replica))
+	assert.NoError(t, err)
+
+	entity := testdata.RandomEntity()
+	err = repo.Create(t.Context(), entity)
+	assert.NoError(t, err, "writes go to the primary")
+
+	_, err = repo.FindByID(t.Context(), entity.ID)
+	assert.ErrorIs(t, err, arepo.ErrNotFound, "reads go to the replica")
+
+	count, err := repo.Count(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	got, err := repo.FindByID(postgres.WithPrimary(t.Context()), entity.ID)
+	assert.NoError(t, err, "forced reads go to the primary")
+	assert.Equal(t, entity, got)
+
+	tx, err := primary.Begin(t.Context())
+	assert.NoError(t, err)
+
+	defer func() { _ = tx.Rollback(t.Context()) }()
+
+	got, err = repo.FindByID(context.WithValue(t.Context(), postgres.CtxTX, tx), entity.ID)
+	assert.NoError(t, err, "reads in a transaction go to the primary")
+	assert.Equal(t, entity, got)
+}
+
+func TestPostgresRepository_SerializationFailure(t *testing.T) {
+	t.Parallel()
+
+	pgx := pgHandler.NewTestDatabase()
+	initTestSchema(t, pgx)
+
+	repo, err := arepo.NewPostgresRepository[testdata.Entity, testdata.EntityID](pgx)
+	assert.NoError(t, err)
+
+	entity := testdata.RandomEntity()
+	err = repo.Create(t.Context(), entity)
+	assert.NoError(t, err)
+
+	read := make(chan struct{})
+	updated := make(chan struct{})
+	attempts := 0
+
+	done := make(chan error)
+
+	go func() {
+		done <- postgres.InTx(t.Context(), pgx, func(ctx context.Context) error {
+			attempts++
+
+			e, err := repo.Read(ctx, entity.ID)
+			if err != nil {
+				return err
+			}
+
+			if attempts == 1 { // let the other transaction change the entity after this one read it
+				close(read)
+				<-updated
+			}
+
+			e.Name = "first"
+
+			return repo.Update(ctx, e)
+		}, postgres.WithIsolation(pgxv5.Serializable))
+	}()
+
+	<-read
+
+	err = postgres.InTx(t.Context(), pgx, func(ctx context.Context) error {
+		e := entity
+		e.Name = "second"
+
+		return repo.Update(ctx, e)
+	}, postgres.WithIsolation(pgxv5.Serializable))
+	assert.NoError(t, err)
+
+	close(updated)
+
+	assert.NoError(t, <-done, "the serialization failure of the repository is retried")
+	assert.Equal(t, 2, attempts)
+
+	got, err := repo.Read(t.Context(), entity.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", got.Name)
+}
+
+func TestTestTable(t *testing.T) {
+	t.Parallel()
+
+	t.Run("hand written table", func(t *testing.T) {
+		t.Parallel()
+
+		pgx := pgHandler.NewTestDatabase()
+		initTestSchema(t, pgx)
+
+		arepo.TestTable[testdata.Entity](t, pgx)
+		arepo.TestTable[testdata.EntityWithIntPK](t, pgx)
+	})
+
+	t.Run("generated table", func(t *testing.T) {
+		t.Parallel()
+
+		type Line struct {
+			Product  string
+			Quantity int
+		}
+
+		type Invoice struct {
+			ID        string
+			Tags      []string
+			Lines     []Line `db:",children"`
+			CreatedAt time.Time
+		}
+
+		pgx := pgHandler.NewTestDatabase()
+
+		sql, err := arepo.CreateTableSQL[Invoice]()
+		assert.NoError(t, err)
+
+		_, err = pgx.Exec(t.Context(), sql)
+		assert.NoError(t, err)
+
+		arepo.TestTable[Invoice](t, pgx)
+	})
+}
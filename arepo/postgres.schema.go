@@ -0,0 +1,249 @@
+package arepo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/google/uuid"
+)
+
+// CreateTableSQL returns the CREATE TABLE statements for the entity E,
+// mapped by the same rules as the PostgresRepository uses, see NewPostgresRepository.
+// Use it to write the migration for a new entity.
+//
+// Pointers, slices, maps, and JSONB columns are nullable, all other columns are NOT NULL.
+// Integer IDs become a BIGSERIAL, so NextID can use the sequence.
+// Tables for children reference the entity's table and are deleted with it.
+func CreateTableSQL[E any](opts ...Option) (string, error) {
+	tables, err := entitySchema[E](opts...)
+	if err != nil {
+		return "", err
+	}
+
+	statements := make([]string, len(tables))
+	for i, table := range tables {
+		statements[i] = table.createSQL()
+	}
+
+	return strings.Join(statements, "\n\n"), nil
+}
+
+// tableSchema is the expected schema of a table the PostgresRepository maps an entity to.
+type tableSchema struct {
+	name    string
+	columns []columnSchema
+	// index is a column that gets an index, e.g. the parent column of children.
+	index string
+}
+
+type columnSchema struct {
+	name     string
+	pgType   string
+	nullable bool
+	// constraint is appended to the column definition, e.g. PRIMARY KEY.
+	constraint string
+}
+
+// entitySchema returns the table of E first, followed by the tables of its children.
+func entitySchema[E any](opts ...Option) ([]tableSchema, error) {
+	repo, err := NewPostgresRepository[E, string](nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	entityType := reflect.TypeFor[E]()
+	idField, _ := entityType.FieldByName(repo.IDFieldName)
+
+	idColumn, _ := parseDBTag(idField)
+	if idColumn == "" {
+		idColumn = dbscan.SnakeCaseMapper(idField.Name)
+	}
+
+	entity := tableSchema{name: repo.Table}
+	idType := ""
+
+	for _, c := range columnTypes(entityType, "") {
+		if c.name == idColumn {
+			c.pgType = idPgType(idField.Type)
+			c.nullable = true // implied by PRIMARY KEY
+			c.constraint = "PRIMARY KEY"
+			idType = pgType(idField.Type)
+		}
+
+		entity.columns = append(entity.columns, c)
+	}
+
+	tables := []tableSchema{entity}
+
+	for _, child := range repo.childTablesWithParent() {
+		parentColumn := strings.Trim(child.parentColumn, `"`)
+
+		table := tableSchema{
+			name:  child.table,
+			index: parentColumn,
+			columns: []columnSchema{{
+				name:       parentColumn,
+				pgType:     idType,
+				constraint: "REFERENCES " + repo.Table + " (" + idColumn + ") ON UPDATE CASCADE ON DELETE CASCADE",
+			}},
+		}
+
+		for _, c := range columnTypes(child.elem, "") {
+			if c.name != parentColumn {
+				table.columns = append(table.columns, c)
+			}
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// createSQL formats the table in the same style as the arrower migrations.
+func (s tableSchema) createSQL() string {
+	nameWidth, typeWidth := 0, 0
+
+	for _, c := range s.columns {
+		nameWidth = max(nameWidth, len(quoteIdent(c.name)))
+		typeWidth = max(typeWidth, len(c.pgType))
+	}
+
+	columns := make([]string, len(s.columns))
+
+	for i, c := range s.columns {
+		definition := fmt.Sprintf("    %-*s %-*s", nameWidth, quoteIdent(c.name), typeWidth, c.pgType)
+
+		if !c.nullable {
+			definition += " NOT NULL"
+		}
+
+		if c.constraint != "" {
+			definition += " " + c.constraint
+		}
+
+		columns[i] = strings.TrimRight(definition, " ")
+	}
+
+	sql := "CREATE TABLE IF NOT EXISTS " + s.name + "\n(\n" + strings.Join(columns, ",\n") + "\n);"
+
+	if s.index != "" {
+		indexName := strings.ReplaceAll(s.name, ".", "_") + "_" + s.index + "_idx"
+		sql += "\n\nCREATE INDEX IF NOT EXISTS " + indexName + " ON " + s.name + " (" + quoteIdent(s.index) + ");"
+	}
+
+	return sql
+}
+
+// columnTypes returns the columns of the struct type t with their Postgres types.
+// It follows the same rules as columnsAndValues applied to the zero value of t.
+func columnTypes(t reflect.Type, prefix string) []columnSchema {
+	result := make([]columnSchema, 0, t.NumField())
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		col, opts := parseDBTag(f)
+		if col == "-" || opts.children {
+			continue
+		}
+
+		if col == "" {
+			col = dbscan.SnakeCaseMapper(f.Name)
+		}
+
+		if opts.json {
+			result = append(result, columnSchema{name: joinPrefix(prefix, col), pgType: "JSONB", nullable: true})
+			continue
+		}
+
+		ft := f.Type
+		nullable := false
+
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+			nullable = true
+		}
+
+		// A nil pointer to a struct is not flattened, same as in columnsAndValues.
+		if ft.Kind() == reflect.Struct && !isScalarStruct(ft) && !nullable {
+			newPrefix := joinPrefix(prefix, col)
+			if name, _ := parseDBTag(f); f.Anonymous && name == "" {
+				newPrefix = prefix
+			}
+
+			result = append(result, columnTypes(ft, newPrefix)...)
+
+			continue
+		}
+
+		kind := ft.Kind()
+		nullable = nullable || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Interface
+
+		result = append(result, columnSchema{name: joinPrefix(prefix, col), pgType: pgType(ft), nullable: nullable})
+	}
+
+	return result
+}
+
+var uuidType = reflect.TypeFor[uuid.UUID]()
+
+// pgType returns the Postgres type pgx encodes values of the Go type t to.
+//
+//nolint:cyclop // one case per kind keeps the mapping readable
+func pgType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "TIMESTAMP WITH TIME ZONE"
+	case t == uuidType:
+		return "UUID"
+	case t.Kind() == reflect.Struct && isScalarStruct(t):
+		return "TEXT"
+	}
+
+	switch t.Kind() { //nolint:exhaustive // all other kinds are stored as JSONB
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "SMALLINT"
+	case reflect.Int32, reflect.Uint16:
+		return "INTEGER"
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "BIGINT"
+	case reflect.Float32:
+		return "REAL"
+	case reflect.Float64:
+		return "DOUBLE PRECISION"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BYTEA"
+		}
+
+		if t.Elem().Kind() == reflect.Struct && !isScalarStruct(t.Elem()) {
+			return "JSONB"
+		}
+
+		return pgType(t.Elem()) + "[]"
+	default:
+		return "JSONB"
+	}
+}
+
+// idPgType returns the type of the primary key column.
+func idPgType(t reflect.Type) string {
+	switch t.Kind() { //nolint:exhaustive // id constraint only allows strings and integers
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "BIGSERIAL"
+	default:
+		return pgType(t)
+	}
+}
@@ -0,0 +1,126 @@
+package arepo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo"
+	"github.com/go-arrower/arrower/arepo/testdata"
+	"github.com/go-arrower/arrower/secret"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	t.Parallel()
+
+	t.Run("entity", func(t *testing.T) {
+		t.Parallel()
+
+		sql, err := arepo.CreateTableSQL[testdata.Entity]()
+		assert.NoError(t, err)
+		assert.Equal(t, `CREATE TABLE IF NOT EXISTS entity
+(
+    "id"   TEXT PRIMARY KEY,
+    "name" TEXT NOT NULL
+);`, sql)
+	})
+
+	t.Run("integer id", func(t *testing.T) {
+		t.Parallel()
+
+		sql, err := arepo.CreateTableSQL[testdata.EntityWithIntPK]()
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `"id"      BIGSERIAL        PRIMARY KEY`)
+		assert.Contains(t, sql, `"uint_id" BIGINT           NOT NULL`)
+		assert.Contains(t, sql, `"score"   DOUBLE PRECISION`+"\n")
+	})
+
+	t.Run("id field", func(t *testing.T) {
+		t.Parallel()
+
+		sql, err := arepo.CreateTableSQL[testdata.EntityWithNamePK](arepo.WithIDField("Name"))
+		assert.NoError(t, err)
+		assert.Contains(t, sql, `"name"        TEXT PRIMARY KEY`)
+	})
+
+	t.Run("all mappings", func(t *testing.T) {
+		t.Parallel()
+
+		type Address struct {
+			Street string
+		}
+
+		type Email struct {
+			Address  string
+			Verified *time.Time
+		}
+
+		type Customer struct {
+			ID        string
+			Address   Address
+			Previous  *Address
+			Age       int
+			Score     float64
+			Admin     bool
+			Tags      []string
+			Avatar    []byte
+			Key       secret.Secret
+			Profile   map[string]string `db:"profile,json"`
+			Emails    []Email           `db:"customer_email,children"`
+			CreatedAt time.Time
+			Ignored   string `db:"-"`
+		}
+
+		sql, err := arepo.CreateTableSQL[Customer]()
+		assert.NoError(t, err)
+		assert.Equal(t, `CREATE TABLE IF NOT EXISTS customer
+(
+    "id"             TEXT                     PRIMARY KEY,
+    "address.street" TEXT                     NOT NULL,
+    "previous"       JSONB,
+    "age"            BIGINT                   NOT NULL,
+    "score"          DOUBLE PRECISION         NOT NULL,
+    "admin"          BOOLEAN                  NOT NULL,
+    "tags"           TEXT[],
+    "avatar"         BYTEA,
+    "key"            TEXT                     NOT NULL,
+    "profile"        JSONB,
+    "created_at"     TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer_email
+(
+    "customer_id" TEXT                     NOT NULL REFERENCES customer (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    "address"     TEXT                     NOT NULL,
+    "verified"    TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS customer_email_customer_id_idx ON customer_email ("customer_id");`, sql)
+	})
+
+	t.Run("json option without column name", func(t *testing.T) {
+		t.Parallel()
+
+		type Customer struct {
+			ID      string
+			Profile map[string]string `db:",json"`
+		}
+
+		sql, err := arepo.CreateTableSQL[Customer]()
+		assert.ErrorContains(t, err, "json option requires a column name")
+		assert.Empty(t, sql)
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		t.Parallel()
+
+		type noID struct {
+			Name string
+		}
+
+		sql, err := arepo.CreateTableSQL[noID]()
+		assert.Error(t, err)
+		assert.Empty(t, sql)
+	})
+}
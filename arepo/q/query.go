@@ -3,6 +3,7 @@ package q
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/georgysavva/scany/v2/dbscan"
@@ -14,8 +15,9 @@ import (
    Pattern matching: Like
    Set operations: In
    Logical grouping: And, Or with nested groups
-   Ordering: OrderBy with ASC/DESC
+   Ordering: OrderBy with ASC/DESC and NULLS FIRST/LAST, multiple sort keys
    Pagination: Limit and Offset
+   Full-text search: Search with ranking
 
 */
 
@@ -40,7 +42,75 @@ const (
 
 type Query struct {
 	Conditions ConditionGroup
-	ordering   *orderBy
+	ordering   []Order
+	limit      int
+	offset     int
+	search     *TextSearch
+}
+
+// TextSearch is a full-text search of a Query.
+// Entities have to match all words of Text in at least one of the Fields.
+// If no Fields are given, the repository decides which fields are searched.
+type TextSearch struct {
+	Text   string
+	Fields []string
+}
+
+// Search returns a Query matching all entities that contain text in one of the fields.
+// Unless the query is ordered otherwise, the best matches come first.
+func Search(text string, fields ...string) Query {
+	return Query{}.Search(text, fields...)
+}
+
+// Search combines the query with a full-text search, see Search.
+func (q Query) Search(text string, fields ...string) Query {
+	q.search = &TextSearch{Text: text, Fields: fields}
+	return q
+}
+
+// TextSearch returns the full-text search of the query or nil, if it has none.
+func (q Query) TextSearch() *TextSearch {
+	return q.search
+}
+
+// Order is a sort key of a Query.
+type Order struct {
+	Field      string
+	Descending bool
+	Nulls      Nulls
+}
+
+// Nulls defines where entities without a value, e.g. a nil pointer, are sorted.
+type Nulls int
+
+const (
+	// NullsDefault sorts missing values like Postgres does:
+	// last for ascending and first for descending orders.
+	NullsDefault Nulls = iota
+	NullsFirst
+	NullsLast
+)
+
+// Orders returns the sort keys of the query, the most significant first.
+func (q Query) Orders() []Order {
+	return q.ordering
+}
+
+// Limit returns at most n results. Zero means no limit.
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n results.
+func (q Query) Offset(n int) Query {
+	q.offset = n
+	return q
+}
+
+// Pagination returns the limit and offset of the query. A limit of zero means no limit.
+func (q Query) Pagination() (int, int) {
+	return q.limit, q.offset
 }
 
 func (q Query) Where(field string) *WhereQuery {
@@ -108,11 +178,10 @@ type FieldQuery struct{}
 
 func (f *FieldQuery) Is(_ any) FieldQuery { return FieldQuery{} }
 
-type orderBy struct {
-	field     string
-	direction string
-}
-
+// OrderBy adds a sort key to the query. Calling it again adds the next sort key,
+// used if the previous ones are equal, e.g.
+//
+//	q.Query{}.OrderBy("name").Ascending().OrderBy("age").Descending()
 func (q Query) OrderBy(field string) *OrderQuery {
 	return &OrderQuery{query: &q, field: field}
 }
@@ -120,15 +189,37 @@ func (q Query) OrderBy(field string) *OrderQuery {
 type OrderQuery struct {
 	query *Query
 	field string
+	nulls Nulls
+}
+
+// NullsFirst sorts entities without a value before all others.
+func (o *OrderQuery) NullsFirst() *OrderQuery {
+	o.nulls = NullsFirst
+	return o
+}
+
+// NullsLast sorts entities without a value after all others.
+func (o *OrderQuery) NullsLast() *OrderQuery {
+	o.nulls = NullsLast
+	return o
 }
 
 func (o *OrderQuery) Ascending() Query {
-	o.query.ordering = &orderBy{field: o.field, direction: "ASC"}
-	return *o.query
+	return o.order(false)
 }
 
 func (o *OrderQuery) Descending() Query {
-	o.query.ordering = &orderBy{field: o.field, direction: "DESC"}
+	return o.order(true)
+}
+
+func (o *OrderQuery) order(descending bool) Query {
+	// copy, so queries derived from the same query do not share their sort keys
+	o.query.ordering = append(slices.Clone(o.query.ordering), Order{
+		Field:      o.field,
+		Descending: descending,
+		Nulls:      o.nulls,
+	})
+
 	return *o.query
 }
 
@@ -161,7 +252,7 @@ func Filter[T any](objFilter T) Query {
 func fieldName(tField reflect.StructField) string {
 	var name string
 
-	if dbTag := tField.Tag.Get("db"); dbTag != "" {
+	if dbTag, _, _ := strings.Cut(tField.Tag.Get("db"), ","); dbTag != "" {
 		name = dbTag
 		if strings.Contains(name, ".") {
 			name = fmt.Sprintf(`"%s"`, dbTag)
@@ -0,0 +1,88 @@
+package q
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FieldRef is a reference to the field of type T of the entity E.
+// Use it instead of field names as strings, so typos fail when the query is built.
+type FieldRef[E any, T any] struct {
+	name string
+}
+
+// Ref returns a reference to the field of E, that field returns a pointer to, e.g.
+//
+//	name := q.Ref(func(u *User) *string { return &u.Name })
+//	repo.AllBy(ctx, name.Is("gopher"))
+//
+// The field is named like in Filter, so the reference works with all repositories.
+// It panics, if field does not return a pointer to an exported field of E.
+// Embedded structs are not supported, reference them directly.
+func Ref[E any, T any](field func(e *E) *T) FieldRef[E, T] {
+	entityType := reflect.TypeFor[E]()
+	if entityType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("arrower: q.Ref: %s is not a struct", entityType))
+	}
+
+	entity := reflect.New(entityType)
+	ptr := reflect.ValueOf(field(entity.Interface().(*E))) //nolint:forcetypeassert // entity is of type *E
+
+	if ptr.IsNil() {
+		panic(fmt.Sprintf("arrower: q.Ref: no field of %s referenced", entityType))
+	}
+
+	for i := range entityType.NumField() {
+		f := entityType.Field(i)
+		if f.Anonymous || f.Type != reflect.TypeFor[T]() {
+			continue
+		}
+
+		if entity.Elem().Field(i).Addr().Pointer() == ptr.Pointer() {
+			if !f.IsExported() {
+				panic(fmt.Sprintf("arrower: q.Ref: field %s of %s is not exported", f.Name, entityType))
+			}
+
+			return FieldRef[E, T]{name: fieldName(f)}
+		}
+	}
+
+	panic(fmt.Sprintf("arrower: q.Ref: referenced value is not a field of %s", entityType))
+}
+
+// String returns the name of the field, to use it with Where or OrderBy.
+func (f FieldRef[E, T]) String() string {
+	return f.name
+}
+
+// Is returns a Query matching all entities, where the field equals value.
+func (f FieldRef[E, T]) Is(value T) Query {
+	return Where(f.name).Is(value)
+}
+
+// Ascending returns a Query ordered by the field in ascending order.
+func (f FieldRef[E, T]) Ascending() Query {
+	return Query{}.OrderBy(f.name).Ascending()
+}
+
+// Descending returns a Query ordered by the field in descending order.
+func (f FieldRef[E, T]) Descending() Query {
+	return Query{}.OrderBy(f.name).Descending()
+}
+
+// AndIs returns the query with the additional condition, that the field equals value, e.g.
+//
+//	name.AndIs(email.Is("gopher@example.com"), "gopher")
+func (f FieldRef[E, T]) AndIs(query Query, value T) Query {
+	return query.Where(f.name).Is(value)
+}
+
+// ThenAscending returns the query additionally ordered by the field in ascending order.
+func (f FieldRef[E, T]) ThenAscending(query Query) Query {
+	return query.OrderBy(f.name).Ascending()
+}
+
+// ThenDescending returns the query additionally ordered by the field in descending order.
+func (f FieldRef[E, T]) ThenDescending(query Query) Query {
+	return query.OrderBy(f.name).Descending()
+}
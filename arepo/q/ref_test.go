@@ -0,0 +1,92 @@
+package q_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo/q"
+)
+
+type refEntity struct {
+	ID        string
+	Name      string
+	UserEmail string
+	Tagged    int               `db:"custom"`
+	Profile   map[string]string `db:"profile,json"`
+	hidden    string
+}
+
+func TestRef(t *testing.T) {
+	t.Parallel()
+
+	t.Run("named like filter", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, "name", q.Ref(func(e *refEntity) *string { return &e.Name }).String())
+		assert.Equal(t, "user_email", q.Ref(func(e *refEntity) *string { return &e.UserEmail }).String())
+		assert.Equal(t, "custom", q.Ref(func(e *refEntity) *int { return &e.Tagged }).String())
+		assert.Equal(t, "profile", q.Ref(func(e *refEntity) *map[string]string { return &e.Profile }).String())
+	})
+
+	t.Run("is", func(t *testing.T) {
+		t.Parallel()
+
+		query := q.Ref(func(e *refEntity) *string { return &e.Name }).Is("gopher")
+		assert.Equal(t, q.Where("name").Is("gopher"), query)
+	})
+
+	t.Run("order", func(t *testing.T) {
+		t.Parallel()
+
+		name := q.Ref(func(e *refEntity) *string { return &e.Name })
+		query := name.Descending().OrderBy("id").NullsFirst().Ascending()
+
+		assert.Equal(t, []q.Order{
+			{Field: "name", Descending: true},
+			{Field: "id", Nulls: q.NullsFirst},
+		}, query.Orders())
+	})
+
+	t.Run("existing query", func(t *testing.T) {
+		t.Parallel()
+
+		id := q.Ref(func(e *refEntity) *string { return &e.ID })
+		name := q.Ref(func(e *refEntity) *string { return &e.Name })
+
+		query := name.AndIs(id.Is("1"), "gopher")
+		assert.Equal(t, q.Where("id").Is("1").Where("name").Is("gopher"), query)
+
+		query = id.ThenDescending(name.ThenAscending(query))
+		assert.Equal(t, []q.Order{{Field: "name"}, {Field: "id", Descending: true}}, query.Orders())
+		assert.Len(t, query.Conditions.Conditions, 2, "conditions are kept")
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Panics(t, func() {
+			q.Ref(func(_ *refEntity) *string { return nil })
+		})
+		assert.Panics(t, func() {
+			q.Ref(func(_ *refEntity) *string { s := ""; return &s })
+		})
+		assert.Panics(t, func() {
+			q.Ref(func(e *refEntity) *string { return &e.hidden })
+		})
+		assert.Panics(t, func() {
+			q.Ref(func(_ *string) *string { return nil })
+		})
+	})
+}
+
+func TestQuery_OrderBy(t *testing.T) {
+	t.Parallel()
+
+	base := q.Query{}.OrderBy("name").Ascending()
+	first := base.OrderBy("age").Descending()
+	second := base.OrderBy("id").NullsLast().Ascending()
+
+	assert.Equal(t, []q.Order{{Field: "name"}, {Field: "age", Descending: true}}, first.Orders())
+	assert.Equal(t, []q.Order{{Field: "name"}, {Field: "id", Nulls: q.NullsLast}}, second.Orders())
+}
@@ -53,11 +53,16 @@ type Repository[E any, ID id] interface { //nolint:interfacebloat // showcase of
 	AllBy(ctx context.Context, query q.Query) ([]E, error)
 	AllByIDs(ctx context.Context, ids []ID) ([]E, error)
 
+	// Search returns all entities containing text in one of the fields, the best match first.
+	// It is a shortcut for AllBy with q.Search, use the latter to combine a search with other conditions.
+	Search(ctx context.Context, text string, fields ...string) ([]E, error)
+
 	FindByID(ctx context.Context, id ID) (E, error)
 	FindBy(ctx context.Context, query q.Query) (E, error)
 
 	Exist(ctx context.Context, id ID) (bool, error)     // TODO redundant take entity instead of ID?
 	ExistByID(ctx context.Context, id ID) (bool, error) // TODO redundant
+	ExistBy(ctx context.Context, query q.Query) (bool, error)
 	ExistByIDs(ctx context.Context, ids []ID) (bool, error)
 	ExistAll(ctx context.Context, ids []ID) (bool, error) // TODO remove for consistency as ExistsByIDs is there?
 	Contains(ctx context.Context, id ID) (bool, error)
@@ -73,25 +78,51 @@ type Repository[E any, ID id] interface { //nolint:interfacebloat // showcase of
 	AddAll(ctx context.Context, entities []E) error
 
 	Count(ctx context.Context) (int, error)
-	// CountBy
+	CountBy(ctx context.Context, query q.Query) (int, error)
 	Length(ctx context.Context) (int, error)
 
+	// Sum returns the sum of the numeric field of all entities matching query.
+	Sum(ctx context.Context, field string, query q.Query) (float64, error)
+	// Min returns the smallest value of field of all entities matching query.
+	// The value is of the same type as the field. If no entity matches, ErrNotFound is returned.
+	Min(ctx context.Context, field string, query q.Query) (any, error)
+	// Max returns the biggest value of field of all entities matching query.
+	// The value is of the same type as the field. If no entity matches, ErrNotFound is returned.
+	Max(ctx context.Context, field string, query q.Query) (any, error)
+	// Project selects only the fields of a projection struct for all entities matching query.
+	// dst has to be a pointer to a slice of structs, whose fields are a subset of the fields of E.
+	Project(ctx context.Context, query q.Query, dst any) error
+
 	// DeleteByID removes the entity with the given it from the repository.
 	// If no ID is set, nothing happens and no error is returned.
 	DeleteByID(ctx context.Context, id ID) error
 	DeleteByIDs(ctx context.Context, ids []ID) error
-	// DeleteBy
+	DeleteBy(ctx context.Context, query q.Query) error
 	DeleteAll(ctx context.Context) error
 	Clear(ctx context.Context) error
 
 	AllIter(ctx context.Context) Iterator[E, ID]
-	// AllByIter
+	// AllByIter iterates over all entities matching query, respecting its ordering, limit and offset.
+	// Stopping the iteration early releases all resources held by the iterator.
+	AllByIter(ctx context.Context, query q.Query) Iterator[E, ID]
 }
 
 type Iterator[E any, ID id] interface {
 	Next() func(yield func(e E, err error) bool)
 }
 
+// projectionType returns the struct type of the elements of dst,
+// which has to be a pointer to a slice of structs.
+func projectionType(dst any) (reflect.Type, error) {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Pointer ||
+		t.Elem().Kind() != reflect.Slice || t.Elem().Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: projection must be a pointer to a slice of structs, got: %T", errInvalidQuery, dst)
+	}
+
+	return t.Elem().Elem(), nil
+}
+
 // id are the types allowed as a primary key used in the generic Repository.
 type id interface {
 	~string |
@@ -111,5 +142,6 @@ var (
 	errExistsFailed       = fmt.Errorf("%w: exists failed", ErrStorage)
 	errSaveFailed         = fmt.Errorf("%w: save failed", ErrStorage)
 	errCountFailed        = fmt.Errorf("%w: count failed", ErrStorage)
+	errAggregateFailed    = fmt.Errorf("%w: aggregate failed", ErrStorage)
 	errInvalidQuery       = fmt.Errorf("%w: invalid query", ErrStorage)
 )
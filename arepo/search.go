@@ -0,0 +1,191 @@
+package arepo
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/go-arrower/arrower/arepo/q"
+)
+
+// SearchWeight ranks matches in a field higher or lower,
+// from SearchWeightA, the most important, to SearchWeightD, the least important.
+// The weights are the same as the ones of tsvector in Postgres.
+type SearchWeight string
+
+const (
+	SearchWeightA SearchWeight = "A"
+	SearchWeightB SearchWeight = "B"
+	SearchWeightC SearchWeight = "C"
+	SearchWeightD SearchWeight = "D"
+)
+
+// rank returns the factor of a match in a field with weight w.
+// The values are the default weights of ts_rank in Postgres.
+func (w SearchWeight) rank() float64 {
+	switch w {
+	case SearchWeightA:
+		return 1.0
+	case SearchWeightB:
+		return 0.4
+	case SearchWeightC:
+		return 0.2
+	default:
+		return 0.1
+	}
+}
+
+// WithSearchFields sets the fields searched by Search and their weight.
+// The fields are named like in q.Where.
+// Without it, all text fields are searched with the same weight.
+// Fields given to Search explicitly, but not set here, have SearchWeightD.
+func WithSearchFields(fields map[string]SearchWeight) Option {
+	return func(repo any) error {
+		for field, weight := range fields {
+			if !slices.Contains([]SearchWeight{SearchWeightA, SearchWeightB, SearchWeightC, SearchWeightD}, weight) {
+				return fmt.Errorf("%w: invalid search weight %q of field: %s", errInvalidOption, weight, field)
+			}
+		}
+
+		if r, ok := repo.(interface {
+			setSearchFields(fields map[string]SearchWeight)
+		}); ok {
+			r.setSearchFields(fields)
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithSearchFields can not be used with this repository", errInvalidOption)
+	}
+}
+
+type searchField struct {
+	name   string
+	weight SearchWeight
+}
+
+// searchFields returns the fields to search, ordered by name.
+// If neither search nor configured name any fields, nil is returned
+// and the repository falls back to all text fields.
+func searchFields(search *q.TextSearch, configured map[string]SearchWeight) []searchField {
+	names := search.Fields
+	if len(names) == 0 {
+		names = slices.Collect(maps.Keys(configured))
+	}
+
+	fields := make([]searchField, 0, len(names))
+
+	for _, name := range names {
+		weight, ok := configured[name]
+		if !ok {
+			weight = SearchWeightD
+		}
+
+		fields = append(fields, searchField{name: name, weight: weight})
+	}
+
+	slices.SortFunc(fields, func(a, b searchField) int { return cmp.Compare(a.name, b.name) })
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return fields
+}
+
+// searchTokens splits text into lower case words.
+// Everything that is not a letter or number separates words,
+// so the tokens are safe to use in a tsquery.
+func searchTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchEntities keeps only the entities matching the search of query, the best match first.
+// An entity matches, if each word of the search is contained in at least one of the fields.
+// Each occurrence of a word in a field counts with the weight of the field.
+func searchEntities[E any](entities []E, query q.Query, configured map[string]SearchWeight) ([]E, error) {
+	search := query.TextSearch()
+	if search == nil {
+		return entities, nil
+	}
+
+	tokens := searchTokens(search.Text)
+	if len(tokens) == 0 {
+		return entities, nil
+	}
+
+	entityType := reflect.TypeFor[E]()
+	fields := searchFields(search, configured)
+
+	if fields == nil {
+		for i := range entityType.NumField() {
+			if f := entityType.Field(i); f.IsExported() && f.Type.Kind() == reflect.String {
+				fields = append(fields, searchField{name: f.Name, weight: SearchWeightD})
+			}
+		}
+	}
+
+	for i, f := range fields {
+		name := fieldName(entityType, f.name)
+		if _, ok := entityType.FieldByName(name); !ok {
+			return nil, fmt.Errorf("%w: entity does not have field: %s", errInvalidQuery, f.name)
+		}
+
+		fields[i].name = name
+	}
+
+	type ranked struct {
+		entity E
+		score  float64
+	}
+
+	matches := []ranked{}
+
+	for _, entity := range entities {
+		score, ok := searchScore(reflect.ValueOf(entity), fields, tokens)
+		if ok {
+			matches = append(matches, ranked{entity: entity, score: score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b ranked) int { return cmp.Compare(b.score, a.score) })
+
+	result := make([]E, len(matches))
+	for i, m := range matches {
+		result[i] = m.entity
+	}
+
+	return result, nil
+}
+
+// searchScore returns the score of the entity and if all tokens are found in its fields.
+func searchScore(entity reflect.Value, fields []searchField, tokens []string) (float64, bool) {
+	values := make([]string, len(fields))
+	for i, f := range fields {
+		values[i] = strings.ToLower(fmt.Sprint(entity.FieldByName(f.name).Interface()))
+	}
+
+	score := 0.0
+
+	for _, token := range tokens {
+		found := false
+
+		for i, f := range fields {
+			if n := strings.Count(values[i], token); n > 0 {
+				score += float64(n) * f.weight.rank()
+				found = true
+			}
+		}
+
+		if !found {
+			return 0, false
+		}
+	}
+
+	return score, true
+}
@@ -25,3 +25,20 @@ func (n noopStore) Store(_ string, _ any) error {
 func (n noopStore) Load(_ string, _ any) error {
 	return nil
 }
+
+// AppendStore is a Store that can persist the changes of single entities,
+// instead of all data of a MemoryRepository at once.
+// If the Store of a MemoryRepository implements AppendStore,
+// the repository only appends the entities it changed.
+type AppendStore interface {
+	Store
+	Append(fileName string, changes ...StoreChange) error
+}
+
+// StoreChange is the change of a single entity of a MemoryRepository.
+type StoreChange struct {
+	ID any
+	// Entity is the new value of the entity, if it is not Deleted.
+	Entity  any
+	Deleted bool
+}
@@ -1,6 +1,10 @@
 package arepo
 
-import "context"
+import (
+	"context"
+
+	"github.com/go-arrower/arrower/arepo/q"
+)
 
 // TenantRepository is a general purpose interface documenting
 // which methods are available by the generic MemoryTenantRepository.
@@ -47,4 +51,6 @@ type TenantRepository[tID id, E any, eID id] interface { //nolint:interfacebloat
 	DeleteAllOfTenant(ctx context.Context, tenantID tID) error
 	Clear(ctx context.Context) error
 	ClearTenant(ctx context.Context, tenantID tID) error
+
+	AllByIter(ctx context.Context, tenantID tID, query q.Query) Iterator[E, eID]
 }
@@ -28,6 +28,7 @@ type (
 		ID     EntityIDInt
 		UintID EntityIDUint `db:"uint_id"`
 		Name   string
+		Score  *float64
 	}
 )
 
@@ -0,0 +1,308 @@
+package arepo
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo/q"
+)
+
+// TestConformance ensures that a custom Repository implementation, e.g. a hand-written one using sqlc,
+// behaves like the MemoryRepository and PostgresRepository.
+// It checks the NotFound and AlreadyExists semantics, queries, ordering, and iterators.
+//
+// newRepo has to return a new and empty repository on each call,
+// newEntity a new entity with a unique ID on each call.
+// The ID field of E is "ID", unless set with WithIDField. It is queried by its column name,
+// e.g. "id", and has to be orderable.
+//
+//	func TestUserRepository(t *testing.T) {
+//		arepo.TestConformance(t,
+//			func() arepo.Repository[User, UserID] { return NewUserRepository(pgHandler.NewTestDatabase()) },
+//			func() User { return User{ID: UserID(uuid.NewString()), Name: gofakeit.Name()} },
+//		)
+//	}
+//
+//nolint:maintidx,tparallel // t.Parallel can only be called ones! The caller decides
+func TestConformance[E any, ID id](
+	t *testing.T,
+	newRepo func() Repository[E, ID],
+	newEntity func() E,
+	opts ...Option,
+) {
+	t.Helper()
+
+	if newRepo == nil || newEntity == nil {
+		t.Fatal("repository or entity constructor is nil")
+	}
+
+	config := &memoryRepoConfig{IDFieldName: "ID"}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			t.Fatal("invalid option: " + err.Error())
+		}
+	}
+
+	idField, ok := reflect.TypeFor[E]().FieldByName(config.IDFieldName)
+	if !ok {
+		t.Fatal("entity does not have the ID field: " + config.IDFieldName)
+	}
+
+	idColumn, _ := parseDBTag(idField)
+	if idColumn == "" {
+		idColumn = dbscan.SnakeCaseMapper(idField.Name)
+	}
+
+	getID := func(e E) ID {
+		return reflect.ValueOf(e).FieldByName(config.IDFieldName).Interface().(ID) //nolint:forcetypeassert,lll // ID is the type of the field
+	}
+
+	// newEntities returns n entities ordered by their ID, and stores them in repo.
+	newEntities := func(t *testing.T, repo Repository[E, ID], n int) []E {
+		t.Helper()
+
+		entities := make([]E, n)
+		for i := range entities {
+			entities[i] = newEntity()
+		}
+
+		slices.SortFunc(entities, func(a, b E) int {
+			c, _ := compareValues(reflect.ValueOf(getID(a)), reflect.ValueOf(getID(b)))
+			return c
+		})
+
+		if err := repo.AddAll(t.Context(), entities); err != nil {
+			t.Fatal("could not add entities: " + err.Error())
+		}
+
+		return entities
+	}
+
+	ids := func(entities []E) []ID {
+		result := make([]ID, len(entities))
+		for i, e := range entities {
+			result[i] = getID(e)
+		}
+
+		return result
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entity := newEntity()
+
+		err := repo.Create(t.Context(), entity)
+		assert.NoError(t, err)
+
+		got, err := repo.Read(t.Context(), getID(entity))
+		assert.NoError(t, err)
+		assert.Equal(t, entity, got)
+
+		err = repo.Create(t.Context(), entity)
+		assert.ErrorIs(t, err, ErrAlreadyExists, "same entity again")
+	})
+
+	t.Run("Read", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+
+		_, err := repo.Read(t.Context(), getID(newEntity()))
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = repo.FindByID(t.Context(), getID(newEntity()))
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entity := newEntity()
+
+		err := repo.Update(t.Context(), entity)
+		assert.ErrorIs(t, err, ErrNotFound, "entity does not exist yet")
+
+		err = repo.Create(t.Context(), entity)
+		assert.NoError(t, err)
+
+		err = repo.Update(t.Context(), entity)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entity := newEntity()
+
+		err := repo.Save(t.Context(), entity)
+		assert.NoError(t, err, "creates")
+
+		err = repo.Save(t.Context(), entity)
+		assert.NoError(t, err, "updates")
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entities := newEntities(t, repo, 2)
+
+		err := repo.Delete(t.Context(), newEntity())
+		assert.NoError(t, err, "deleting a non existing entity is not an error")
+
+		err = repo.Delete(t.Context(), entities[0])
+		assert.NoError(t, err)
+
+		exists, err := repo.ExistByID(t.Context(), getID(entities[0]))
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		err = repo.DeleteAll(t.Context())
+		assert.NoError(t, err)
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entities := newEntities(t, repo, 3)
+
+		err := repo.AddAll(t.Context(), []E{newEntity(), entities[0]})
+		assert.ErrorIs(t, err, ErrAlreadyExists)
+
+		err = repo.UpdateAll(t.Context(), []E{entities[0], newEntity()})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count, "failed bulk operations change nothing")
+
+		all, err := repo.AllByIDs(t.Context(), ids(entities))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, entities, all)
+
+		exist, err := repo.ExistAll(t.Context(), append(ids(entities), getID(newEntity())))
+		assert.NoError(t, err)
+		assert.False(t, exist)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entities := newEntities(t, repo, 3)
+		byID := q.Where(idColumn).Is(getID(entities[1]))
+
+		all, err := repo.AllBy(t.Context(), byID)
+		assert.NoError(t, err)
+		assert.Equal(t, []E{entities[1]}, all)
+
+		entity, err := repo.FindBy(t.Context(), byID)
+		assert.NoError(t, err)
+		assert.Equal(t, entities[1], entity)
+
+		_, err = repo.FindBy(t.Context(), q.Where(idColumn).Is(getID(newEntity())))
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		count, err := repo.CountBy(t.Context(), byID)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		exists, err := repo.ExistBy(t.Context(), byID)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		err = repo.DeleteBy(t.Context(), byID)
+		assert.NoError(t, err)
+
+		all, err = repo.All(t.Context())
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []E{entities[0], entities[2]}, all)
+	})
+
+	t.Run("ordering", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entities := newEntities(t, repo, 4)
+
+		all, err := repo.AllBy(t.Context(), q.Query{}.OrderBy(idColumn).Ascending())
+		assert.NoError(t, err)
+		assert.Equal(t, entities, all)
+
+		all, err = repo.AllBy(t.Context(), q.Query{}.OrderBy(idColumn).Descending().Limit(2).Offset(1))
+		assert.NoError(t, err)
+		assert.Equal(t, []E{entities[2], entities[1]}, all)
+
+		all, err = repo.AllBy(t.Context(), q.Query{}.OrderBy(idColumn).Ascending().Offset(-1))
+		assert.NoError(t, err)
+		assert.Equal(t, entities, all, "negative offset is ignored")
+
+		count, err := repo.CountBy(t.Context(), q.Query{}.OrderBy(idColumn).Descending().Limit(2).Offset(1))
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count, "ordering and pagination do not apply to aggregates")
+
+		exists, err := repo.ExistBy(t.Context(), q.Query{}.OrderBy(idColumn).Ascending().Offset(4))
+		assert.NoError(t, err)
+		assert.True(t, exists, "ordering and pagination do not apply to aggregates")
+	})
+
+	t.Run("iterators", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entities := newEntities(t, repo, 3)
+
+		all := []E{}
+
+		for e, err := range repo.AllIter(t.Context()).Next() {
+			assert.NoError(t, err)
+
+			all = append(all, e)
+		}
+
+		assert.ElementsMatch(t, entities, all)
+
+		all = []E{}
+
+		for e, err := range repo.AllByIter(t.Context(), q.Query{}.OrderBy(idColumn).Descending()).Next() {
+			assert.NoError(t, err)
+
+			all = append(all, e)
+			if len(all) == 2 {
+				break
+			}
+		}
+
+		assert.Equal(t, []E{entities[2], entities[1]}, all)
+
+		count, err := repo.Count(t.Context())
+		assert.NoError(t, err, "repository is usable after stopping an iterator early")
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+
+		_, err := repo.AllBy(t.Context(), q.Where(idColumn).Is(nil))
+		assert.ErrorIs(t, err, ErrStorage, "invalid queries are reported as ErrStorage")
+	})
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/brianvoe/gofakeit/v6"
+	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-arrower/arrower/arepo/q"
@@ -97,6 +101,96 @@ func (a *TestAssertions[E, ID]) Total(total int, msgAndArgs ...any) bool {
 	return true
 }
 
+// TestTable asserts that the tables in the database match the entity E,
+// as the PostgresRepository maps it. See CreateTableSQL for the expected tables.
+// Use it in an integration test, to catch mismatches between a migration and the struct.
+func TestTable[E any](t *testing.T, pgx *pgxpool.Pool, opts ...Option) bool {
+	t.Helper()
+
+	tables, err := entitySchema[E](opts...)
+	if err != nil {
+		return assert.Fail(t, "can not map entity to tables: "+err.Error())
+	}
+
+	ok := true
+
+	for _, table := range tables {
+		schema, name := "", table.name
+		if s, n, found := strings.Cut(table.name, "."); found {
+			schema, name = s, n
+		}
+
+		rows := []struct {
+			ColumnName string
+			DataType   string
+		}{}
+
+		err := pgxscan.Select(t.Context(), pgx, &rows,
+			`SELECT column_name, data_type FROM information_schema.columns
+				WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2`,
+			schema, name,
+		)
+		if err != nil {
+			return assert.Fail(t, "can not read columns of table "+table.name+": "+err.Error())
+		}
+
+		if len(rows) == 0 {
+			ok = assert.Fail(t, "table "+table.name+" does not exist")
+			continue
+		}
+
+		actual := make(map[string]string, len(rows))
+		for _, r := range rows {
+			actual[r.ColumnName] = r.DataType
+		}
+
+		for _, c := range table.columns {
+			dataType, exists := actual[c.name]
+			if !exists {
+				ok = assert.Fail(t, fmt.Sprintf("column %q of table %s is missing", c.name, table.name))
+				continue
+			}
+
+			if typeFamily(dataType) != typeFamily(c.pgType) {
+				ok = assert.Fail(t, fmt.Sprintf("column %q of table %s has type %s, expected %s",
+					c.name, table.name, dataType, c.pgType))
+			}
+
+			delete(actual, c.name)
+		}
+
+		for column := range actual {
+			ok = assert.Fail(t, fmt.Sprintf("column %q of table %s is not mapped to a field of %T",
+				column, table.name, *new(E)))
+		}
+	}
+
+	return ok
+}
+
+// typeFamily groups compatible Postgres types, so e.g. an INTEGER column can hold a Go int.
+// It accepts the types of CreateTableSQL as well as the data_type of the information_schema.
+func typeFamily(pgType string) string {
+	pgType = strings.ToLower(pgType)
+
+	switch {
+	case strings.HasSuffix(pgType, "[]") || pgType == "array":
+		return "array"
+	case slices.Contains([]string{"smallint", "integer", "bigint", "serial", "bigserial", "numeric"}, pgType):
+		return "integer"
+	case slices.Contains([]string{"real", "double precision"}, pgType):
+		return "float"
+	case slices.Contains([]string{"text", "character varying", "character"}, pgType):
+		return "text"
+	case strings.HasPrefix(pgType, "timestamp"):
+		return "timestamp"
+	case pgType == "json" || pgType == "jsonb":
+		return "json"
+	default:
+		return pgType
+	}
+}
+
 // TestTenant returns a MemoryTenantRepository tuned for unit testing.
 func TestTenant[tID id, E any, eID id](t *testing.T, repo TenantRepository[tID, E, eID]) *TestTenantRepository[tID, E, eID] {
 	if repo == nil {
@@ -182,6 +276,8 @@ func (a *TestTenantAssertions[tID, E, eID]) Total(total int, msgAndArgs ...any)
 
 // TestSuite is a suite that ensures a Repository implementation
 // adheres to the intended behaviour.
+// It is used for the implementations of arepo and includes TestConformance.
+// To test your own implementation, use TestConformance.
 //
 //nolint:maintidx,tparallel,dupl // t.Parallel can only be called ones! The caller decides
 func TestSuite(
@@ -198,6 +294,15 @@ func TestSuite(
 
 	ctx := t.Context()
 
+	t.Run("Conformance", func(t *testing.T) {
+		t.Parallel()
+
+		TestConformance(t,
+			func() Repository[testdata.Entity, testdata.EntityID] { return newEntityRepo() },
+			testdata.RandomEntity,
+		)
+	})
+
 	//
 	// The assertions of the Repository start below.
 	// Each implementation must adhere to at least this behaviour.
@@ -626,6 +731,31 @@ func TestSuite(
 			assert.NotNil(t, all)
 			assert.Empty(t, all)
 		})
+
+		t.Run("order by multiple fields", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newEntityRepoInt()
+			_ = repo.AddAll(ctx, []testdata.EntityWithIntPK{
+				{ID: 1, UintID: 3, Name: "a"},
+				{ID: 2, UintID: 1, Name: "b"},
+				{ID: 3, UintID: 2, Name: "a"},
+				{ID: 4, UintID: 4, Name: "a"},
+			})
+
+			name := q.Ref(func(e *testdata.EntityWithIntPK) *string { return &e.Name })
+			uintID := q.Ref(func(e *testdata.EntityWithIntPK) *testdata.EntityIDUint { return &e.UintID })
+
+			all, err := repo.AllBy(ctx, name.Ascending().OrderBy(uintID.String()).Descending())
+			assert.NoError(t, err)
+
+			ids := []testdata.EntityIDInt{}
+			for _, e := range all {
+				ids = append(ids, e.ID)
+			}
+
+			assert.Equal(t, []testdata.EntityIDInt{4, 1, 3, 2}, ids)
+		})
 	})
 
 	t.Run("AllByIDs", func(t *testing.T) {
@@ -771,6 +901,33 @@ func TestSuite(
 		assert.False(t, ex, "should check for all IDs")
 	})
 
+	t.Run("ExistAll", func(t *testing.T) {
+		t.Parallel()
+
+		e0 := testdata.RandomEntity()
+		e1 := testdata.RandomEntity()
+		repo := newEntityRepo()
+		_ = repo.Create(ctx, e0)
+		_ = repo.Create(ctx, e1)
+		_ = repo.Create(ctx, testdata.RandomEntity())
+
+		ex, err := repo.ExistAll(ctx, nil)
+		assert.NoError(t, err)
+		assert.False(t, ex, "nil is not matching any IDs")
+
+		ex, err = repo.ExistAll(ctx, []testdata.EntityID{e0.ID, e1.ID})
+		assert.NoError(t, err)
+		assert.True(t, ex)
+
+		ex, err = repo.ExistAll(ctx, []testdata.EntityID{e0.ID, e0.ID, e1.ID})
+		assert.NoError(t, err)
+		assert.True(t, ex, "duplicate IDs should be ignored")
+
+		ex, err = repo.ExistAll(ctx, []testdata.EntityID{e0.ID, testdata.RandomEntity().ID})
+		assert.NoError(t, err)
+		assert.False(t, ex, "should check for all IDs")
+	})
+
 	t.Run("CreateAll", func(t *testing.T) {
 		t.Parallel()
 
@@ -865,6 +1022,57 @@ func TestSuite(
 		})
 	})
 
+	t.Run("SaveAll", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("save all", func(t *testing.T) {
+			t.Parallel()
+
+			entity := testdata.RandomEntity()
+			repo := newEntityRepo()
+			err := repo.Create(ctx, entity)
+			assert.NoError(t, err)
+
+			entity.Name = gofakeit.Name()
+			err = repo.SaveAll(ctx, []testdata.Entity{entity, testdata.RandomEntity(), testdata.RandomEntity()})
+			assert.NoError(t, err)
+
+			c, err := repo.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, c)
+
+			got, err := repo.FindByID(ctx, entity.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, entity, got, "save all implements upsert semantic")
+		})
+
+		t.Run("empty", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newEntityRepo()
+
+			err := repo.SaveAll(ctx, nil)
+			assert.NoError(t, err)
+
+			c, err := repo.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, c)
+		})
+
+		t.Run("missing id", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newEntityRepo()
+
+			err := repo.SaveAll(ctx, []testdata.Entity{testdata.DefaultEntity, {}})
+			assert.ErrorIs(t, err, ErrStorage)
+
+			c, err := repo.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, c, "no entity should be saved")
+		})
+	})
+
 	t.Run("UpdateAll", func(t *testing.T) {
 		t.Parallel()
 
@@ -995,6 +1203,163 @@ func TestSuite(
 		assert.Equal(t, 2, count, "should have two entities")
 	})
 
+	t.Run("CountBy", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newEntityRepo()
+		_ = repo.Create(ctx, testdata.DefaultEntity)
+		_ = repo.Create(ctx, testdata.Entity{ID: testdata.EntityID(uuid.New().String()), Name: testdata.DefaultEntity.Name})
+		_ = repo.Create(ctx, testdata.RandomEntity())
+
+		count, err := repo.CountBy(ctx, q.Query{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count, "empty query should count all entities")
+
+		count, err = repo.CountBy(ctx, q.Where("name").Is(testdata.DefaultEntity.Name))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		count, err = repo.CountBy(ctx, q.Where("name").Is("non-existent"))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		_, err = repo.CountBy(ctx, q.Where("name").Is(nil))
+		assert.ErrorIs(t, err, ErrStorage)
+	})
+
+	t.Run("ExistBy", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newEntityRepo()
+
+		ex, err := repo.ExistBy(ctx, q.Query{})
+		assert.NoError(t, err)
+		assert.False(t, ex, "empty repository")
+
+		_ = repo.Create(ctx, testdata.DefaultEntity)
+
+		ex, err = repo.ExistBy(ctx, q.Where("name").Is(testdata.DefaultEntity.Name))
+		assert.NoError(t, err)
+		assert.True(t, ex)
+
+		ex, err = repo.ExistBy(ctx, q.Where("name").Is("non-existent"))
+		assert.NoError(t, err)
+		assert.False(t, ex)
+	})
+
+	t.Run("Aggregates", func(t *testing.T) {
+		t.Parallel()
+
+		score := func(f float64) *float64 { return &f }
+
+		repo := newEntityRepoInt()
+		_ = repo.Create(ctx, testdata.EntityWithIntPK{ID: 1, UintID: 10, Name: "b", Score: score(1.5)})
+		_ = repo.Create(ctx, testdata.EntityWithIntPK{ID: 2, UintID: 20, Name: "a"})
+		_ = repo.Create(ctx, testdata.EntityWithIntPK{ID: 3, UintID: 30, Name: "c", Score: score(2.5)})
+
+		t.Run("sum", func(t *testing.T) {
+			t.Parallel()
+
+			sum, err := repo.Sum(ctx, "uint_id", q.Query{})
+			assert.NoError(t, err)
+			assert.InDelta(t, 60.0, sum, 0)
+
+			sum, err = repo.Sum(ctx, "uint_id", q.Where("name").Is("a"))
+			assert.NoError(t, err)
+			assert.InDelta(t, 20.0, sum, 0)
+
+			sum, err = repo.Sum(ctx, "uint_id", q.Where("name").Is("non-existent"))
+			assert.NoError(t, err)
+			assert.InDelta(t, 0.0, sum, 0, "sum of nothing is zero")
+
+			sum, err = repo.Sum(ctx, "score", q.Query{})
+			assert.NoError(t, err)
+			assert.InDelta(t, 4.0, sum, 0, "nil values are skipped")
+
+			_, err = repo.Sum(ctx, "name", q.Where("name").Is("non-existent"))
+			assert.ErrorIs(t, err, ErrStorage, "field is not numeric, even if nothing matches")
+		})
+
+		t.Run("min", func(t *testing.T) {
+			t.Parallel()
+
+			minimum, err := repo.Min(ctx, "uint_id", q.Query{})
+			assert.NoError(t, err)
+			assert.Equal(t, testdata.EntityIDUint(10), minimum, "value has the type of the field")
+
+			minimum, err = repo.Min(ctx, "name", q.Query{})
+			assert.NoError(t, err)
+			assert.Equal(t, "a", minimum)
+
+			_, err = repo.Min(ctx, "uint_id", q.Where("name").Is("non-existent"))
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+
+		t.Run("max", func(t *testing.T) {
+			t.Parallel()
+
+			maximum, err := repo.Max(ctx, "uint_id", q.Query{})
+			assert.NoError(t, err)
+			assert.Equal(t, testdata.EntityIDUint(30), maximum)
+
+			maximum, err = repo.Max(ctx, "uint_id", q.Where("name").Is("a"))
+			assert.NoError(t, err)
+			assert.Equal(t, testdata.EntityIDUint(20), maximum)
+		})
+
+		t.Run("unknown field", func(t *testing.T) {
+			t.Parallel()
+
+			_, err := repo.Max(ctx, "non-existent", q.Query{})
+			assert.ErrorIs(t, err, ErrStorage)
+
+			_, err = repo.Sum(ctx, "non-existent", q.Query{})
+			assert.ErrorIs(t, err, ErrStorage)
+		})
+	})
+
+	t.Run("Project", func(t *testing.T) {
+		t.Parallel()
+
+		type name struct {
+			Name string
+		}
+
+		repo := newEntityRepo()
+		_ = repo.Create(ctx, testdata.DefaultEntity)
+		_ = repo.Create(ctx, testdata.RandomEntity())
+
+		var all []name
+		err := repo.Project(ctx, q.Query{}, &all)
+		assert.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		var names []name
+		err = repo.Project(ctx, q.Where("name").Is(testdata.DefaultEntity.Name), &names)
+		assert.NoError(t, err)
+		assert.Equal(t, []name{{Name: testdata.DefaultEntity.Name}}, names)
+
+		err = repo.Project(ctx, q.Query{}, names)
+		assert.ErrorIs(t, err, ErrStorage, "dst has to be a pointer to a slice")
+
+		type title struct {
+			Title string `db:"name"`
+		}
+
+		var titles []title
+		err = repo.Project(ctx, q.Where("name").Is(testdata.DefaultEntity.Name), &titles)
+		assert.NoError(t, err)
+		assert.Equal(t, []title{{Title: testdata.DefaultEntity.Name}}, titles, "fields are mapped by column")
+
+		type unknown struct {
+			Name string `db:"title"`
+		}
+
+		var unknowns []unknown
+		err = repo.Project(ctx, q.Query{}, &unknowns)
+		assert.ErrorIs(t, err, ErrStorage, "entity has no column title")
+	})
+
 	t.Run("DeleteByID", func(t *testing.T) {
 		t.Parallel()
 
@@ -1068,6 +1433,32 @@ func TestSuite(
 		})
 	})
 
+	t.Run("DeleteBy", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newEntityRepo()
+		_ = repo.Create(ctx, testdata.DefaultEntity)
+		_ = repo.Create(ctx, testdata.Entity{ID: testdata.EntityID(uuid.New().String()), Name: testdata.DefaultEntity.Name})
+		_ = repo.Create(ctx, testdata.RandomEntity())
+
+		err := repo.DeleteBy(ctx, q.Where("name").Is(testdata.DefaultEntity.Name))
+		assert.NoError(t, err)
+
+		count, err := repo.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		err = repo.DeleteBy(ctx, q.Where("name").Is("non-existent"))
+		assert.NoError(t, err, "deleting nothing is not an error")
+
+		err = repo.DeleteBy(ctx, q.Where("name").Is(nil))
+		assert.ErrorIs(t, err, ErrStorage)
+
+		count, err = repo.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
 	t.Run("DeleteAll", func(t *testing.T) {
 		t.Parallel()
 
@@ -1104,6 +1495,93 @@ func TestSuite(
 		assert.Equal(t, 0, c)
 	})
 
+	t.Run("History", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("record changes", func(t *testing.T) {
+			t.Parallel()
+
+			repo, ok := newEntityRepo(WithHistory()).(HistoryReader[testdata.Entity, testdata.EntityID])
+			if !ok {
+				t.Skip("repository does not implement HistoryReader")
+			}
+
+			entityRepo := repo.(Repository[testdata.Entity, testdata.EntityID]) //nolint:forcetypeassert // created as such
+			userCtx := context.WithValue(ctx, ctxUserID, "some-user")
+
+			entity := testdata.RandomEntity()
+			err := entityRepo.Create(userCtx, entity)
+			assert.NoError(t, err)
+
+			updated := entity
+			updated.Name = gofakeit.Name()
+			err = entityRepo.Save(userCtx, updated)
+			assert.NoError(t, err)
+
+			err = entityRepo.Delete(ctx, updated)
+			assert.NoError(t, err)
+
+			changes, err := repo.History(ctx, entity.ID)
+			assert.NoError(t, err)
+			assert.Len(t, changes, 3)
+
+			assert.Equal(t, OperationCreate, changes[0].Operation)
+			assert.Equal(t, entity.ID, changes[0].EntityID)
+			assert.Nil(t, changes[0].Old)
+			assert.Equal(t, entity, *changes[0].New)
+			assert.Equal(t, "some-user", changes[0].ChangedBy)
+			assert.NotEmpty(t, changes[0].ChangedAt)
+
+			assert.Equal(t, OperationUpdate, changes[1].Operation)
+			assert.Equal(t, entity, *changes[1].Old)
+			assert.Equal(t, updated, *changes[1].New)
+
+			assert.Equal(t, OperationDelete, changes[2].Operation)
+			assert.Equal(t, updated, *changes[2].Old)
+			assert.Nil(t, changes[2].New)
+			assert.Empty(t, changes[2].ChangedBy, "no user in ctx")
+		})
+
+		t.Run("bulk changes", func(t *testing.T) {
+			t.Parallel()
+
+			repo, ok := newEntityRepo(WithHistory()).(HistoryReader[testdata.Entity, testdata.EntityID])
+			if !ok {
+				t.Skip("repository does not implement HistoryReader")
+			}
+
+			entityRepo := repo.(Repository[testdata.Entity, testdata.EntityID]) //nolint:forcetypeassert // created as such
+
+			e0, e1 := testdata.RandomEntity(), testdata.RandomEntity()
+			err := entityRepo.SaveAll(ctx, []testdata.Entity{e0, e1})
+			assert.NoError(t, err)
+
+			err = entityRepo.DeleteAll(ctx)
+			assert.NoError(t, err)
+
+			for _, e := range []testdata.Entity{e0, e1} {
+				changes, err := repo.History(ctx, e.ID)
+				assert.NoError(t, err)
+				assert.Len(t, changes, 2)
+				assert.Equal(t, OperationCreate, changes[0].Operation)
+				assert.Equal(t, OperationDelete, changes[1].Operation)
+			}
+		})
+
+		t.Run("no history recorded", func(t *testing.T) {
+			t.Parallel()
+
+			repo, ok := newEntityRepo().(HistoryReader[testdata.Entity, testdata.EntityID])
+			if !ok {
+				t.Skip("repository does not implement HistoryReader")
+			}
+
+			changes, err := repo.History(ctx, testdata.DefaultEntity.ID)
+			assert.ErrorIs(t, err, ErrStorage)
+			assert.Empty(t, changes)
+		})
+	})
+
 	t.Run("AllIter", func(t *testing.T) {
 		t.Parallel()
 
@@ -1154,6 +1632,178 @@ func TestSuite(
 		})
 	})
 
+	t.Run("AllByIter", func(t *testing.T) {
+		t.Parallel()
+
+		newRepo := func() Repository[testdata.EntityWithIntPK, testdata.EntityIDInt] {
+			repo := newEntityRepoInt()
+
+			err := repo.AddAll(ctx, []testdata.EntityWithIntPK{
+				{ID: 1, UintID: 3, Name: "a"},
+				{ID: 2, UintID: 1, Name: "b"},
+				{ID: 3, UintID: 2, Name: "a"},
+				{ID: 4, UintID: 4, Name: "a"},
+			})
+			assert.NoError(t, err)
+
+			return repo
+		}
+
+		t.Run("filter, order, and limit", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+			query := q.Where("name").Is("a").OrderBy("uint_id").Descending().Limit(2)
+
+			ids := []testdata.EntityIDInt{}
+
+			for e, err := range repo.AllByIter(ctx, query).Next() {
+				assert.NoError(t, err)
+
+				ids = append(ids, e.ID)
+			}
+
+			assert.Equal(t, []testdata.EntityIDInt{4, 1}, ids)
+
+			all, err := repo.AllBy(ctx, query.Offset(1))
+			assert.NoError(t, err)
+			assert.Len(t, all, 2)
+			assert.Equal(t, testdata.EntityIDInt(1), all[0].ID, "same order as the iterator")
+		})
+
+		t.Run("stop early", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+
+			for range 3 { // iterators release their resources, so they can be used again and again
+				for _, err := range repo.AllByIter(ctx, q.Query{}.OrderBy("id").Ascending()).Next() {
+					assert.NoError(t, err)
+					break
+				}
+			}
+
+			err := repo.Save(ctx, testdata.EntityWithIntPK{ID: 5, Name: "c"})
+			assert.NoError(t, err, "repository is still usable after stopping an iterator early")
+
+			count, err := repo.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, 5, count)
+		})
+
+		t.Run("invalid query", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+
+			for _, err := range repo.AllByIter(ctx, q.Where("name").Is(nil)).Next() {
+				assert.ErrorIs(t, err, ErrStorage)
+			}
+		})
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		t.Parallel()
+
+		newRepo := func(opts ...Option) Repository[testdata.EntityWithNamePK, string] {
+			repo := newEntityRepoOtherPk(append([]Option{WithIDField("Name")}, opts...)...)
+
+			err := repo.AddAll(ctx, []testdata.EntityWithNamePK{
+				{Name: "Alice Smith", Description: "admin"},
+				{Name: "Bob Admin", Description: "smith"},
+				{Name: "Carol Jones", Description: "support"},
+			})
+			assert.NoError(t, err)
+
+			return repo
+		}
+
+		names := func(entities []testdata.EntityWithNamePK) []string {
+			result := make([]string, len(entities))
+			for i, e := range entities {
+				result[i] = e.Name
+			}
+
+			return result
+		}
+
+		t.Run("all words have to match", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+
+			all, err := repo.Search(ctx, "SMITH, alice")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Alice Smith"}, names(all))
+
+			all, err = repo.Search(ctx, "smith")
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{"Alice Smith", "Bob Admin"}, names(all))
+
+			all, err = repo.Search(ctx, "nobody")
+			assert.NoError(t, err)
+			assert.Empty(t, all)
+		})
+
+		t.Run("fields", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+
+			all, err := repo.Search(ctx, "smith", "description")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Bob Admin"}, names(all))
+		})
+
+		t.Run("weights", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo(WithSearchFields(map[string]SearchWeight{
+				"name":        SearchWeightA,
+				"description": SearchWeightD,
+			}))
+
+			all, err := repo.Search(ctx, "admin")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Bob Admin", "Alice Smith"}, names(all))
+
+			repo = newRepo(WithSearchFields(map[string]SearchWeight{
+				"name":        SearchWeightD,
+				"description": SearchWeightA,
+			}))
+
+			all, err = repo.Search(ctx, "admin")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Alice Smith", "Bob Admin"}, names(all))
+		})
+
+		t.Run("combine with query", func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo()
+
+			all, err := repo.AllBy(ctx, q.Where("description").Is("smith").Search("smith"))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Bob Admin"}, names(all))
+
+			count, err := repo.CountBy(ctx, q.Search("smith"))
+			assert.NoError(t, err)
+			assert.Equal(t, 2, count)
+
+			all, err = repo.AllBy(ctx, q.Search("smith").OrderBy("name").Descending().Limit(1))
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Bob Admin"}, names(all))
+		})
+
+		t.Run("invalid weight", func(t *testing.T) {
+			t.Parallel()
+
+			assert.Panics(t, func() {
+				newEntityRepoOtherPk(WithIDField("Name"), WithSearchFields(map[string]SearchWeight{"name": "E"}))
+			})
+		})
+	})
+
 	t.Run("Concurrently", func(t *testing.T) {
 		t.Parallel()
 
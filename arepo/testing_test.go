@@ -1,8 +1,10 @@
 package arepo_test
 
 import (
+	"sync/atomic"
 	"testing"
 
+	"github.com/brianvoe/gofakeit/v6"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/go-arrower/arrower/arepo"
@@ -119,3 +121,15 @@ func TestTestAssertions_Total(t *testing.T) {
 		assert.False(t, pass)
 	})
 }
+
+func TestTestConformance(t *testing.T) {
+	t.Parallel()
+
+	var lastID atomic.Int64
+
+	// a custom repository, as a third party would write it
+	arepo.TestConformance(t,
+		func() arepo.Repository[User, UserID] { return NewUserMemoryRepository() },
+		func() User { return User{ID: UserID(lastID.Add(1)), Login: gofakeit.Email()} },
+	)
+}
@@ -0,0 +1,152 @@
+package arepo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/georgysavva/scany/v2/dbscan"
+)
+
+const (
+	fieldCreatedAt = "CreatedAt"
+	fieldUpdatedAt = "UpdatedAt"
+	fieldCreatedBy = "CreatedBy"
+	fieldUpdatedBy = "UpdatedBy"
+)
+
+// WithTimestamps sets the fields CreatedAt and UpdatedAt to the current time on every write,
+// and the fields CreatedBy and UpdatedBy to the id of the logged-in user, see auth.CurrentUserID.
+// Only the fields the entity has are set. The times have to be a time.Time or *time.Time,
+// the users a string or *string.
+//
+// CreatedAt and CreatedBy are set, when an entity is created, and kept as they are on updates.
+// The entity passed to a write is a copy, so read the entity again to see the values.
+func WithTimestamps() Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(interface{ enableTimestamps() }); ok {
+			repo.enableTimestamps()
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithTimestamps is not supported by this repository", errInvalidOption)
+	}
+}
+
+// WithClock sets the clock used for WithTimestamps and WithHistory.
+// Use it in tests to get predictable times.
+func WithClock(now func() time.Time) Option {
+	return func(rawRepo any) error {
+		if repo, ok := rawRepo.(interface{ setClock(now func() time.Time) }); ok && now != nil {
+			repo.setClock(now)
+			return nil
+		}
+
+		return fmt.Errorf("%w: WithClock is not supported by this repository", errInvalidOption)
+	}
+}
+
+// timestamps is embedded in the repositories supporting WithTimestamps and WithClock.
+type timestamps struct {
+	stampEntities bool
+	now           func() time.Time
+}
+
+func (t *timestamps) enableTimestamps() {
+	t.stampEntities = true
+}
+
+func (t *timestamps) setClock(now func() time.Time) {
+	t.now = now
+}
+
+func (t *timestamps) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+
+	return t.now()
+}
+
+// stamp returns entity with the timestamp and user fields set to now and the logged-in user,
+// if WithTimestamps is used. If created is false, CreatedAt and CreatedBy are not changed.
+// All entities of one write are stamped with the same time.
+func stamp[E any](ctx context.Context, t *timestamps, now time.Time, entity E, created bool) E {
+	if !t.stampEntities {
+		return entity
+	}
+
+	user := currentUserID(ctx)
+	v := reflect.ValueOf(&entity).Elem()
+
+	if created {
+		setStampField(v.FieldByName(fieldCreatedAt), now)
+		setStampField(v.FieldByName(fieldCreatedBy), user)
+	}
+
+	setStampField(v.FieldByName(fieldUpdatedAt), now)
+	setStampField(v.FieldByName(fieldUpdatedBy), user)
+
+	return entity
+}
+
+// keepCreated returns entity with CreatedAt and CreatedBy taken from existing,
+// if WithTimestamps is used.
+func keepCreated[E any](t *timestamps, entity E, existing E) E {
+	if !t.stampEntities {
+		return entity
+	}
+
+	v := reflect.ValueOf(&entity).Elem()
+	old := reflect.ValueOf(existing)
+
+	for _, name := range []string{fieldCreatedAt, fieldCreatedBy} {
+		if f := v.FieldByName(name); f.IsValid() && f.CanSet() {
+			f.Set(old.FieldByName(name))
+		}
+	}
+
+	return entity
+}
+
+// setStampField sets f to value, if f is of the type or a pointer to the type of value.
+func setStampField[T any](f reflect.Value, value T) {
+	if !f.IsValid() || !f.CanSet() {
+		return
+	}
+
+	val := reflect.ValueOf(value)
+
+	switch {
+	case f.Kind() == reflect.Pointer && val.Type().ConvertibleTo(f.Type().Elem()):
+		ptr := reflect.New(f.Type().Elem())
+		ptr.Elem().Set(val.Convert(f.Type().Elem()))
+		f.Set(ptr)
+	case f.Kind() != reflect.Pointer && val.Type().ConvertibleTo(f.Type()) && f.Kind() == val.Kind():
+		f.Set(val.Convert(f.Type()))
+	}
+}
+
+// createdColumns returns the quoted columns of CreatedAt and CreatedBy, if E has them.
+// They are not changed when an entity is updated.
+func createdColumns[E any]() []string {
+	entityType := reflect.TypeFor[E]()
+	columns := []string{}
+
+	for _, name := range []string{fieldCreatedAt, fieldCreatedBy} {
+		f, ok := entityType.FieldByName(name)
+		if !ok {
+			continue
+		}
+
+		column, _ := parseDBTag(f)
+		if column == "" {
+			column = dbscan.SnakeCaseMapper(f.Name)
+		}
+
+		columns = append(columns, quoteIdent(column))
+	}
+
+	return columns
+}
@@ -0,0 +1,121 @@
+package arepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arepo"
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+type stampedEntity struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	CreatedBy string
+	UpdatedBy string
+}
+
+func TestMemoryRepository_WithTimestamps(t *testing.T) {
+	t.Parallel()
+
+	testTimestamps(t, func(opts ...arepo.Option) arepo.Repository[stampedEntity, string] {
+		return arepo.NewMemoryRepository[stampedEntity, string](opts...)
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Panics(t, func() {
+			arepo.NewMemoryRepository[stampedEntity, string](arepo.WithClock(nil))
+		})
+	})
+}
+
+// testTimestamps ensures all repositories set the timestamps in the same way.
+func testTimestamps(t *testing.T, newRepo func(opts ...arepo.Option) arepo.Repository[stampedEntity, string]) {
+	t.Helper()
+
+	clock := func(start time.Time) func() time.Time {
+		now := start
+
+		return func() time.Time {
+			now = now.Add(time.Hour)
+			return now
+		}
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.WithValue(t.Context(), ctx2.CTXKey("auth.user_id"), "creator")
+	otherCtx := context.WithValue(t.Context(), ctx2.CTXKey("auth.user_id"), "updater")
+
+	t.Run("create and update", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(arepo.WithTimestamps(), arepo.WithClock(clock(start)))
+		entity := stampedEntity{ID: uuid.NewString(), Name: "created"}
+
+		err := repo.Create(ctx, entity)
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(ctx, entity.ID)
+		assert.NoError(t, err)
+		assert.True(t, start.Add(time.Hour).Equal(got.CreatedAt))
+		assert.True(t, start.Add(time.Hour).Equal(*got.UpdatedAt))
+		assert.Equal(t, "creator", got.CreatedBy)
+		assert.Equal(t, "creator", got.UpdatedBy)
+
+		entity.Name = "updated"
+		err = repo.Update(otherCtx, entity)
+		assert.NoError(t, err)
+
+		got, err = repo.FindByID(ctx, entity.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "updated", got.Name)
+		assert.True(t, start.Add(time.Hour).Equal(got.CreatedAt), "creation time is kept")
+		assert.True(t, start.Add(2*time.Hour).Equal(*got.UpdatedAt))
+		assert.Equal(t, "creator", got.CreatedBy, "creator is kept")
+		assert.Equal(t, "updater", got.UpdatedBy)
+	})
+
+	t.Run("save", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo(arepo.WithTimestamps(), arepo.WithClock(clock(start)))
+		entities := []stampedEntity{{ID: uuid.NewString()}, {ID: uuid.NewString()}}
+
+		err := repo.SaveAll(ctx, entities)
+		assert.NoError(t, err)
+
+		err = repo.Save(otherCtx, entities[0])
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(ctx, entities[0].ID)
+		assert.NoError(t, err)
+		assert.True(t, start.Add(time.Hour).Equal(got.CreatedAt), "creation time is kept")
+		assert.True(t, start.Add(2*time.Hour).Equal(*got.UpdatedAt))
+		assert.Equal(t, "creator", got.CreatedBy)
+		assert.Equal(t, "updater", got.UpdatedBy)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		repo := newRepo()
+		entity := stampedEntity{ID: uuid.NewString()}
+
+		err := repo.Create(ctx, entity)
+		assert.NoError(t, err)
+
+		got, err := repo.FindByID(ctx, entity.ID)
+		assert.NoError(t, err)
+		assert.True(t, got.CreatedAt.IsZero())
+		assert.Nil(t, got.UpdatedAt)
+		assert.Empty(t, got.CreatedBy)
+	})
+}
@@ -0,0 +1,30 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBCmd(t *testing.T) {
+	t.Parallel()
+
+	t.Run("list sub commands", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := executeCommand(testArrowerCLI(), "db")
+		assert.NoError(t, err)
+		assert.Contains(t, output, "migrate")
+		assert.Contains(t, output, "rollback")
+		assert.Contains(t, output, "status")
+	})
+
+	t.Run("migrate has dry-run", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := executeCommand(testArrowerCLI(), "db", "migrate", "--help")
+		assert.NoError(t, err)
+		assert.Contains(t, output, "--dry-run")
+		assert.Contains(t, output, "--source")
+	})
+}
@@ -29,6 +29,7 @@ func newGenerateCmd() *cobra.Command {
 	cmd.AddCommand(newGenerateCommand())
 	cmd.AddCommand(newGenerateQuery())
 	cmd.AddCommand(newGenerateJob())
+	cmd.AddCommand(newGenerateSchema())
 
 	return cmd
 }
@@ -171,3 +172,34 @@ func newGenerateJob() *cobra.Command {
 		},
 	}
 }
+
+func newGenerateSchema() *cobra.Command {
+	var idField string
+
+	cmd := &cobra.Command{
+		Use:   "schema <package> <entity>",
+		Short: "Print the CREATE TABLE statements of an entity, as the arepo.PostgresRepository maps it",
+		Example: "  arrower generate schema contexts/admin/internal/domain Product\n" +
+			"  arrower generate schema contexts/admin/internal/domain Product --id SKU > migration.up.sql",
+		Args: cobra.ExactArgs(2), //nolint:mnd // package and entity
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			sql, err := generate.Schema(cmd.Context(), path, args[0], args[1], idField)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), sql)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&idField, "id", "", "name of the ID field of the entity, see arepo.WithIDField")
+
+	return cmd
+}
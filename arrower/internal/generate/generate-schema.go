@@ -0,0 +1,94 @@
+package generate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Schema returns the CREATE TABLE statements for the entity typeName in the package pkgDir,
+// see arepo.CreateTableSQL. The pkgDir is relative to calledFromPath, the root of the module.
+// If idField is not empty, it is used as the ID field of the entity.
+//
+// The entity type is only known to the compiler, so Schema runs a temporary program inside the module.
+func Schema(ctx context.Context, calledFromPath string, pkgDir string, typeName string, idField string) (string, error) {
+	if pkgDir == "" || typeName == "" {
+		return "", ErrInvalidArguments
+	}
+
+	b, err := os.ReadFile(filepath.Join(calledFromPath, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("could not read go.mod file: %w", err)
+	}
+
+	file, err := modfile.Parse("go.mod", b, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not parse go.mod file: %w", err)
+	}
+
+	program := bytes.Buffer{}
+
+	err = schemaTemplate.Execute(&program, map[string]string{
+		"PkgPath": path.Join(file.Module.Mod.Path, filepath.ToSlash(filepath.Clean(pkgDir))),
+		"Type":    typeName,
+		"IDField": idField,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not render schema program: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(calledFromPath, "arrower-schema-")
+	if err != nil {
+		return "", fmt.Errorf("could not create schema program: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = os.WriteFile(filepath.Join(dir, "main.go"), program.Bytes(), 0o600)
+	if err != nil {
+		return "", fmt.Errorf("could not create schema program: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "go", "run", "./"+filepath.Base(dir))
+	cmd.Dir = calledFromPath
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("could not generate schema of %s.%s: %w: %s",
+			pkgDir, typeName, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+var schemaTemplate = template.Must(template.New("schema").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-arrower/arrower/arepo"
+
+	entity "{{ .PkgPath }}"
+)
+
+func main() {
+	sql, err := arepo.CreateTableSQL[entity.{{ .Type }}]({{ if .IDField }}arepo.WithIDField("{{ .IDField }}"){{ end }})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(sql)
+}
+`))
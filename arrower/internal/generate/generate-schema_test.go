@@ -0,0 +1,41 @@
+//go:build integration
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/arrower/internal/generate"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+
+	const moduleRoot = "../../.." // the arrower module itself has entities to generate the schema for
+
+	t.Run("generate schema", func(t *testing.T) {
+		t.Parallel()
+
+		sql, err := generate.Schema(t.Context(), moduleRoot, "arepo/testdata", "EntityWithNamePK", "Name")
+		assert.NoError(t, err)
+		assert.Contains(t, sql, "CREATE TABLE IF NOT EXISTS entitywithnamepk")
+		assert.Contains(t, sql, `"name"        TEXT PRIMARY KEY`)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		t.Parallel()
+
+		sql, err := generate.Schema(t.Context(), moduleRoot, "arepo/testdata", "NonExisting", "")
+		assert.Error(t, err)
+		assert.Empty(t, sql)
+	})
+
+	t.Run("missing arguments", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := generate.Schema(t.Context(), moduleRoot, "", "", "")
+		assert.ErrorIs(t, err, generate.ErrInvalidArguments)
+	})
+}
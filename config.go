@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
@@ -50,13 +51,14 @@ type (
 	}
 
 	Postgres struct {
-		User     string        `json:"user"     mapstructure:"user"`
-		Password secret.Secret `json:"-"        mapstructure:"password,squash"`
-		Database string        `json:"database" mapstructure:"database"`
-		Host     string        `json:"host"     mapstructure:"host"`
-		Port     int           `json:"port"     mapstructure:"port"`
-		SSLMode  string        `json:"sslMode"  mapstructure:"ssl_mode"`
-		MaxConns int           `json:"maxConns" mapstructure:"max_conns"`
+		User               string        `json:"user"     mapstructure:"user"`
+		Password           secret.Secret `json:"-"        mapstructure:"password,squash"`
+		Database           string        `json:"database" mapstructure:"database"`
+		Host               string        `json:"host"     mapstructure:"host"`
+		Port               int           `json:"port"     mapstructure:"port"`
+		SSLMode            string        `json:"sslMode"  mapstructure:"ssl_mode"`
+		MaxConns           int           `json:"maxConns" mapstructure:"max_conns"`
+		SlowQueryThreshold time.Duration `json:"slowQueryThreshold" mapstructure:"slow_query_threshold"`
 	}
 
 	OTEL struct {
@@ -91,6 +93,7 @@ func DefaultViper() *Viper {
 	vip.SetDefault("postgres.port", 5432)
 	vip.SetDefault("postgres.ssl_mode", "disable")
 	vip.SetDefault("postgres.max_conns", 10)
+	vip.SetDefault("postgres.slow_query_threshold", "200ms")
 
 	vip.SetDefault("otel.host", "localhost")
 	vip.SetDefault("otel.port", 4317)
@@ -111,7 +114,10 @@ type Viper struct {
 
 //nolint:cyclop
 func (vip *Viper) Unmarshal(rawVal any, _ ...viper.DecoderConfigOption) error {
-	err := vip.Viper.Unmarshal(rawVal, viper.DecodeHook(allowedEnvironmentHookFunc()))
+	err := vip.Viper.Unmarshal(rawVal, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		allowedEnvironmentHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+	)))
 	if err != nil {
 		return fmt.Errorf("%w: could not decode configuration into struct: %v", errConfigLoadFailed, err)
 	}
@@ -2,6 +2,7 @@ package arrower_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -35,6 +36,7 @@ func TestDefaultViper(t *testing.T) {
 	assert.Equal(t, 5432, vip.GetInt("postgres.port"))
 	assert.Equal(t, "disable", vip.GetString("postgres.ssl_mode"))
 	assert.Equal(t, 10, vip.GetInt("postgres.max_conns"))
+	assert.Equal(t, 200*time.Millisecond, vip.GetDuration("postgres.slow_query_threshold"))
 
 	assert.Equal(t, "localhost", vip.GetString("otel.host"))
 	assert.Equal(t, 4317, vip.GetInt("otel.port"))
@@ -90,6 +92,19 @@ func TestDefaultViper_CustomTypes(t *testing.T) {
 		assert.Equal(t, "my-db-secret", conf.Postgres.Password.Secret())
 	})
 
+	t.Run("unmarshal duration", func(t *testing.T) {
+		t.Parallel()
+
+		vip := arrower.DefaultViper()
+		vip.Set("postgres.slow_query_threshold", "1s")
+
+		conf := arrower.Config{}
+
+		err := vip.Unmarshal(&conf)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second, conf.Postgres.SlowQueryThreshold)
+	})
+
 	t.Run("custom config", func(t *testing.T) {
 		t.Parallel()
 
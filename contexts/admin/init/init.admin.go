@@ -84,6 +84,10 @@ func ensureRequiredDependencies(di *arrower.Container) error {
 		return fmt.Errorf("%w: settings", arrower.ErrMissingDependency)
 	}
 
+	if di.UseCases == nil {
+		return fmt.Errorf("%w: use cases", arrower.ErrMissingDependency)
+	}
+
 	return nil
 }
 
@@ -115,7 +119,7 @@ func setupAdminContext(ctx context.Context, di *arrower.Container) (*AdminContex
 		jobRepository: jobRepository,
 
 		jobsController:     web.NewJobsController(logger, di.Settings, appDI, jobRepository, models.New(di.PGx)),
-		routesController:   web.NewRoutesController(di.WebRouter),
+		routesController:   web.NewRoutesController(di.WebRouter, di.UseCases),
 		settingsController: web.NewSettingsController(),
 		logsController: web.NewLogsController(
 			logger,
@@ -155,28 +159,28 @@ func setupAdminContext(ctx context.Context, di *arrower.Container) (*AdminContex
 
 func setupApplication(di *arrower.Container, jobRepository jobs.Repository) application.App {
 	return application.App{
-		PruneJobHistory: app.NewInstrumentedRequest(di.TraceProvider, di.MeterProvider, di.Logger,
+		PruneJobHistory: app.RegisterRequest(di.UseCases,
 			application.NewPruneJobHistoryRequestHandler(models.New(di.PGx)),
 		),
-		VacuumJobTable: app.NewInstrumentedRequest(di.TraceProvider, di.MeterProvider, di.Logger,
+		VacuumJobTable: app.RegisterRequest(di.UseCases,
 			application.NewVacuumJobTableRequestHandler(di.PGx),
 		),
-		DeleteJob: app.NewInstrumentedCommand(di.TraceProvider, di.MeterProvider, di.Logger,
+		DeleteJob: app.RegisterCommand(di.UseCases,
 			application.NewDeleteJobCommandHandler(jobRepository),
 		),
-		GetQueue: app.NewInstrumentedQuery(di.TraceProvider, di.MeterProvider, di.Logger,
+		GetQueue: app.RegisterQuery(di.UseCases,
 			application.NewGetQueueQueryHandler(jobRepository),
 		),
-		GetWorkers: app.NewInstrumentedQuery(di.TraceProvider, di.MeterProvider, di.Logger,
+		GetWorkers: app.RegisterQuery(di.UseCases,
 			application.NewGetWorkersQueryHandler(jobRepository),
 		),
-		JobTypesForQueue: app.NewInstrumentedQuery(di.TraceProvider, di.MeterProvider, di.Logger,
+		JobTypesForQueue: app.RegisterQuery(di.UseCases,
 			application.NewJobTypesForQueueQueryHandler(models.New(di.PGx)),
 		),
-		ListAllQueues: app.NewInstrumentedQuery(di.TraceProvider, di.MeterProvider, di.Logger,
+		ListAllQueues: app.RegisterQuery(di.UseCases,
 			application.NewListAllQueuesQueryHandler(jobRepository),
 		),
-		ScheduleJobs: app.NewInstrumentedCommand(di.TraceProvider, di.MeterProvider, di.Logger,
+		ScheduleJobs: app.RegisterCommand(di.UseCases,
 			application.NewScheduleJobsCommandHandler(models.New(di.PGx)),
 		),
 	}
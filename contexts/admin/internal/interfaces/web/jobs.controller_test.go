@@ -221,7 +221,7 @@ func TestJobsController_ScheduleJobs(t *testing.T) {
 					reqBody.Set("priority", "-32768") // max int16 + 1; controller transforms *=-1 into linux priorities
 					return reqBody
 				}(),
-				errContains: `'Priority' failed on the 'max' tag`,
+				errContains: `Priority: must be at most`,
 			},
 			"runAt date format wrong 0": {
 				body: func() url.Values {
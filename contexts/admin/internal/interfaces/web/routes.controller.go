@@ -9,14 +9,17 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/go-arrower/arrower/app"
 )
 
-func NewRoutesController(echo *echo.Echo) *RoutesController {
-	return &RoutesController{echo: echo}
+func NewRoutesController(echo *echo.Echo, useCases *app.Registry) *RoutesController {
+	return &RoutesController{echo: echo, useCases: useCases}
 }
 
 type RoutesController struct {
-	echo *echo.Echo
+	echo     *echo.Echo
+	useCases *app.Registry
 }
 
 func (ctrl *RoutesController) Index() func(e echo.Context) error {
@@ -68,8 +71,9 @@ func (ctrl *RoutesController) Index() func(e echo.Context) error {
 		}
 
 		return c.Render(http.StatusOK, "routes.index", echo.Map{
-			"Flashes": nil,
-			"Routes":  routes,
+			"Flashes":  nil,
+			"Routes":   routes,
+			"UseCases": ctrl.useCases.UseCases(),
 		})
 	}
 }
@@ -92,7 +92,8 @@ func NewAuthContext(di *arrower.Container) (*AuthContext, error) {
 		VerifyUser: app.NewInstrumentedCommand(di.TraceProvider, di.MeterProvider, logger,
 			application.NewVerifyUserCommandHandler(repo)),
 		BlockUser: app.NewInstrumentedRequest(di.TraceProvider, di.MeterProvider, logger,
-			application.NewBlockUserRequestHandler(repo)),
+			app.NewAuthorizedRequest(logger, di.MeterProvider, app.Superuser[application.BlockUserRequest],
+				application.NewBlockUserRequestHandler(repo))),
 	}
 
 	userController := web.NewUserController(uc, webRoutes, []byte("secret"))
@@ -5,13 +5,13 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 
+	"github.com/go-arrower/arrower/app"
 	"github.com/go-arrower/arrower/contexts/auth"
 	"github.com/go-arrower/arrower/contexts/auth/internal/application"
 	"github.com/go-arrower/arrower/contexts/auth/internal/domain"
@@ -81,18 +81,11 @@ func (ctrl *UserController) Login() func(echo.Context) error {
 
 		response, err := ctrl.app.LoginUser.H(c.Request().Context(), loginUser.LoginUserRequest)
 		if err != nil {
-			valErrs := make(map[string]string)
-
-			var validationErrors validator.ValidationErrors
-
-			if !errors.As(err, &validationErrors) {
+			valErrs := app.FormErrors(err)
+			if !errors.Is(err, app.ErrValidation) {
 				valErrs["LoginEmail"] = "Invalid user name"
 			}
 
-			for _, e := range validationErrors {
-				valErrs[e.StructField()] = e.Translate(nil)
-			}
-
 			return c.Render(http.StatusOK, "auth=>=>login", map[string]any{
 				"Errors":     valErrs,
 				"LoginEmail": loginUser.LoginEmail,
@@ -273,18 +266,11 @@ func (ctrl *UserController) Register() func(echo.Context) error { //nolint:funle
 
 		response, err := ctrl.app.RegisterUser.H(c.Request().Context(), newUser)
 		if err != nil {
-			valErrs := make(map[string]string)
-
-			var validationErrors validator.ValidationErrors
-
-			if !errors.As(err, &validationErrors) {
+			valErrs := app.FormErrors(err)
+			if !errors.Is(err, app.ErrValidation) {
 				valErrs["RegisterEmail"] = "Invalid user name"
 			}
 
-			for _, e := range validationErrors {
-				valErrs[e.StructField()] = e.Translate(nil)
-			}
-
 			return c.Render(http.StatusOK, "auth=>=>create", map[string]any{
 				"Title":         "Registrieren",
 				"Errors":        valErrs,
@@ -389,19 +375,11 @@ func (ctrl *UserController) Store() func(echo.Context) error {
 
 		err := ctrl.app.NewUser.H(c.Request().Context(), newUser)
 		if err != nil {
-			valErrs := make(map[string]string)
-
+			valErrs := app.FormErrors(err)
 			if errors.Is(err, domain.ErrUserAlreadyExists) {
 				valErrs["Email"] = "User already exists"
 			}
 
-			var validationErrors validator.ValidationErrors
-			if !errors.As(err, &validationErrors) {
-				for _, e := range validationErrors {
-					valErrs[e.StructField()] = e.Translate(nil)
-				}
-			}
-
 			return c.Render(http.StatusOK, "new", map[string]any{
 				"Errors": valErrs,
 				"Email":  newUser.Email,
@@ -420,6 +398,10 @@ func (ctrl *UserController) BlockUser() {
 			UserID:     domain.ID(c.Param("userID")),
 			SetBlocked: &true,
 		})
+		if errors.Is(err, app.ErrForbidden) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
@@ -439,6 +421,10 @@ func (ctrl *UserController) UnBlockUser() {
 			UserID:     domain.ID(c.Param("userID")),
 			SetBlocked: &false,
 		})
+		if errors.Is(err, app.ErrForbidden) {
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		}
+
 		if err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
@@ -37,6 +37,7 @@ import (
 
 	"github.com/go-arrower/arrower/alog"
 	"github.com/go-arrower/arrower/alog/logging"
+	"github.com/go-arrower/arrower/app"
 	"github.com/go-arrower/arrower/contexts/auth"
 	"github.com/go-arrower/arrower/jobs"
 	"github.com/go-arrower/arrower/postgres"
@@ -63,8 +64,14 @@ type Container struct {
 	AdminRouter *echo.Group
 	WebRenderer *renderer.EchoRenderer
 
+	// APIEndpoints mounts use cases on the APIRouter and documents them at /api/openapi.json, see app.MountRequest.
+	APIEndpoints *app.Endpoints
+
 	RootCmd *cobra.Command
 
+	// UseCases holds the use cases of all Contexts and instruments them, see app.Registry.
+	UseCases *app.Registry
+
 	ArrowerQueue jobs.Queue
 	DefaultQueue jobs.Queue
 
@@ -191,6 +198,8 @@ func InitialiseDefaultDependencies(
 		}
 	}
 
+	var pgHandler *postgres.Handler
+
 	{ // postgres
 		if conf.Postgres != (Postgres{}) {
 			mf := migrations
@@ -207,12 +216,23 @@ func InitialiseDefaultDependencies(
 				SSLMode:    conf.Postgres.SSLMode,
 				MaxConns:   conf.Postgres.MaxConns,
 				Migrations: mf,
+				MigrationSources: []postgres.MigrationSource{
+					postgres.ArrowerAuthMigrations,
+				},
+
+				SlowQueryThreshold: conf.Postgres.SlowQueryThreshold,
 			}, dc.TraceProvider)
 			if err != nil {
 				return nil, fmt.Errorf("could not connect to postgres: %w", err)
 			}
 
+			err = pg.RegisterMetrics(dc.MeterProvider)
+			if err != nil {
+				return nil, fmt.Errorf("could not register postgres metrics: %w", err)
+			}
+
 			dc.PGx = pg.PGx
+			pgHandler = pg
 
 			dc.Settings = setting.NewPostgresSettings(dc.PGx)
 		}
@@ -233,9 +253,15 @@ func InitialiseDefaultDependencies(
 		}
 
 		dc.Logger = logger
+
+		if pgHandler != nil {
+			pgHandler.LogSlowQueries(logger.WithGroup("arrower.postgres"))
+		}
 		slog.SetDefault(dc.Logger.(*slog.Logger)) //nolint:forcetypeassert
 	}
 
+	dc.UseCases = app.NewRegistry(app.Instrumented(dc.TraceProvider, dc.MeterProvider, dc.Logger))
+
 	{ // web routers
 		router := echo.New()
 		router.HideBanner = true
@@ -310,6 +336,13 @@ func InitialiseDefaultDependencies(
 		// todo enable adain. Is disabled because pgx could be null or session not present...
 
 		dc.APIRouter = router.Group("/api") // todo add api middleware
+		dc.APIRouter.Use(app.IdempotencyKeyMiddleware)
+
+		dc.APIEndpoints = app.NewEndpoints(dc.APIRouter)
+		dc.APIRouter.GET("/openapi.json", dc.APIEndpoints.OpenAPIHandler(app.OpenAPIInfo{
+			Title:   conf.ApplicationName,
+			Version: "v1",
+		}))
 	}
 
 	{ // jobs
@@ -84,6 +84,7 @@ func NewPostgresJobs(
 		scheduler:          nil, // has to be set after all opts have benn applied
 		shutdownWorkerPool: nil,
 		groupWorkerPool:    nil,
+		txHooks:            &sync.Map{},
 		mu:                 sync.Mutex{},
 		schedules:          []schedule{},
 		hasStarted:         false,
@@ -145,6 +146,9 @@ type PostgresJobsHandler struct {
 	shutdownWorkerPool context.CancelFunc
 	groupWorkerPool    *errgroup.Group
 
+	// txHooks are the postgres.TxHooks of succeeded jobs, by job id, until the transaction of the job is committed.
+	txHooks *sync.Map
+
 	mu                sync.Mutex
 	schedules         []schedule
 	startTimer        *time.Timer
@@ -527,6 +531,8 @@ func (h *PostgresJobsHandler) gueWorkerAdapter(workerFn JobFunc) gue.WorkFunc {
 		ctx = context.WithValue(ctx, CTXJobID, job.ID.String())
 		ctx = context.WithValue(ctx, postgres.CtxTX, txHandle)
 
+		ctx, hooks := postgres.WithTxHooks(ctx)
+
 		if payload.Ctx.UserID != "" {
 			ctx = context.WithValue(ctx, auth.CtxUserID, payload.Ctx.UserID)
 		}
@@ -553,6 +559,8 @@ func (h *PostgresJobsHandler) gueWorkerAdapter(workerFn JobFunc) gue.WorkFunc {
 					return fmt.Errorf("%w: could not roll back to savepoint: %v", ErrJobFuncFailed, err)
 				}
 
+				hooks.RolledBack(ctx)
+
 				return fmt.Errorf("%w: %v", ErrJobFuncFailed, jobErr)
 			}
 		}
@@ -562,6 +570,8 @@ func (h *PostgresJobsHandler) gueWorkerAdapter(workerFn JobFunc) gue.WorkFunc {
 			return fmt.Errorf("%w: could not release savepoint: %v", ErrJobFuncFailed, err)
 		}
 
+		h.txHooks.Store(job.ID.String(), hooks) // called by finishJobTx, after the job is committed
+
 		return nil
 	}
 }
@@ -628,7 +638,10 @@ func (h *PostgresJobsHandler) startWorkers() error {
 		workers, err := gue.NewWorkerPool(h.gueClient, h.gueWorkMap, h.poolSize,
 			gue.WithPoolQueue(h.queue), gue.WithPoolPollInterval(h.pollInterval),
 			gue.WithPoolHooksJobLocked(recordStartedJobsToHistory(h.logger, h.queries, h.gitHash)),
-			gue.WithPoolHooksJobDone(recordFinishedJobsToHistory(h.logger, h.queries)),
+			gue.WithPoolHooksJobDone(
+				recordFinishedJobsToHistory(h.logger, h.queries),
+				finishJobTx(h.logger, h.txHooks), // last, as it commits the transaction of the job
+			),
 			gue.WithPoolID(h.poolName),
 			gue.WithPoolLogger(h.gueLogger), gue.WithPoolMeter(h.meter), gue.WithPoolTracer(h.tracer),
 			gue.WithPoolPollStrategy(pollStrategyToGue(h.pollStrategy)),
@@ -813,6 +826,37 @@ func recordGitHashToArgs(args []byte, gitHash string) ([]byte, error) {
 	return args, nil
 }
 
+// finishJobTx commits the transaction of a succeeded job and calls the hooks registered
+// by the worker with postgres.AfterCommit. gue has no hook after it commits the job itself,
+// its own Delete and Done do nothing for a job that is committed already.
+func finishJobTx(logger alog.Logger, txHooks *sync.Map) func(context.Context, *gue.Job, error) {
+	return func(ctx context.Context, job *gue.Job, jobErr error) {
+		stored, ok := txHooks.LoadAndDelete(job.ID.String())
+		if !ok || jobErr != nil {
+			return // failed jobs called the rollback hooks already
+		}
+
+		hooks, _ := stored.(*postgres.TxHooks)
+
+		err := job.Delete(ctx)
+		if err == nil {
+			err = job.Done(ctx)
+		}
+
+		if err != nil {
+			logger.InfoContext(ctx, "could not commit finished job",
+				slog.Group("job", logging.ID(job.ID.String())),
+				logging.Error(err),
+			)
+			hooks.RolledBack(ctx)
+
+			return
+		}
+
+		hooks.Committed(ctx)
+	}
+}
+
 // recordFinishedJobsToHistory takes each job that's finished and logs it into a new table,
 // so it's persisted for later analytics.
 // gue does delete finished jobs from the gue_jobs table, and the information would be lost otherwise.
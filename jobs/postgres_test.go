@@ -1211,6 +1211,53 @@ func TestPostgresJobs_Tx(t *testing.T) {
 		ensureJobTableRows(t, pg, 1+1) // one job + gueron
 		ensureJobHistoryTableRows(t, pg, 1)
 	})
+
+	t.Run("ensure after commit hooks run after the job is committed", func(t *testing.T) {
+		t.Parallel()
+
+		pg := pgHandler.NewTestDatabase()
+		jq, err := jobs.NewPostgresJobs(slog.New(slog.DiscardHandler), mnoop.NewMeterProvider(), tnoop.NewTracerProvider(), pg,
+			jobs.WithPollInterval(10*time.Millisecond),
+		)
+		assert.NoError(t, err)
+		_, err = pg.Exec(t.Context(), `CREATE TABLE IF NOT EXISTS some_table (id SERIAL PRIMARY KEY);`)
+		assert.NoError(t, err)
+
+		committed := make(chan int, 1)
+
+		err = jq.RegisterJobFunc(func(ctx context.Context, _ simpleJob) error {
+			return postgres.InTx(ctx, nil, func(ctx context.Context) error {
+				tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+				_, funcErr := tx.Exec(ctx, `INSERT INTO some_table(id) VALUES (DEFAULT);`)
+				assert.NoError(t, funcErr)
+
+				postgres.AfterCommit(ctx, func(ctx context.Context) {
+					var ids []int
+					_ = pgxscan.Select(ctx, pg, &ids, `SELECT * FROM some_table;`) // outside the tx of the job
+					committed <- len(ids)
+				})
+
+				return nil
+			})
+		})
+		assert.NoError(t, err)
+
+		err = jq.Enqueue(t.Context(), simpleJob{})
+		assert.NoError(t, err)
+
+		select {
+		case rows := <-committed:
+			assert.Equal(t, 1, rows, "changes of the job are committed")
+		case <-time.After(5 * time.Second):
+			t.Fatal("after commit hook not called")
+		}
+
+		err = jq.Shutdown(t.Context())
+		assert.NoError(t, err)
+
+		ensureJobHistoryTableRows(t, pg, 1)
+	})
 }
 
 func TestPostgresJobs_Instrumentation(t *testing.T) {
@@ -12,13 +12,18 @@ var ErrQueryFailed = errors.New("query failed")
 // BaseRepository can be used in repository implementations.
 // Because sqlc generates an own Queries struct for each corresponding models/db.go file,
 // a generic approach is used, so each repo can create a BaseRepository with the fitting *models.Queries type.
+//
+// Optionally, pass the Queries of read replicas, e.g. created from Handler.Replicas,
+// and use Reader for read only queries.
 type BaseRepository[T interface{ WithTx(tx pgx.Tx) T }] struct {
-	queries T
+	queries  T
+	replicas []T
 }
 
-func NewPostgresBaseRepository[T interface{ WithTx(tx pgx.Tx) T }](queries T) BaseRepository[T] {
+func NewPostgresBaseRepository[T interface{ WithTx(tx pgx.Tx) T }](queries T, replicas ...T) BaseRepository[T] {
 	return BaseRepository[T]{
-		queries: queries,
+		queries:  queries,
+		replicas: replicas,
 	}
 }
 
@@ -49,3 +54,27 @@ func (repo BaseRepository[T]) TX(ctx context.Context) T { //nolint:ireturn,lll /
 func (repo BaseRepository[T]) Conn() T { //nolint:ireturn // fp, as it is not recognised even with "generic" setting
 	return repo.queries
 }
+
+// Reader returns the models.Queries to read from.
+// Inside a transaction it is wrapped into the transaction in ctx,
+// otherwise a random replica is used, unless there are none or WithPrimary is set, see ReadPool.
+func (repo BaseRepository[T]) Reader(ctx context.Context) T { //nolint:ireturn // fp, as it is not recognised even with "generic" setting
+	if tx, ok := ctx.Value(CtxTX).(pgx.Tx); ok {
+		return repo.queries.WithTx(tx)
+	}
+
+	return pick(ctx, repo.queries, repo.replicas)
+}
+
+// Savepoint runs fn with the models.Queries wrapped into a savepoint of the transaction in ctx,
+// see InTx: if fn fails, only its changes are rolled back and the surrounding transaction can continue.
+// If no transaction is present in the given context, fn runs with the raw Queries struct.
+func (repo BaseRepository[T]) Savepoint(ctx context.Context, fn func(ctx context.Context, queries T) error) error {
+	if _, ok := ctx.Value(CtxTX).(pgx.Tx); !ok {
+		return fn(ctx, repo.queries)
+	}
+
+	return InTx(ctx, nil, func(ctx context.Context) error {
+		return fn(ctx, repo.TX(ctx))
+	})
+}
@@ -4,6 +4,7 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -81,4 +82,51 @@ func TestBaseRepository(t *testing.T) {
 
 		_ = tx.Rollback(ctx)
 	})
+
+	t.Run("read from replica", func(t *testing.T) {
+		t.Parallel()
+
+		primary, replica := models.New(pgHandler.PGx()), models.New(pgHandler.PGx())
+		repo := postgres.NewPostgresBaseRepository(primary, replica)
+
+		assert.Same(t, replica, repo.Reader(ctx))
+		assert.Same(t, primary, repo.Reader(postgres.WithPrimary(ctx)))
+
+		tx, err := pgHandler.PGx().Begin(ctx)
+		assert.NoError(t, err)
+
+		txCtx := context.WithValue(ctx, postgres.CtxTX, tx)
+		res, err := repo.Reader(txCtx).GetTrue(txCtx)
+		assert.NoError(t, err)
+		assert.True(t, res)
+
+		_ = tx.Rollback(ctx)
+	})
+	t.Run("savepoint", func(t *testing.T) {
+		t.Parallel()
+
+		repo := postgres.NewPostgresBaseRepository(models.New(pgHandler.PGx()))
+
+		tx, err := pgHandler.PGx().Begin(ctx)
+		assert.NoError(t, err)
+
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		txCtx := context.WithValue(ctx, postgres.CtxTX, tx)
+
+		err = repo.Savepoint(txCtx, func(ctx context.Context, queries *models.Queries) error {
+			_, err := queries.GetTrue(ctx)
+			assert.NoError(t, err)
+
+			_, err = repo.TX(ctx).GetTrue(ctx)
+			assert.NoError(t, err, "the savepoint is in the ctx")
+
+			return errors.New("fails") //nolint:err113 // the error does not matter
+		})
+		assert.Error(t, err)
+
+		res, err := repo.TX(txCtx).GetTrue(txCtx)
+		assert.NoError(t, err, "the surrounding transaction can continue")
+		assert.True(t, res)
+	})
 }
@@ -0,0 +1,273 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Lock is a named advisory lock, held by a single connection of the pool.
+// It is released, if Release is called or Postgres notices the connection is lost,
+// e.g. if the instance holding it crashes.
+type Lock struct {
+	mu   sync.Mutex
+	conn *pgxpool.Conn
+	name string
+	key  int64
+}
+
+// AcquireLock blocks until the lock with the given name is acquired or ctx is done.
+// The lock is held across all instances using the same database, so only one of them can hold it at a time.
+func AcquireLock(ctx context.Context, pool *pgxpool.Pool, name string) (*Lock, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not get connection for %s: %v", ErrLockFailed, name, err)
+	}
+
+	key := lockKey(name)
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, key)
+	if err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("%w: could not acquire %s: %v", ErrLockFailed, name, err)
+	}
+
+	return &Lock{mu: sync.Mutex{}, conn: conn, name: name, key: key}, nil
+}
+
+// TryLock acquires the lock with the given name, if it is free, and returns immediately.
+// If another instance holds the lock, the returned lock is nil and ok is false.
+func TryLock(ctx context.Context, pool *pgxpool.Pool, name string) (*Lock, bool, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: could not get connection for %s: %v", ErrLockFailed, name, err)
+	}
+
+	key := lockKey(name)
+
+	var acquired bool
+
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1);`, key).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Release()
+
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: could not acquire %s: %v", ErrLockFailed, name, err)
+		}
+
+		return nil, false, nil
+	}
+
+	return &Lock{mu: sync.Mutex{}, conn: conn, name: name, key: key}, true, nil
+}
+
+// WithLock runs fn while holding the lock with the given name.
+// It blocks until the lock is acquired, see AcquireLock, and releases it after fn returns.
+func WithLock(ctx context.Context, pool *pgxpool.Pool, name string, fn func(ctx context.Context) error) error {
+	lock, err := AcquireLock(ctx, pool, name)
+	if err != nil {
+		return err
+	}
+
+	defer lock.Release(context.WithoutCancel(ctx)) //nolint:errcheck // the lock is dropped with the connection on failure
+
+	return fn(ctx)
+}
+
+// Name returns the name of the lock.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Held reports if the lock is still held, by checking that its connection is alive.
+func (l *Lock) Held(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return false
+	}
+
+	return l.conn.Ping(ctx) == nil
+}
+
+// Release releases the lock and returns its connection to the pool.
+// Releasing a lock a second time does nothing.
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	conn := l.conn
+	l.conn = nil
+
+	_, err := conn.Exec(ctx, `SELECT pg_advisory_unlock($1);`, l.key)
+	if err != nil {
+		// close the connection, so postgres releases the lock and the connection is not reused while holding it
+		_ = conn.Hijack().Close(ctx)
+		return fmt.Errorf("%w: could not release %s: %v", ErrLockFailed, l.name, err)
+	}
+
+	conn.Release()
+
+	return nil
+}
+
+// lockKey maps the name of a lock to the key of the advisory lock in postgres.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+
+	return int64(h.Sum64()) //nolint:gosec // overflow is fine, the key only has to be stable
+}
+
+// DefaultLeaseDuration is how often a Leader renews its lease or tries to become the leader.
+const DefaultLeaseDuration = 5 * time.Second
+
+// LeaderOption configures a Leader.
+type LeaderOption func(*Leader)
+
+// WithLeaseDuration sets how often the leader checks it still holds the lock,
+// and how often the other instances try to become the leader.
+func WithLeaseDuration(d time.Duration) LeaderOption {
+	return func(l *Leader) {
+		if d > 0 {
+			l.lease = d
+		}
+	}
+}
+
+// OnElected sets fn to be called when the instance becomes the leader.
+// fn runs in its own goroutine and its ctx is cancelled, when the leadership is lost.
+func OnElected(fn func(ctx context.Context)) LeaderOption {
+	return func(l *Leader) {
+		l.onElected = fn
+	}
+}
+
+// OnDemoted sets fn to be called when the instance loses the leadership,
+// after the ctx of OnElected is cancelled and its fn has returned.
+func OnDemoted(fn func()) LeaderOption {
+	return func(l *Leader) {
+		l.onDemoted = fn
+	}
+}
+
+// Leader elects one instance of all instances using the same name as the leader, e.g.
+// to run cron jobs only once. It uses an advisory lock, see Lock.
+//
+// Postgres frees the lock only, when it notices the session of the leader is gone:
+// right away, if the leader closes its connection, e.g. on a crash of the process,
+// but only after the TCP keepalive of the server times out, e.g. tcp_keepalives_idle, if the network is lost.
+// Another instance takes over within one lease duration after that.
+// The old leader keeps running the fn of OnElected until its next check of the lock fails,
+// which takes up to two lease durations. If Postgres frees the lock earlier,
+// two instances act as the leader for a short time, so fn must be safe to run more than once.
+type Leader struct {
+	pool *pgxpool.Pool
+	name string
+
+	lease     time.Duration
+	onElected func(ctx context.Context)
+	onDemoted func()
+
+	mu     sync.RWMutex
+	leader bool
+}
+
+// NewLeader returns a Leader for the given name. Call Run to take part in the election.
+func NewLeader(pool *pgxpool.Pool, name string, opts ...LeaderOption) *Leader {
+	leader := &Leader{
+		pool:      pool,
+		name:      name,
+		lease:     DefaultLeaseDuration,
+		onElected: func(context.Context) {},
+		onDemoted: func() {},
+		mu:        sync.RWMutex{},
+		leader:    false,
+	}
+
+	for _, opt := range opts {
+		opt(leader)
+	}
+
+	return leader
+}
+
+// IsLeader reports if this instance is the leader right now.
+func (l *Leader) IsLeader() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.leader
+}
+
+// Run takes part in the election until ctx is done. Then the leadership is given up.
+// Errors, e.g. a lost connection, do not stop Run, the instance tries again after the lease duration.
+func (l *Leader) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.lease)
+	defer ticker.Stop()
+
+	for {
+		lock, ok, err := TryLock(ctx, l.pool, l.name)
+		if err == nil && ok {
+			l.lead(ctx, lock, ticker)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead calls the callbacks and renews the lease, until the lock is lost or ctx is done.
+func (l *Leader) lead(ctx context.Context, lock *Lock, ticker *time.Ticker) {
+	l.setLeader(true)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() { l.onElected(leaderCtx) })
+
+renew:
+	for {
+		select {
+		case <-ctx.Done():
+			break renew
+		case <-ticker.C:
+		}
+
+		pingCtx, cancelPing := context.WithTimeout(ctx, l.lease)
+		held := lock.Held(pingCtx)
+		cancelPing()
+
+		if !held {
+			break renew
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	_ = lock.Release(context.WithoutCancel(ctx))
+
+	l.setLeader(false)
+	l.onDemoted()
+}
+
+func (l *Leader) setLeader(leader bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.leader = leader
+}
@@ -0,0 +1,162 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestTryLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lock only once", func(t *testing.T) {
+		t.Parallel()
+
+		db := pgHandler.NewTestDatabase()
+
+		lock, ok, err := postgres.TryLock(t.Context(), db, "some-lock")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "some-lock", lock.Name())
+
+		other, ok, err := postgres.TryLock(t.Context(), db, "some-lock")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, other)
+
+		_, ok, _ = postgres.TryLock(t.Context(), db, "other-lock")
+		assert.True(t, ok, "other names are independent")
+
+		err = lock.Release(t.Context())
+		assert.NoError(t, err)
+		err = lock.Release(t.Context())
+		assert.NoError(t, err, "release twice")
+
+		_, ok, _ = postgres.TryLock(t.Context(), db, "some-lock")
+		assert.True(t, ok)
+	})
+}
+
+func TestAcquireLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wait until released", func(t *testing.T) {
+		t.Parallel()
+
+		db := pgHandler.NewTestDatabase()
+
+		lock, err := postgres.AcquireLock(t.Context(), db, "some-lock")
+		assert.NoError(t, err)
+		assert.True(t, lock.Held(t.Context()))
+
+		acquired := make(chan struct{})
+
+		go func() {
+			other, err := postgres.AcquireLock(t.Context(), db, "some-lock")
+			assert.NoError(t, err)
+			close(acquired)
+
+			_ = other.Release(t.Context())
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("lock acquired twice")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		_ = lock.Release(t.Context())
+		assert.False(t, lock.Held(t.Context()))
+
+		<-acquired
+	})
+
+	t.Run("stop waiting when ctx is done", func(t *testing.T) {
+		t.Parallel()
+
+		db := pgHandler.NewTestDatabase()
+
+		_, err := postgres.AcquireLock(t.Context(), db, "some-lock")
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err = postgres.AcquireLock(ctx, db, "some-lock")
+		assert.ErrorIs(t, err, postgres.ErrLockFailed)
+	})
+}
+
+func TestWithLock(t *testing.T) {
+	t.Parallel()
+
+	db := pgHandler.NewTestDatabase()
+
+	err := postgres.WithLock(t.Context(), db, "some-lock", func(ctx context.Context) error {
+		_, ok, err := postgres.TryLock(ctx, db, "some-lock")
+		assert.NoError(t, err)
+		assert.False(t, ok, "lock is held while fn runs")
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	_, ok, _ := postgres.TryLock(t.Context(), db, "some-lock")
+	assert.True(t, ok, "lock is released after fn")
+}
+
+func TestLeader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("elect one leader and fail over", func(t *testing.T) {
+		t.Parallel()
+
+		db := pgHandler.NewTestDatabase()
+
+		var elected, demoted atomic.Int32
+
+		newLeader := func() *postgres.Leader {
+			return postgres.NewLeader(db, "some-leader",
+				postgres.WithLeaseDuration(20*time.Millisecond),
+				postgres.OnElected(func(ctx context.Context) {
+					elected.Add(1)
+					<-ctx.Done()
+				}),
+				postgres.OnDemoted(func() { demoted.Add(1) }),
+			)
+		}
+
+		first, second := newLeader(), newLeader()
+
+		ctx, cancel := context.WithCancel(t.Context())
+		done := make(chan struct{})
+
+		go func() {
+			first.Run(ctx)
+			close(done)
+		}()
+
+		assert.Eventually(t, first.IsLeader, time.Second, 10*time.Millisecond)
+
+		go second.Run(t.Context())
+
+		time.Sleep(100 * time.Millisecond)
+		assert.False(t, second.IsLeader())
+		assert.Equal(t, int32(1), elected.Load())
+
+		cancel() // first instance shuts down
+		<-done
+
+		assert.False(t, first.IsLeader())
+		assert.Equal(t, int32(1), demoted.Load())
+		assert.Eventually(t, second.IsLeader, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(2), elected.Load())
+	})
+}
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+const defaultMigrationsDir = "migrations"
+
+// MigrationSource is a set of migrations, e.g. the ones of arrower, a Context, or the app.
+// Each source keeps track of its version in its own table, so sources can evolve independently.
+type MigrationSource struct {
+	// Name identifies the source, e.g. "auth". Its version is kept in the table schema_migrations_<name>.
+	// Without a name, the table schema_migrations is used, same as for Config.Migrations.
+	Name string
+	// FS contains the migration files in the directory Dir,
+	// named like golang-migrate expects them, e.g. 000001_create_user.up.sql and 000001_create_user.down.sql.
+	FS fs.FS
+	// Dir defaults to "migrations".
+	Dir string
+}
+
+// Table returns the version table of the source.
+func (s MigrationSource) Table() string {
+	if s.Name == "" {
+		return postgres.DefaultMigrationsTable
+	}
+
+	return postgres.DefaultMigrationsTable + "_" + s.Name
+}
+
+func (s MigrationSource) dir() string {
+	if s.Dir == "" {
+		return defaultMigrationsDir
+	}
+
+	return s.Dir
+}
+
+// Migration is a single migration of a MigrationSource.
+type Migration struct {
+	Source  string
+	Version uint
+	Name    string
+	// SQL is the statement of the up migration.
+	SQL string
+}
+
+// MigrationStatus is the state of a MigrationSource in the database.
+type MigrationStatus struct {
+	Source string
+	Table  string
+	// Version is the last applied migration, zero if none is applied.
+	Version uint
+	// Dirty is true, if the last migration failed and the database needs to be fixed manually.
+	Dirty bool
+	// Latest is the version of the newest migration in the source.
+	Latest uint
+	// Pending is the number of migrations not applied yet.
+	Pending int
+}
+
+// Migrator migrates the database with the migrations of multiple sources.
+type Migrator struct {
+	db      *sql.DB
+	sources []MigrationSource
+}
+
+// NewMigrator returns a Migrator for the sources. Up applies them in the given order.
+func NewMigrator(db *sql.DB, sources ...MigrationSource) *Migrator {
+	return &Migrator{db: db, sources: sources}
+}
+
+// Up applies all pending migrations of all sources.
+func (m *Migrator) Up(ctx context.Context) error {
+	for _, src := range m.sources {
+		err := m.run(ctx, src, func(mig *migrate.Migrate) error { return mig.Up() })
+		if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("%w: could not migrate up %s: %w", ErrMigrationFailed, src.Table(), err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the last steps migrations of the source with the given name.
+// If steps is zero or less, all migrations of the source are rolled back.
+func (m *Migrator) Down(ctx context.Context, name string, steps int) error {
+	src, err := m.source(name)
+	if err != nil {
+		return err
+	}
+
+	err = m.run(ctx, src, func(mig *migrate.Migrate) error {
+		if steps <= 0 {
+			return mig.Down()
+		}
+
+		return mig.Steps(-steps)
+	})
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%w: could not migrate down %s: %w", ErrMigrationFailed, src.Table(), err)
+	}
+
+	return nil
+}
+
+// Goto migrates the source with the given name up or down to the version.
+func (m *Migrator) Goto(ctx context.Context, name string, version uint) error {
+	src, err := m.source(name)
+	if err != nil {
+		return err
+	}
+
+	err = m.run(ctx, src, func(mig *migrate.Migrate) error { return mig.Migrate(version) })
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("%w: could not migrate %s to version %d: %w", ErrMigrationFailed, src.Table(), version, err)
+	}
+
+	return nil
+}
+
+// Status returns the state of all sources.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	status := make([]MigrationStatus, 0, len(m.sources))
+
+	for _, src := range m.sources {
+		migrations, current, dirty, err := m.migrations(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+
+		s := MigrationStatus{
+			Source:  src.Name,
+			Table:   src.Table(),
+			Version: current,
+			Dirty:   dirty,
+			Latest:  0,
+			Pending: 0,
+		}
+
+		for _, migration := range migrations {
+			s.Latest = migration.Version
+			if migration.Version > current {
+				s.Pending++
+			}
+		}
+
+		status = append(status, s)
+	}
+
+	return status, nil
+}
+
+// Pending returns the migrations Up would apply, in the order it would apply them.
+// Use it for a dry-run.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	pending := []Migration{}
+
+	for _, src := range m.sources {
+		migrations, current, _, err := m.migrations(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, migration := range migrations {
+			if migration.Version > current {
+				pending = append(pending, migration)
+			}
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) source(name string) (MigrationSource, error) {
+	for _, src := range m.sources {
+		if src.Name == name {
+			return src, nil
+		}
+	}
+
+	return MigrationSource{}, fmt.Errorf("%w: unknown migration source: %q", ErrMigrationFailed, name)
+}
+
+// run calls fn with a migrate instance of src. The connection is released afterwards,
+// but the db of the Migrator stays open.
+func (m *Migrator) run(ctx context.Context, src MigrationSource, fn func(mig *migrate.Migrate) error) error {
+	srcDriver, dbDriver, err := m.drivers(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	mig, err := migrate.NewWithInstance("iofs", srcDriver, "postgres", dbDriver)
+	if err != nil {
+		_ = srcDriver.Close()
+		_ = dbDriver.Close()
+
+		return fmt.Errorf("%w: could not create new migration instance: %v", ErrMigrationFailed, err)
+	}
+
+	defer mig.Close()
+
+	return fn(mig)
+}
+
+// migrations returns all migrations of src, the current version, and if the database is dirty.
+func (m *Migrator) migrations(ctx context.Context, src MigrationSource) ([]Migration, uint, bool, error) {
+	srcDriver, dbDriver, err := m.drivers(ctx, src)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	defer srcDriver.Close()
+	defer dbDriver.Close()
+
+	version, dirty, err := dbDriver.Version()
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("%w: could not get version of %s: %v", ErrMigrationFailed, src.Table(), err)
+	}
+
+	current := uint(0)
+	if version != database.NilVersion {
+		current = uint(version) //nolint:gosec // versions are not negative
+	}
+
+	migrations := []Migration{}
+
+	next, err := srcDriver.First()
+	for err == nil {
+		migration, readErr := readMigration(srcDriver, src.Name, next)
+		if readErr != nil {
+			return nil, 0, false, readErr
+		}
+
+		migrations = append(migrations, migration)
+		next, err = srcDriver.Next(next)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, false, fmt.Errorf("%w: could not read migrations of %s: %v", ErrMigrationFailed, src.Table(), err)
+	}
+
+	return migrations, current, dirty, nil
+}
+
+func readMigration(srcDriver source.Driver, name string, version uint) (Migration, error) {
+	r, identifier, err := srcDriver.ReadUp(version)
+	if err != nil {
+		return Migration{}, fmt.Errorf("%w: could not read migration %d: %v", ErrMigrationFailed, version, err)
+	}
+	defer r.Close()
+
+	stmt, err := io.ReadAll(r)
+	if err != nil {
+		return Migration{}, fmt.Errorf("%w: could not read migration %d: %v", ErrMigrationFailed, version, err)
+	}
+
+	return Migration{Source: name, Version: version, Name: identifier, SQL: string(stmt)}, nil
+}
+
+func (m *Migrator) drivers(ctx context.Context, src MigrationSource) (source.Driver, database.Driver, error) { //nolint:ireturn,lll // drivers of golang-migrate
+	if src.FS == nil {
+		return nil, nil, fmt.Errorf("%w: no migration files given for %s", ErrMigrationFailed, src.Table())
+	}
+
+	srcDriver, err := iofs.New(src.FS, src.dir())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: could not create migration file driver: %v", ErrMigrationFailed, err)
+	}
+
+	// use a single connection instead of the db, so closing the driver does not close the db
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		_ = srcDriver.Close()
+		return nil, nil, fmt.Errorf("%w: could not get connection: %v", ErrMigrationFailed, err)
+	}
+
+	dbDriver, err := postgres.WithConnection(ctx, conn, &postgres.Config{MigrationsTable: src.Table()})
+	if err != nil {
+		_ = srcDriver.Close()
+		_ = conn.Close()
+
+		return nil, nil, fmt.Errorf("%w: could not get database driver: %v", ErrMigrationFailed, err)
+	}
+
+	return srcDriver, dbDriver, nil
+}
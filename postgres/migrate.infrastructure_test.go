@@ -0,0 +1,122 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestMigrator(t *testing.T) {
+	t.Parallel()
+
+	app := postgres.MigrationSource{
+		Name: "",
+		FS: fstest.MapFS{
+			"migrations/000001_a.up.sql":   {Data: []byte(`CREATE TABLE a(id INT);`)},
+			"migrations/000001_a.down.sql": {Data: []byte(`DROP TABLE a;`)},
+			"migrations/000002_b.up.sql":   {Data: []byte(`CREATE TABLE b(id INT);`)},
+			"migrations/000002_b.down.sql": {Data: []byte(`DROP TABLE b;`)},
+		},
+		Dir: "",
+	}
+	auth := postgres.MigrationSource{
+		Name: "auth",
+		FS: fstest.MapFS{
+			"000001_user.up.sql":   {Data: []byte(`CREATE TABLE auth_user(id INT);`)},
+			"000001_user.down.sql": {Data: []byte(`DROP TABLE auth_user;`)},
+		},
+		Dir: ".",
+	}
+
+	newMigrator := func(t *testing.T) *postgres.Migrator {
+		t.Helper()
+
+		db := stdlib.OpenDBFromPool(pgHandler.NewTestDatabase())
+		t.Cleanup(func() { _ = db.Close() })
+
+		return postgres.NewMigrator(db, app, auth)
+	}
+
+	t.Run("up", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := newMigrator(t)
+
+		err := migrator.Up(t.Context())
+		assert.NoError(t, err)
+
+		status, err := migrator.Status(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, []postgres.MigrationStatus{
+			{Source: "", Table: "schema_migrations", Version: 2, Dirty: false, Latest: 2, Pending: 0},
+			{Source: "auth", Table: "schema_migrations_auth", Version: 1, Dirty: false, Latest: 1, Pending: 0},
+		}, status)
+
+		err = migrator.Up(t.Context())
+		assert.NoError(t, err, "up to date is no error")
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := newMigrator(t)
+
+		pending, err := migrator.Pending(t.Context())
+		assert.NoError(t, err)
+		assert.Len(t, pending, 3)
+		assert.Equal(t, postgres.Migration{Source: "auth", Version: 1, Name: "user", SQL: `CREATE TABLE auth_user(id INT);`}, pending[2])
+
+		status, err := migrator.Status(t.Context())
+		assert.NoError(t, err)
+		assert.Equal(t, uint(0), status[0].Version, "nothing is applied")
+		assert.Equal(t, 2, status[0].Pending)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := newMigrator(t)
+		_ = migrator.Up(t.Context())
+
+		err := migrator.Down(t.Context(), "", 1)
+		assert.NoError(t, err)
+
+		status, _ := migrator.Status(t.Context())
+		assert.Equal(t, uint(1), status[0].Version)
+		assert.Equal(t, uint(1), status[1].Version, "other sources are not affected")
+
+		err = migrator.Down(t.Context(), "auth", 0)
+		assert.NoError(t, err)
+
+		status, _ = migrator.Status(t.Context())
+		assert.Equal(t, uint(0), status[1].Version)
+	})
+
+	t.Run("goto", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := newMigrator(t)
+
+		err := migrator.Goto(t.Context(), "", 1)
+		assert.NoError(t, err)
+
+		status, _ := migrator.Status(t.Context())
+		assert.Equal(t, uint(1), status[0].Version)
+		assert.Equal(t, 1, status[0].Pending)
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		t.Parallel()
+
+		migrator := newMigrator(t)
+
+		err := migrator.Down(t.Context(), "unknown", 1)
+		assert.ErrorIs(t, err, postgres.ErrMigrationFailed)
+	})
+}
@@ -0,0 +1,7 @@
+BEGIN;
+
+
+DROP TABLE IF EXISTS arrower.history;
+
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+
+CREATE TABLE IF NOT EXISTS arrower.history
+(
+    id         BIGSERIAL PRIMARY KEY,
+    entity     TEXT                     NOT NULL,
+    entity_id  TEXT                     NOT NULL,
+    operation  TEXT                     NOT NULL,
+    old_value  JSONB                             DEFAULT NULL,
+    new_value  JSONB                             DEFAULT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    changed_by TEXT                     NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS history_entity_idx ON arrower.history(entity, entity_id);
+
+
+COMMIT;
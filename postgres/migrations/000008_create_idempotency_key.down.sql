@@ -0,0 +1,7 @@
+BEGIN;
+
+
+DROP TABLE IF EXISTS arrower.idempotency_key;
+
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+
+CREATE TABLE IF NOT EXISTS arrower.idempotency_key
+(
+    key          TEXT                     NOT NULL,
+    command      TEXT                     NOT NULL,
+    user_id      TEXT                     NOT NULL DEFAULT '',
+    request_hash TEXT                     NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE          DEFAULT NULL,
+    result       JSONB                             DEFAULT NULL,
+    error        TEXT                              DEFAULT NULL,
+
+    PRIMARY KEY (key, command, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_created_at_idx ON arrower.idempotency_key(created_at);
+
+
+COMMIT;
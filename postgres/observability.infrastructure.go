@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+const (
+	ctxQueryStart     ctx2.CTXKey = "arrower.query.start"
+	ctxSlowQueryEntry ctx2.CTXKey = "arrower.query.slow"
+)
+
+// QueryLogger logs slow queries, e.g. an alog.Logger.
+type QueryLogger interface {
+	LogAttrs(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr)
+}
+
+// slowQueryLog logs queries taking longer than the threshold.
+// The logger is set after connecting, as it might write to the database itself, see LogSlowQueries.
+type slowQueryLog struct {
+	threshold time.Duration
+
+	mu     sync.RWMutex
+	logger QueryLogger
+}
+
+func (s *slowQueryLog) setLogger(logger QueryLogger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger = logger
+}
+
+func (s *slowQueryLog) start(ctx context.Context) context.Context {
+	if s == nil || s.threshold <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ctxQueryStart, time.Now())
+}
+
+func (s *slowQueryLog) end(ctx context.Context, sql string, args []any, err error) {
+	start, ok := ctx.Value(ctxQueryStart).(time.Time)
+	if !ok || ctx.Value(ctxSlowQueryEntry) != nil { // writing the entry must not log itself, if the logger uses the db
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < s.threshold {
+		return
+	}
+
+	s.mu.RLock()
+	logger := s.logger
+	s.mu.RUnlock()
+
+	if logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Any("args", redactArgs(args)),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", s.threshold),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("err", err.Error()))
+	}
+
+	logger.LogAttrs(context.WithValue(ctx, ctxSlowQueryEntry, true), slog.LevelWarn, "slow query", attrs...)
+}
+
+// redactArgs replaces the values of args with their types, so no personal data or secrets are logged.
+func redactArgs(args []any) []string {
+	s := make([]string, len(args))
+
+	for i, arg := range args {
+		if arg == nil {
+			s[i] = "NULL"
+			continue
+		}
+
+		s[i] = fmt.Sprintf("$%d=<%T>", i+1, arg)
+	}
+
+	return s
+}
+
+// LogSlowQueries logs all queries taking longer than Config.SlowQueryThreshold to logger,
+// with the SQL and the types of the arguments, but not their values.
+// Without a threshold, no queries are logged.
+func (h Handler) LogSlowQueries(logger QueryLogger) {
+	if h.slowQueries != nil {
+		h.slowQueries.setLogger(logger)
+	}
+}
+
+// RegisterMetrics reports the stats of the connection pools of the primary and the replicas via meterProvider,
+// so the number of connections, see Config.MaxConns, can be tuned.
+//
+//nolint:funlen // registering each instrument takes a few lines
+func (h Handler) RegisterMetrics(meterProvider metric.MeterProvider) error {
+	meter := meterProvider.Meter("arrower.postgres")
+
+	connections, err := meter.Int64ObservableGauge("db.client.connection.count",
+		metric.WithDescription("number of connections by state: idle or used"))
+	if err != nil {
+		return fmt.Errorf("could not create pool metric: %w", err)
+	}
+
+	maxConns, err := meter.Int64ObservableGauge("db.client.connection.max",
+		metric.WithDescription("maximum number of connections of the pool"))
+	if err != nil {
+		return fmt.Errorf("could not create pool metric: %w", err)
+	}
+
+	acquires, err := meter.Int64ObservableCounter("db.client.connection.acquires",
+		metric.WithDescription("number of connections acquired from the pool"))
+	if err != nil {
+		return fmt.Errorf("could not create pool metric: %w", err)
+	}
+
+	waits, err := meter.Int64ObservableCounter("db.client.connection.waits",
+		metric.WithDescription("number of acquires, that had to wait for a connection, as none was idle"))
+	if err != nil {
+		return fmt.Errorf("could not create pool metric: %w", err)
+	}
+
+	waitDuration, err := meter.Float64ObservableCounter("db.client.connection.wait_duration",
+		metric.WithDescription("total time spent waiting for a connection, as none was idle"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return fmt.Errorf("could not create pool metric: %w", err)
+	}
+
+	pools := map[string]*pgxpool.Pool{"primary": h.PGx}
+	for i, replica := range h.Replicas {
+		pools[h.replicaName(i)] = replica
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for name, pool := range pools {
+			stat := pool.Stat()
+			attr := attribute.String("db.client.connection.pool.name", name)
+
+			o.ObserveInt64(connections, int64(stat.IdleConns()),
+				metric.WithAttributes(attr, attribute.String("db.client.connection.state", "idle")))
+			o.ObserveInt64(connections, int64(stat.AcquiredConns()),
+				metric.WithAttributes(attr, attribute.String("db.client.connection.state", "used")))
+			o.ObserveInt64(maxConns, int64(stat.MaxConns()), metric.WithAttributes(attr))
+			o.ObserveInt64(acquires, stat.AcquireCount(), metric.WithAttributes(attr))
+			o.ObserveInt64(waits, stat.EmptyAcquireCount(), metric.WithAttributes(attr))
+			o.ObserveFloat64(waitDuration, stat.EmptyAcquireWaitTime().Seconds(), metric.WithAttributes(attr))
+		}
+
+		return nil
+	}, connections, maxConns, acquires, waits, waitDuration)
+	if err != nil {
+		return fmt.Errorf("could not register pool metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (h Handler) replicaName(i int) string {
+	if i < len(h.Config.Replicas) {
+		return "replica " + h.Config.Replicas[i]
+	}
+
+	return fmt.Sprintf("replica %d", i)
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func newHandler(t *testing.T, threshold time.Duration) *postgres.Handler {
+	t.Helper()
+
+	conf := pgHandler.PGx().Config().ConnConfig
+
+	handler, err := postgres.Connect(t.Context(), postgres.Config{
+		User:               conf.User,
+		Password:           conf.Password,
+		Database:           conf.Database,
+		Host:               conf.Host,
+		Port:               int(conf.Port),
+		SSLMode:            "disable",
+		MaxConns:           3,
+		SlowQueryThreshold: threshold,
+	}, noop.NewTracerProvider())
+	assert.NoError(t, err)
+
+	t.Cleanup(func() { _ = handler.Shutdown(t.Context()) })
+
+	return handler
+}
+
+func TestHandler_LogSlowQueries(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log slow query with redacted args", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t, 10*time.Millisecond)
+
+		buf := &bytes.Buffer{}
+		handler.LogSlowQueries(slog.New(slog.NewTextHandler(buf, nil)))
+
+		_, err := handler.PGx.Exec(t.Context(), `SELECT pg_sleep(0.05), $1::TEXT;`, "my-secret")
+		assert.NoError(t, err)
+
+		assert.Contains(t, buf.String(), "slow query")
+		assert.Contains(t, buf.String(), "pg_sleep")
+		assert.Contains(t, buf.String(), "$1=<string>")
+		assert.NotContains(t, buf.String(), "my-secret")
+	})
+
+	t.Run("ignore fast query", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t, time.Second)
+
+		buf := &bytes.Buffer{}
+		handler.LogSlowQueries(slog.New(slog.NewTextHandler(buf, nil)))
+
+		_, err := handler.PGx.Exec(t.Context(), `SELECT 1;`)
+		assert.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("disabled without threshold", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHandler(t, 0)
+
+		buf := &bytes.Buffer{}
+		handler.LogSlowQueries(slog.New(slog.NewTextHandler(buf, nil)))
+
+		_, err := handler.PGx.Exec(t.Context(), `SELECT pg_sleep(0.01);`)
+		assert.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestHandler_RegisterMetrics(t *testing.T) {
+	t.Parallel()
+
+	handler := newHandler(t, 0)
+
+	reader := sdkmetric.NewManualReader()
+
+	err := handler.RegisterMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	assert.NoError(t, err)
+
+	conn, err := handler.PGx.Acquire(t.Context())
+	assert.NoError(t, err)
+
+	defer conn.Release()
+
+	data := metricdata.ResourceMetrics{}
+	err = reader.Collect(t.Context(), &data)
+	assert.NoError(t, err)
+
+	names := map[string]metricdata.Aggregation{}
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		names[m.Name] = m.Data
+	}
+
+	assert.Contains(t, names, "db.client.connection.count")
+	assert.Contains(t, names, "db.client.connection.acquires")
+	assert.Contains(t, names, "db.client.connection.waits")
+	assert.Contains(t, names, "db.client.connection.wait_duration")
+
+	maxConns, ok := names["db.client.connection.max"].(metricdata.Gauge[int64])
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), maxConns.DataPoints[0].Value)
+}
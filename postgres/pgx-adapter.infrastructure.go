@@ -12,7 +12,10 @@ import (
 	ctx2 "github.com/go-arrower/arrower/ctx"
 )
 
-const spanKey ctx2.CTXKey = "otel_span"
+const (
+	spanKey     ctx2.CTXKey = "otel_span"
+	querySQLKey ctx2.CTXKey = "arrower.query.sql"
+)
 
 var (
 	_ pgx.QueryTracer    = (*pgxTraceAdapter)(nil)
@@ -23,7 +26,8 @@ var (
 )
 
 type pgxTraceAdapter struct {
-	tracer trace.Tracer
+	tracer      trace.Tracer
+	slowQueries *slowQueryLog
 }
 
 //nolint:spancheck // span is closed in TraceQueryEnd. No memory leak
@@ -42,10 +46,16 @@ func (p pgxTraceAdapter) TraceQueryStart(
 		attribute.StringSlice("db.query.parameters", anySliceToStrings(data.Args)),
 	))
 
-	return context.WithValue(ctx, spanKey, span)
+	ctx = context.WithValue(ctx, querySQLKey, data)
+
+	return context.WithValue(p.slowQueries.start(ctx), spanKey, span)
 }
 
 func (p pgxTraceAdapter) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	if query, ok := ctx.Value(querySQLKey).(pgx.TraceQueryStartData); ok {
+		p.slowQueries.end(ctx, query.SQL, query.Args, data.Err)
+	}
+
 	if span, ok := ctx.Value(spanKey).(trace.Span); ok {
 		span.SetAttributes(attribute.Int64("db.response.returned_rows", data.CommandTag.RowsAffected()))
 
@@ -9,10 +9,9 @@ import (
 	"io/fs"
 	"net"
 	"strconv"
+	"sync"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"go.opentelemetry.io/otel/trace"
@@ -26,21 +25,44 @@ const CtxTX ctx2.CTXKey = "arrower.tx"
 //go:embed migrations/*.sql
 var ArrowerDefaultMigrations embed.FS
 
+//go:embed migrations/auth/*.sql
+var authMigrations embed.FS
+
+// ArrowerAuthMigrations are the migrations of the auth Context.
+// They are applied after ArrowerDefaultMigrations and tracked in the table schema_migrations_auth.
+// For existing databases, ArrowerDefaultMigrations still create the auth schema in version 6,
+// the migrations here are idempotent, so both can be applied.
+var ArrowerAuthMigrations = MigrationSource{ //nolint:gochecknoglobals // same as the embedded files
+	Name: "auth",
+	FS:   authMigrations,
+	Dir:  "migrations/auth",
+}
+
 var (
 	ErrConnectionFailed = errors.New("connection failed")
 	ErrMigrationFailed  = errors.New("migration failed")
+	ErrLockFailed       = errors.New("lock failed")
 )
 
 // Config holds all values used to configure and connect to a postgres database.
 type Config struct {
+	// Migrations are applied by ConnectAndMigrate and tracked in the table schema_migrations.
 	Migrations fs.FS
-	User       string
-	Password   string
-	Database   string
-	SSLMode    string
-	Host       string
-	Port       int
-	MaxConns   int
+	// MigrationSources are applied by ConnectAndMigrate after the Migrations, e.g. the ones of each Context.
+	MigrationSources []MigrationSource
+	User             string
+	Password         string
+	Database         string
+	SSLMode          string
+	Host             string
+	Port             int
+	MaxConns         int
+	// Replicas are the hosts of read replicas of the database, as "host" or "host:port".
+	// All other settings are the same as for the primary, if the port is missing Port is used.
+	Replicas []string
+	// SlowQueryThreshold is the duration after which a query is logged as slow, see Handler.LogSlowQueries.
+	// Zero disables the logging.
+	SlowQueryThreshold time.Duration
 }
 
 func (c Config) toURL() string {
@@ -52,11 +74,92 @@ func (c Config) toURL() string {
 		c.User, c.Password, net.JoinHostPort(c.Host, strconv.Itoa(c.Port)), c.Database, c.SSLMode, c.MaxConns)
 }
 
-// Connect connects to a PostgreSQL database.
+// replica returns the config of the replica at host.
+func (c Config) replica(host string) (Config, error) {
+	h, p, err := net.SplitHostPort(host)
+	if err != nil { // no port given
+		c.Host = host
+		return c, nil //nolint:nilerr // use the port of the primary
+	}
+
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		return c, fmt.Errorf("%w: invalid port of replica %s: %v", ErrConnectionFailed, host, err)
+	}
+
+	c.Host = h
+	c.Port = port
+
+	return c, nil
+}
+
+// Connect connects to a PostgreSQL database and its read replicas.
 func Connect(ctx context.Context, pgConf Config, tracerProvider trace.TracerProvider) (*Handler, error) {
+	slowQueries := &slowQueryLog{threshold: pgConf.SlowQueryThreshold, mu: sync.RWMutex{}, logger: nil}
+
+	config, dbpool, err := connect(ctx, pgConf, tracerProvider, slowQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	connStr := stdlib.RegisterConnConfig(config.ConnConfig) // offer std SQL if a library would need it.
+
+	db, err := sql.Open("pgx", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not connect via the std lib registration: %v", ErrConnectionFailed, err)
+	}
+
+	replicas := make([]*pgxpool.Pool, 0, len(pgConf.Replicas))
+
+	for _, host := range pgConf.Replicas {
+		replica, err := connectReplica(ctx, pgConf, host, tracerProvider, slowQueries)
+		if err != nil {
+			_ = Handler{PGx: dbpool, Replicas: replicas}.Shutdown(ctx)
+			return nil, err
+		}
+
+		replicas = append(replicas, replica)
+	}
+
+	return &Handler{
+		PGx:         dbpool,
+		DB:          db,
+		Replicas:    replicas,
+		Config:      pgConf,
+		slowQueries: slowQueries,
+	}, nil
+}
+
+func connectReplica(
+	ctx context.Context,
+	pgConf Config,
+	host string,
+	tracerProvider trace.TracerProvider,
+	slowQueries *slowQueryLog,
+) (*pgxpool.Pool, error) {
+	replicaConf, err := pgConf.replica(host)
+	if err != nil {
+		return nil, err
+	}
+
+	_, replica, err := connect(ctx, replicaConf, tracerProvider, slowQueries)
+	if err != nil {
+		return nil, fmt.Errorf("replica %s: %w", host, err)
+	}
+
+	return replica, nil
+}
+
+// connect creates a connection pool and ensures the database is reachable.
+func connect(
+	ctx context.Context,
+	pgConf Config,
+	tracerProvider trace.TracerProvider,
+	slowQueries *slowQueryLog,
+) (*pgxpool.Config, *pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(pgConf.toURL())
 	if err != nil {
-		return nil, fmt.Errorf("%w: could not parse config: %v", ErrConnectionFailed, err)
+		return nil, nil, fmt.Errorf("%w: could not parse config: %v", ErrConnectionFailed, err)
 	}
 
 	// to list all runtime settings: SHOW ALL;
@@ -68,37 +171,33 @@ func Connect(ctx context.Context, pgConf Config, tracerProvider trace.TracerProv
 		"search_path": "public,arrower",
 	}
 	config.ConnConfig.Tracer = &pgxTraceAdapter{
-		tracer: tracerProvider.Tracer("arrower.pgx"),
+		tracer:      tracerProvider.Tracer("arrower.pgx"),
+		slowQueries: slowQueries,
 	}
 
 	dbpool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		return nil, fmt.Errorf("%w: could not connect: %v", ErrConnectionFailed, err)
+		return nil, nil, fmt.Errorf("%w: could not connect: %v", ErrConnectionFailed, err)
 	}
 
 	err = dbpool.Ping(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("%w: could not ping db: %v", ErrConnectionFailed, err)
-	}
-
-	connStr := stdlib.RegisterConnConfig(config.ConnConfig) // offer std SQL if a library would need it.
-
-	db, err := sql.Open("pgx", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("%w: could not connect via the std lib registration: %v", ErrConnectionFailed, err)
+		return nil, nil, fmt.Errorf("%w: could not ping db: %v", ErrConnectionFailed, err)
 	}
 
-	return &Handler{
-		PGx:    dbpool,
-		DB:     db,
-		Config: pgConf,
-	}, nil
+	return config, dbpool, nil
 }
 
 // ConnectAndMigrate connects to a PostgreSQL database and
 // runs all migrations to ensure that the schema is on the latest version.
+// The Migrations are applied first, then the MigrationSources in order.
 func ConnectAndMigrate(ctx context.Context, conf Config, tracerProvider trace.TracerProvider) (*Handler, error) {
-	if conf.Migrations == nil {
+	sources := conf.MigrationSources
+	if conf.Migrations != nil {
+		sources = append([]MigrationSource{{Name: "", FS: conf.Migrations, Dir: ""}}, sources...)
+	}
+
+	if len(sources) == 0 {
 		return nil, fmt.Errorf("%w: no migration files given", ErrMigrationFailed)
 	}
 
@@ -107,7 +206,7 @@ func ConnectAndMigrate(ctx context.Context, conf Config, tracerProvider trace.Tr
 		return nil, err
 	}
 
-	err = migrateUp(handler.DB, conf.Database, conf.Migrations)
+	err = NewMigrator(handler.DB, sources...).Up(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -115,39 +214,28 @@ func ConnectAndMigrate(ctx context.Context, conf Config, tracerProvider trace.Tr
 	return handler, nil
 }
 
-func migrateUp(db *sql.DB, dbName string, migrationsFS fs.FS) error {
-	fsDriver, err := iofs.New(migrationsFS, "migrations")
-	if err != nil {
-		return fmt.Errorf("%w: could not create migration file driver: %v", ErrMigrationFailed, err)
-	}
-
-	driver, err := postgres.WithInstance(db, &postgres.Config{}) // use default config
-	if err != nil {
-		return fmt.Errorf("%w: could not get database driver: %v", ErrMigrationFailed, err)
-	}
-
-	m, err := migrate.NewWithInstance("", fsDriver, dbName, driver)
-	if err != nil {
-		return fmt.Errorf("%w: could not create new migration instance: %v", ErrMigrationFailed, err)
-	}
-
-	err = m.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return fmt.Errorf("%w: could not migrate up: %v", ErrMigrationFailed, err)
-	}
+type Handler struct {
+	PGx *pgxpool.Pool
+	DB  *sql.DB // keep a sql.DB connection around for migration & integration tests, e.g. setting up test fixtures.
+	// Replicas are the connections to the read replicas, in the order of Config.Replicas.
+	Replicas []*pgxpool.Pool
+	Config   Config
 
-	return nil
+	slowQueries *slowQueryLog
 }
 
-type Handler struct {
-	PGx    *pgxpool.Pool
-	DB     *sql.DB // keep a sql.DB connection around for migration & integration tests, e.g. setting up test fixtures.
-	Config Config
+// Reader returns the connection to read from, see ReadPool.
+func (h Handler) Reader(ctx context.Context) *pgxpool.Pool {
+	return ReadPool(ctx, h.PGx, h.Replicas)
 }
 
 // Shutdown waits & closes all connections to PostgreSQL.
 func (h Handler) Shutdown(_ context.Context) error {
 	h.PGx.Close()
 
+	for _, replica := range h.Replicas {
+		replica.Close()
+	}
+
 	return nil
 }
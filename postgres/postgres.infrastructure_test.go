@@ -150,6 +150,9 @@ func TestConnectAndMigrate(t *testing.T) {
 					Password:   "secret",
 					Database:   "dbname_test",
 					Migrations: postgres.ArrowerDefaultMigrations,
+					MigrationSources: []postgres.MigrationSource{
+						postgres.ArrowerAuthMigrations,
+					},
 				}, noop.NewTracerProvider())
 				if err != nil {
 					return err
@@ -165,6 +168,7 @@ func TestConnectAndMigrate(t *testing.T) {
 		assert.NoError(t, err)
 		ensureMigrationsRun(t, pgHandler.PGx)
 		ensureMigrationFilesLoadedAndApplied(t, pgHandler.PGx)
+		ensureAuthMigrationsApplied(t, pgHandler.PGx)
 
 		_ = cleanup()
 	})
@@ -234,3 +238,23 @@ func ensureMigrationFilesLoadedAndApplied(t *testing.T, pgx *pgxpool.Pool) {
 	err := row.Scan(&funcExists)
 	assert.NoError(t, err)
 }
+
+func ensureAuthMigrationsApplied(t *testing.T, pgx *pgxpool.Pool) {
+	t.Helper()
+
+	row := pgx.QueryRow(t.Context(), `SELECT version FROM schema_migrations_auth;`)
+
+	var version int
+
+	err := row.Scan(&version)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	row = pgx.QueryRow(t.Context(), `SELECT to_regclass('auth.user') IS NOT NULL;`)
+
+	var exists bool
+
+	err = row.Scan(&exists)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+const ctxPrimary ctx2.CTXKey = "arrower.primary"
+
+// WithPrimary returns a context, in which all reads go to the primary.
+// Use it to read your own writes, as the replicas might not have received them yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxPrimary, true)
+}
+
+// UsePrimary reports if reads in ctx have to go to the primary.
+// This is the case inside a transaction, see CtxTX, or after WithPrimary.
+func UsePrimary(ctx context.Context) bool {
+	if _, ok := ctx.Value(CtxTX).(pgx.Tx); ok {
+		return true
+	}
+
+	primary, _ := ctx.Value(ctxPrimary).(bool)
+
+	return primary
+}
+
+// ReadPool returns the connection to read from:
+// a random one of the replicas or the primary, if there are no replicas or UsePrimary is true.
+// Writes always have to go to the primary.
+func ReadPool(ctx context.Context, primary *pgxpool.Pool, replicas []*pgxpool.Pool) *pgxpool.Pool {
+	return pick(ctx, primary, replicas)
+}
+
+func pick[T any](ctx context.Context, primary T, replicas []T) T { //nolint:ireturn // generic
+	if len(replicas) == 0 || UsePrimary(ctx) {
+		return primary
+	}
+
+	return replicas[rand.IntN(len(replicas))] //nolint:gosec // spread the load, no crypto required
+}
@@ -0,0 +1,52 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestReadPool(t *testing.T) {
+	t.Parallel()
+
+	primary, replica := &pgxpool.Pool{}, &pgxpool.Pool{}
+
+	t.Run("without replicas", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Same(t, primary, postgres.ReadPool(t.Context(), primary, nil))
+	})
+
+	t.Run("replica", func(t *testing.T) {
+		t.Parallel()
+
+		assert.False(t, postgres.UsePrimary(t.Context()))
+		assert.Same(t, replica, postgres.ReadPool(t.Context(), primary, []*pgxpool.Pool{replica}))
+	})
+
+	t.Run("force primary", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := postgres.WithPrimary(t.Context())
+
+		assert.True(t, postgres.UsePrimary(ctx))
+		assert.Same(t, primary, postgres.ReadPool(ctx, primary, []*pgxpool.Pool{replica}))
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.WithValue(t.Context(), postgres.CtxTX, pgx.Tx(nil))
+		assert.False(t, postgres.UsePrimary(ctx), "nil is no transaction")
+
+		ctx = context.WithValue(t.Context(), postgres.CtxTX, pgx.Tx(fakeTx{}))
+
+		assert.True(t, postgres.UsePrimary(ctx))
+		assert.Same(t, primary, postgres.ReadPool(ctx, primary, []*pgxpool.Pool{replica}))
+	})
+}
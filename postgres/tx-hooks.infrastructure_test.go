@@ -0,0 +1,160 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestAfterCommit(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("failed")
+
+	t.Run("without transaction", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+
+		postgres.AfterCommit(t.Context(), func(context.Context) { called = append(called, "commit") })
+		postgres.AfterRollback(t.Context(), func(context.Context) { called = append(called, "rollback") })
+
+		assert.Equal(t, []string{"commit"}, called)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+
+		err := postgres.InTx(t.Context(), fakeBeginner{}, func(ctx context.Context) error {
+			postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit 1") })
+			postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit 2") })
+			postgres.AfterRollback(ctx, func(context.Context) { called = append(called, "rollback") })
+
+			assert.Empty(t, called, "not before the commit")
+
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"commit 1", "commit 2"}, called)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+
+		err := postgres.InTx(t.Context(), fakeBeginner{}, func(ctx context.Context) error {
+			postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+			postgres.AfterRollback(ctx, func(context.Context) { called = append(called, "rollback") })
+
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, []string{"rollback"}, called)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+
+		assert.PanicsWithValue(t, "failed", func() {
+			_ = postgres.InTx(t.Context(), fakeBeginner{}, func(ctx context.Context) error {
+				postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+				postgres.AfterRollback(ctx, func(context.Context) { called = append(called, "rollback") })
+
+				panic("failed")
+			})
+		})
+		assert.Equal(t, []string{"rollback"}, called)
+	})
+
+	t.Run("savepoint", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+
+		err := postgres.InTx(t.Context(), fakeBeginner{}, func(ctx context.Context) error {
+			_ = postgres.InTx(ctx, nil, func(ctx context.Context) error {
+				postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "released") })
+				return nil
+			})
+
+			_ = postgres.InTx(ctx, nil, func(ctx context.Context) error {
+				postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+				postgres.AfterRollback(ctx, func(context.Context) { called = append(called, "rolled back") })
+
+				return errFailed
+			})
+
+			assert.Equal(t, []string{"rolled back"}, called, "commit hooks wait for the outer transaction")
+
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"rolled back", "released"}, called)
+	})
+
+	t.Run("transaction not started by InTx", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+		ctx := context.WithValue(t.Context(), postgres.CtxTX, pgx.Tx(fakeTx{}))
+
+		err := postgres.InTx(ctx, nil, func(ctx context.Context) error {
+			postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+			return nil
+		})
+		assert.NoError(t, err)
+
+		postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+
+		assert.Empty(t, called, "it is unknown when the transaction commits")
+	})
+
+	t.Run("with tx hooks", func(t *testing.T) {
+		t.Parallel()
+
+		called := []string{}
+		ctx := context.WithValue(t.Context(), postgres.CtxTX, pgx.Tx(fakeTx{}))
+		ctx, hooks := postgres.WithTxHooks(ctx)
+
+		err := postgres.InTx(ctx, nil, func(ctx context.Context) error {
+			postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "savepoint") })
+			return nil
+		})
+		assert.NoError(t, err)
+
+		postgres.AfterCommit(ctx, func(context.Context) { called = append(called, "commit") })
+		postgres.AfterRollback(ctx, func(context.Context) { called = append(called, "rollback") })
+
+		assert.Empty(t, called, "not before the owner of the transaction commits")
+
+		hooks.Committed(t.Context())
+
+		assert.Equal(t, []string{"savepoint", "commit"}, called)
+	})
+}
+
+// fakeBeginner starts transactions that do nothing.
+type fakeBeginner struct{}
+
+func (fakeBeginner) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// fakeTx marks a context as being inside a transaction, that does nothing.
+type fakeTx struct {
+	pgx.Tx
+}
+
+func (fakeTx) Begin(context.Context) (pgx.Tx, error) { return fakeTx{}, nil }
+func (fakeTx) Commit(context.Context) error          { return nil }
+func (fakeTx) Rollback(context.Context) error        { return nil }
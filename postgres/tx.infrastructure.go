@@ -0,0 +1,260 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	ctx2 "github.com/go-arrower/arrower/ctx"
+)
+
+const (
+	// DefaultTxAttempts is how often InTx runs a transaction that fails with a serialization failure or deadlock.
+	DefaultTxAttempts = 3
+	// DefaultTxBackoff is the time InTx waits before the first retry, it doubles with each retry.
+	DefaultTxBackoff = 10 * time.Millisecond
+)
+
+// Beginner starts transactions, e.g. a *pgxpool.Pool.
+type Beginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// TxOption configures a transaction started by InTx.
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	options  pgx.TxOptions
+	attempts int
+	backoff  time.Duration
+}
+
+// WithIsolation sets the isolation level of the transaction, e.g. pgx.Serializable or pgx.RepeatableRead.
+// Without it, the default of the database is used, which is read committed.
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *txConfig) {
+		c.options.IsoLevel = level
+	}
+}
+
+// WithReadOnly starts a read only transaction. Writes fail.
+func WithReadOnly() TxOption {
+	return func(c *txConfig) {
+		c.options.AccessMode = pgx.ReadOnly
+	}
+}
+
+// WithRetry sets how often a transaction is run, if it fails with a serialization failure or deadlock,
+// and the time to wait before the first retry. The time doubles with each retry.
+// Use one attempt to disable retries.
+func WithRetry(attempts int, backoff time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.attempts = max(attempts, 1)
+		c.backoff = max(backoff, 0)
+	}
+}
+
+// InTx runs fn in a transaction, which is passed to fn in ctx, see CtxTX.
+// The transaction is committed, if fn succeeds, and rolled back, if fn fails or panics.
+//
+// If the transaction fails with a serialization failure or deadlock (SQLSTATE 40001 or 40P01),
+// it is run again with an exponential backoff, see WithRetry. So fn must not have side effects
+// outside the database, or they must be safe to repeat.
+//
+// If ctx contains a transaction already, a savepoint in that transaction is used instead of a new one,
+// and db can be nil. This allows nesting transactional code:
+//   - only the outermost InTx commits, an inner one releases its savepoint
+//   - an inner InTx that fails rolls back to its savepoint and returns the error,
+//     the outer one decides to return the error as well, so all changes are rolled back,
+//     or to handle it and continue with its own changes.
+//   - the options of the outermost InTx apply, an inner one does not retry,
+//     as a serialization failure aborts the whole transaction.
+func InTx(ctx context.Context, db Beginner, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if outer, ok := ctx.Value(CtxTX).(pgx.Tx); ok {
+		return runTx(ctx, outer.Begin, fn) // pgx uses a savepoint for nested transactions
+	}
+
+	config := txConfig{attempts: DefaultTxAttempts, backoff: DefaultTxBackoff}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	begin := func(ctx context.Context) (pgx.Tx, error) {
+		return db.BeginTx(ctx, config.options)
+	}
+
+	backoff := config.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, begin, fn)
+		if err == nil || attempt >= config.attempts || !isRetryable(err) {
+			return err
+		}
+
+		jitter := time.Duration(rand.Int64N(int64(backoff)/2 + 1)) //nolint:gosec // spread retries, no crypto required
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff + jitter):
+		}
+
+		backoff *= 2
+	}
+}
+
+func runTx(ctx context.Context, begin func(ctx context.Context) (pgx.Tx, error), fn func(ctx context.Context) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not start transaction: %w", err)
+	}
+
+	hooks := &TxHooks{} //nolint:exhaustruct // hooks are registered by fn
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(ctx)
+			hooks.RolledBack(ctx)
+			panic(r)
+		}
+	}()
+
+	err = fn(context.WithValue(context.WithValue(ctx, CtxTX, tx), ctxTxHooks, hooks))
+	if err != nil {
+		rb := tx.Rollback(ctx)
+		if rb != nil {
+			return fmt.Errorf("could not rollback transaction: %w", rb)
+		}
+
+		hooks.RolledBack(ctx)
+
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		hooks.RolledBack(ctx)
+		return fmt.Errorf("could not commit transaction: %w", err)
+	}
+
+	if outer, ok := ctx.Value(ctxTxHooks).(*TxHooks); ok {
+		outer.merge(hooks) // a savepoint is only committed, if the outer transaction is
+		return nil
+	}
+
+	if _, ok := ctx.Value(CtxTX).(pgx.Tx); ok {
+		return nil // a savepoint of a transaction without hooks, it is unknown when it commits, see AfterCommit
+	}
+
+	hooks.Committed(ctx)
+
+	return nil
+}
+
+// isRetryable reports if err is a serialization failure or deadlock,
+// so the transaction can succeed, if it is run again.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+const ctxTxHooks ctx2.CTXKey = "arrower.tx.hooks"
+
+// WithTxHooks returns a ctx to register hooks with AfterCommit and AfterRollback,
+// for a transaction in ctx that is not started by InTx, e.g. the transaction of a job.
+// The owner of the transaction calls TxHooks.Committed or TxHooks.RolledBack, after it finished the transaction.
+func WithTxHooks(ctx context.Context) (context.Context, *TxHooks) {
+	hooks := &TxHooks{} //nolint:exhaustruct // hooks are registered later
+
+	return context.WithValue(ctx, ctxTxHooks, hooks), hooks
+}
+
+// AfterCommit registers fn to run after the transaction in ctx is committed, e.g.
+// to send emails or publish events, that must not happen if the transaction rolls back.
+// Inside a savepoint, fn runs after the outermost transaction is committed.
+//
+// It only works with transactions started by InTx, e.g. by the app Tx decorators, or with hooks of WithTxHooks.
+// If ctx contains no transaction, fn runs immediately, as all writes are committed already.
+// If ctx contains a transaction without hooks, fn is dropped and never called, as it is unknown when it commits.
+// Callers that must act after such a transaction, have to do it themselves once its owner committed it.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(ctxTxHooks).(*TxHooks)
+	if !ok {
+		if _, inTx := ctx.Value(CtxTX).(pgx.Tx); !inTx {
+			fn(ctx)
+		}
+
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	hooks.commit = append(hooks.commit, fn)
+}
+
+// AfterRollback registers fn to run after the transaction in ctx is rolled back.
+// Inside a savepoint, fn runs if the savepoint or the outer transaction is rolled back.
+//
+// It only works with transactions started by InTx, or with hooks of WithTxHooks.
+// If ctx contains no such transaction, fn is never called.
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(ctxTxHooks).(*TxHooks)
+	if !ok {
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	hooks.rollback = append(hooks.rollback, fn)
+}
+
+// TxHooks are the functions registered with AfterCommit and AfterRollback for one transaction.
+// They are called in the order they are registered.
+type TxHooks struct {
+	mu       sync.Mutex
+	commit   []func(ctx context.Context)
+	rollback []func(ctx context.Context)
+}
+
+// Committed calls the hooks registered with AfterCommit.
+func (h *TxHooks) Committed(ctx context.Context) {
+	h.mu.Lock()
+	hooks := h.commit
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
+
+// RolledBack calls the hooks registered with AfterRollback.
+func (h *TxHooks) RolledBack(ctx context.Context) {
+	h.mu.Lock()
+	hooks := h.rollback
+	h.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn(ctx)
+	}
+}
+
+// merge moves the hooks of a committed savepoint into the hooks of its outer transaction.
+func (h *TxHooks) merge(inner *TxHooks) {
+	inner.mu.Lock()
+	defer inner.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.commit = append(h.commit, inner.commit...)
+	h.rollback = append(h.rollback, inner.rollback...)
+}
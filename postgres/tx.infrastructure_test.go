@@ -0,0 +1,184 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-arrower/arrower/postgres"
+)
+
+func TestInTx(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("failed")
+
+	newDB := func(t *testing.T) postgres.Beginner {
+		t.Helper()
+
+		db := pgHandler.NewTestDatabase()
+		_, err := db.Exec(t.Context(), `CREATE TABLE some_table(id SERIAL PRIMARY KEY);`)
+		assert.NoError(t, err)
+
+		return db
+	}
+
+	insert := func(ctx context.Context) error {
+		tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+		_, err := tx.Exec(ctx, `INSERT INTO some_table(id) VALUES (DEFAULT);`)
+
+		return err
+	}
+
+	count := func(t *testing.T, db postgres.Beginner) int {
+		t.Helper()
+
+		var n int
+		err := pgxscan.Get(t.Context(), db.(pgxscan.Querier), &n, `SELECT COUNT(*) FROM some_table;`) //nolint:forcetypeassert,lll // is a pool
+		assert.NoError(t, err)
+
+		return n
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		t.Parallel()
+
+		db := newDB(t)
+
+		err := postgres.InTx(t.Context(), db, insert)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count(t, db))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		t.Parallel()
+
+		db := newDB(t)
+
+		err := postgres.InTx(t.Context(), db, func(ctx context.Context) error {
+			_ = insert(ctx)
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, 0, count(t, db))
+	})
+
+	t.Run("nested rolls back to savepoint", func(t *testing.T) {
+		t.Parallel()
+
+		db := newDB(t)
+
+		err := postgres.InTx(t.Context(), db, func(ctx context.Context) error {
+			outer := ctx.Value(postgres.CtxTX)
+
+			err := postgres.InTx(ctx, db, func(ctx context.Context) error {
+				assert.NotEqual(t, outer, ctx.Value(postgres.CtxTX), "savepoint")
+
+				_ = insert(ctx)
+
+				return errFailed
+			})
+			assert.ErrorIs(t, err, errFailed)
+
+			assert.Equal(t, 0, count(t, db), "nothing committed before the outer transaction ends")
+
+			return insert(ctx)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count(t, db), "only the changes of the outer transaction")
+	})
+	t.Run("isolation level", func(t *testing.T) {
+		t.Parallel()
+
+		err := postgres.InTx(t.Context(), newDB(t), func(ctx context.Context) error {
+			tx, _ := ctx.Value(postgres.CtxTX).(pgx.Tx)
+
+			var level string
+			err := pgxscan.Get(ctx, tx, &level, `SHOW transaction_isolation;`)
+			assert.NoError(t, err)
+			assert.Equal(t, "serializable", level)
+
+			return nil
+		}, postgres.WithIsolation(pgx.Serializable))
+		assert.NoError(t, err)
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		t.Parallel()
+
+		err := postgres.InTx(t.Context(), newDB(t), insert, postgres.WithReadOnly())
+		assert.Error(t, err)
+	})
+
+	t.Run("retry serialization failures", func(t *testing.T) {
+		t.Parallel()
+
+		db := newDB(t)
+		attempts := 0
+
+		err := postgres.InTx(t.Context(), db, func(ctx context.Context) error {
+			attempts++
+
+			_ = insert(ctx)
+
+			if attempts < 3 {
+				return &pgconn.PgError{Code: "40001"}
+			}
+
+			return nil
+		}, postgres.WithRetry(3, time.Millisecond))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, 1, count(t, db), "failed attempts are rolled back")
+	})
+
+	t.Run("give up retrying", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+
+		err := postgres.InTx(t.Context(), newDB(t), func(_ context.Context) error {
+			attempts++
+			return &pgconn.PgError{Code: "40P01"}
+		}, postgres.WithRetry(2, time.Millisecond))
+		assert.Error(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("do not retry other errors", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+
+		err := postgres.InTx(t.Context(), newDB(t), func(_ context.Context) error {
+			attempts++
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("nested does not retry", func(t *testing.T) {
+		t.Parallel()
+
+		db := newDB(t)
+		attempts := 0
+
+		err := postgres.InTx(t.Context(), db, func(ctx context.Context) error {
+			return postgres.InTx(ctx, db, func(_ context.Context) error {
+				attempts++
+				return &pgconn.PgError{Code: "40001"}
+			})
+		}, postgres.WithRetry(1, 0))
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
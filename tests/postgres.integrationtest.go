@@ -146,6 +146,9 @@ var (
 		Port:       5432, //nolint:mnd
 		MaxConns:   10,   //nolint:mnd
 		Migrations: postgres.ArrowerDefaultMigrations,
+		MigrationSources: []postgres.MigrationSource{
+			postgres.ArrowerAuthMigrations,
+		},
 	}
 
 	defaultPGRunOptions = &dockertest.RunOptions{ //nolint:gochecknoglobals,exhaustruct // only set required configuration
@@ -221,7 +224,7 @@ func (pd *PostgresDocker) PrepareDatabase(files ...string) {
 					FROM information_schema.tables 
 					WHERE table_schema NOT IN ('pg_catalog', 'information_schema') 
 					  AND table_type = 'BASE TABLE' 
-					  AND table_name <> 'schema_migrations'`,
+					  AND table_name NOT IN ('schema_migrations', 'schema_migrations_auth')`,
 		)
 
 		cleaner.Clean(tables...)